@@ -0,0 +1,334 @@
+package pefile
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/md5"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Authenticode verdicts, from best to worst.
+const (
+	AuthenticodeValid          = "valid"
+	AuthenticodeUntrusted      = "untrusted"
+	AuthenticodeBadSignature   = "bad-signature"
+	AuthenticodeDigestMismatch = "digest-mismatch"
+	AuthenticodeUnsigned       = "unsigned"
+)
+
+// checkSumFieldOffset is where CheckSum sits in both optional header formats.
+const checkSumFieldOffset = 64
+
+var digestHashes = map[string]crypto.Hash{
+	"MD5":    crypto.MD5,
+	"SHA1":   crypto.SHA1,
+	"SHA256": crypto.SHA256,
+	"SHA384": crypto.SHA384,
+	"SHA512": crypto.SHA512,
+}
+
+// AuthenticodeResult is the outcome of checking one signature of the image.
+type AuthenticodeResult struct {
+	Signature      *Signature
+	ComputedDigest []byte // Authenticode hash of the image, in the signature's algorithm
+	// DigestMatches reports whether ComputedDigest is the digest the
+	// signature's SpcIndirectDataContent carries.
+	DigestMatches bool
+	// MessageDigestMatches reports whether the signer's messageDigest
+	// attribute is the hash of the SpcIndirectDataContent.
+	MessageDigestMatches bool
+	SignatureErr         error // the signer's signature over its attributes
+	Chain                []*x509.Certificate
+	ChainErr             error // nil once the signer chains up to a trust anchor
+	// Timestamp is the time of the first countersignature that verified,
+	// at which the chain was checked; zero when no countersignature was
+	// trusted.
+	Timestamp    time.Time
+	TimestampErr error // why no countersignature was trusted
+	Verdict      string
+}
+
+// AuthenticodeDigest hashes the image the way Authenticode does: the whole
+// file except the CheckSum field, the Certificate Table directory entry and
+// the certificate table itself.
+func (p *PeFull) AuthenticodeDigest(hash crypto.Hash) ([]byte, error) {
+	if !hash.Available() {
+		return nil, fmt.Errorf("hash %v is not available", hash)
+	}
+	certDir, ok := p.Directory("Certificate Table")
+	if !ok {
+		return nil, fmt.Errorf("no certificate table directory entry")
+	}
+
+	checkSumOffset := uint64(p.OptionalHeaderOffset()) + checkSumFieldOffset
+	dirOffset := uint64(certDir.Offset)
+	tableStart, tableEnd := uint64(len(p.FileData)), uint64(len(p.FileData))
+	if certDir.Present() {
+		tableStart = uint64(certDir.VirtualAddress)
+		tableEnd = tableStart + uint64(certDir.Size)
+	}
+	if checkSumOffset+4 > dirOffset || dirOffset+8 > tableStart || tableEnd > uint64(len(p.FileData)) {
+		return nil, fmt.Errorf("certificate table at 0x%X out of bounds", certDir.VirtualAddress)
+	}
+
+	h := hash.New()
+	h.Write(p.FileData[:checkSumOffset])
+	h.Write(p.FileData[checkSumOffset+4 : dirOffset])
+	h.Write(p.FileData[dirOffset+8 : tableStart])
+	h.Write(p.FileData[tableEnd:])
+	return h.Sum(nil), nil
+}
+
+// LoadTrustAnchors reads the root certificates signatures are checked
+// against from a PEM bundle or a single DER certificate, so verification
+// does not depend on the system store or the network.
+func LoadTrustAnchors(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		cert, err := x509.ParseCertificate(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		pool.AddCert(cert)
+		return pool, nil
+	}
+
+	count := 0
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		pool.AddCert(cert)
+		count++
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("%s: no certificates found", path)
+	}
+	return pool, nil
+}
+
+// VerifyAuthenticode checks every signature of the image, nested ones
+// included. Signer chains are only built up to roots, and are reported as
+// untrusted when roots is nil. Signatures without a trusted timestamp are
+// checked at the current time.
+func (p *PeFull) VerifyAuthenticode(roots *x509.CertPool) ([]AuthenticodeResult, error) {
+	return p.VerifyAuthenticodeAt(roots, time.Now())
+}
+
+// VerifyAuthenticodeAt is VerifyAuthenticode checking signatures without a
+// trusted timestamp at the given time. A zero time leaves their chain
+// unchecked, so that the results only depend on the file and roots.
+func (p *PeFull) VerifyAuthenticodeAt(roots *x509.CertPool, at time.Time) ([]AuthenticodeResult, error) {
+	signatures, err := p.Signatures()
+	if len(signatures) == 0 {
+		return nil, err
+	}
+
+	var results []AuthenticodeResult
+	for _, signature := range signatures {
+		results = append(results, p.verifySignature(signature, roots, at))
+	}
+	return results, err
+}
+
+func (p *PeFull) verifySignature(signature *Signature, roots *x509.CertPool, at time.Time) AuthenticodeResult {
+	result := AuthenticodeResult{Signature: signature, Verdict: AuthenticodeDigestMismatch}
+
+	hash, ok := digestHashes[signature.FileDigestAlgorithm]
+	if !ok {
+		result.SignatureErr = fmt.Errorf("unsupported digest algorithm %s", signature.FileDigestAlgorithm)
+		return result
+	}
+	digest, err := p.AuthenticodeDigest(hash)
+	if err != nil {
+		result.SignatureErr = err
+		return result
+	}
+	result.ComputedDigest = digest
+	result.DigestMatches = bytes.Equal(digest, signature.FileDigest)
+
+	if len(signature.Signers) == 0 {
+		result.SignatureErr = fmt.Errorf("signature has no signer")
+		return result
+	}
+	signer := signature.Signers[0]
+	result.MessageDigestMatches = digestMatches(signer.DigestAlgorithm, signature.Content, signer.MessageDigest)
+	if !result.DigestMatches || !result.MessageDigestMatches {
+		return result
+	}
+
+	result.Verdict = AuthenticodeBadSignature
+	result.SignatureErr = checkSignerSignature(signer)
+	if result.SignatureErr != nil {
+		return result
+	}
+
+	result.Verdict = AuthenticodeUntrusted
+	if roots == nil {
+		result.ChainErr = fmt.Errorf("no trust anchors loaded")
+		return result
+	}
+
+	// A timestamped signature stays valid after the certificate expires,
+	// provided the timestamp itself comes from a trusted authority
+	verifyTime := at
+	for _, countersignature := range signer.Countersignatures {
+		err := verifyCountersignature(countersignature, signer, signature.Certificates, roots)
+		if err == nil {
+			verifyTime = countersignature.Time
+			result.Timestamp = countersignature.Time
+			result.TimestampErr = nil
+			break
+		}
+		if result.TimestampErr == nil {
+			result.TimestampErr = err
+		}
+	}
+	if verifyTime.IsZero() {
+		result.ChainErr = fmt.Errorf("no trusted timestamp to check the chain at")
+		return result
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range signature.Certificates {
+		intermediates.AddCert(cert)
+	}
+	chains, err := signer.Certificate.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   verifyTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		result.ChainErr = err
+		return result
+	}
+	result.Chain = chains[0]
+	result.Verdict = AuthenticodeValid
+	return result
+}
+
+// verifyCountersignature checks that a countersignature is a valid
+// signature over the signer's encrypted digest by a timestamping authority
+// that chains up to roots.
+func verifyCountersignature(countersignature Countersignature, signer SignerInfo, certificates []*x509.Certificate, roots *x509.CertPool) error {
+	if countersignature.Time.IsZero() {
+		return fmt.Errorf("%s countersignature has no time", countersignature.Type)
+	}
+	counterSigner := countersignature.Signer
+	if err := checkSignerSignature(counterSigner); err != nil {
+		return fmt.Errorf("%s countersignature: %v", countersignature.Type, err)
+	}
+
+	// A legacy countersigner signs a hash of the encrypted digest directly,
+	// an RFC 3161 token signs a TSTInfo which holds that hash
+	algorithm, digest := counterSigner.DigestAlgorithm, counterSigner.MessageDigest
+	if countersignature.Type == "RFC 3161" {
+		if !digestMatches(counterSigner.DigestAlgorithm, countersignature.Content, counterSigner.MessageDigest) {
+			return fmt.Errorf("RFC 3161 countersignature does not sign its TSTInfo")
+		}
+		algorithm, digest = countersignature.ImprintAlgorithm, countersignature.Imprint
+	}
+	if !digestMatches(algorithm, signer.EncryptedDigest, digest) {
+		return fmt.Errorf("%s countersignature is not over the signer's digest", countersignature.Type)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certificates {
+		intermediates.AddCert(cert)
+	}
+	for _, cert := range countersignature.Certificates {
+		intermediates.AddCert(cert)
+	}
+	_, err := counterSigner.Certificate.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   countersignature.Time,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	if err != nil {
+		return fmt.Errorf("%s countersignature: %v", countersignature.Type, err)
+	}
+	return nil
+}
+
+// digestMatches reports whether digest is the hash of data with the named
+// algorithm.
+func digestMatches(algorithm string, data []byte, digest []byte) bool {
+	hash, ok := digestHashes[algorithm]
+	if !ok || !hash.Available() {
+		return false
+	}
+	h := hash.New()
+	h.Write(data)
+	return bytes.Equal(h.Sum(nil), digest)
+}
+
+// checkSignerSignature verifies the signer's encrypted digest over its
+// authenticated attributes with the public key of its certificate.
+func checkSignerSignature(signer SignerInfo) error {
+	if signer.Certificate == nil {
+		return fmt.Errorf("signer certificate not included")
+	}
+	if len(signer.AuthenticatedAttributes) == 0 {
+		return fmt.Errorf("signer has no authenticated attributes")
+	}
+
+	var algorithm x509.SignatureAlgorithm
+	switch signer.Certificate.PublicKeyAlgorithm {
+	case x509.RSA:
+		algorithm = map[string]x509.SignatureAlgorithm{
+			"SHA1":   x509.SHA1WithRSA,
+			"SHA256": x509.SHA256WithRSA,
+			"SHA384": x509.SHA384WithRSA,
+			"SHA512": x509.SHA512WithRSA,
+		}[signer.DigestAlgorithm]
+	case x509.ECDSA:
+		algorithm = map[string]x509.SignatureAlgorithm{
+			"SHA1":   x509.ECDSAWithSHA1,
+			"SHA256": x509.ECDSAWithSHA256,
+			"SHA384": x509.ECDSAWithSHA384,
+			"SHA512": x509.ECDSAWithSHA512,
+		}[signer.DigestAlgorithm]
+	}
+	if algorithm == x509.UnknownSignatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %s with %s", signer.EncryptionAlgorithm, signer.DigestAlgorithm)
+	}
+
+	// The attributes are signed as a SET OF, not with their [0] tag
+	signed := append([]byte{0x31}, signer.AuthenticatedAttributes[1:]...)
+	return signer.Certificate.CheckSignature(algorithm, signed, signer.EncryptedDigest)
+}
+
+// AuthenticodeVerdict sums up the results of VerifyAuthenticode as the
+// worst verdict of any signature, or unsigned when there is none.
+func AuthenticodeVerdict(results []AuthenticodeResult) string {
+	if len(results) == 0 {
+		return AuthenticodeUnsigned
+	}
+	order := []string{AuthenticodeDigestMismatch, AuthenticodeBadSignature, AuthenticodeUntrusted}
+	for _, verdict := range order {
+		for _, result := range results {
+			if result.Verdict == verdict {
+				return verdict
+			}
+		}
+	}
+	return AuthenticodeValid
+}
@@ -0,0 +1,483 @@
+package pefile
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+)
+
+var (
+	oidTestSHA256       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidTestECDSASHA256  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidTestSpcPeImage   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 15}
+	testTimestampTime   = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	testSignerNotBefore = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// testPKI is a root with a code signing certificate that has expired and a
+// timestamping certificate that has not.
+type testPKI struct {
+	root, signer, tsa          *x509.Certificate
+	rootKey, signerKey, tsaKey *ecdsa.PrivateKey
+}
+
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	pki := &testPKI{}
+	pki.root, pki.rootKey = newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root"},
+		NotBefore:             time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2049, 1, 1, 0, 0, 0, 0, time.UTC),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	pki.signer, pki.signerKey = newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Publisher"},
+		NotBefore:    testSignerNotBefore,
+		NotAfter:     testSignerNotBefore.AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}, pki.root, pki.rootKey)
+	pki.tsa, pki.tsaKey = newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Test Timestamping"},
+		NotBefore:    time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2049, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}, pki.root, pki.rootKey)
+	return pki
+}
+
+func (pki *testPKI) roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(pki.root)
+	return pool
+}
+
+func mustMarshal(t *testing.T, v any) []byte {
+	t.Helper()
+	der, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// explicitTag wraps der in a constructed context specific tag.
+func explicitTag(tag int, der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: tag, IsCompound: true, Bytes: der}
+}
+
+func testAttribute(t *testing.T, oid asn1.ObjectIdentifier, value []byte) []byte {
+	return mustMarshal(t, pkcs7Attribute{
+		Type:   oid,
+		Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: value},
+	})
+}
+
+// testSignerInfo signs the attributes with key and returns the SignerInfo.
+func testSignerInfo(t *testing.T, cert *x509.Certificate, key *ecdsa.PrivateKey, attributes [][]byte) pkcs7SignerInfo {
+	t.Helper()
+	set := mustMarshal(t, asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: bytes.Join(attributes, nil)})
+	sum := sha256.Sum256(set)
+	encryptedDigest, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pkcs7SignerInfo{
+		Version: 1,
+		SignerIdentifier: asn1.RawValue{FullBytes: mustMarshal(t, pkcs7IssuerAndSerial{
+			Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+			SerialNumber: cert.SerialNumber,
+		})},
+		DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: oidTestSHA256},
+		AuthenticatedAttributes:   asn1.RawValue{FullBytes: append([]byte{0xA0}, set[1:]...)},
+		DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidTestECDSASHA256},
+		EncryptedDigest:           encryptedDigest,
+	}
+}
+
+// countersign returns a legacy Authenticode countersignature attribute over
+// info's encrypted digest. tamper, when set, changes the countersignature
+// before it is encoded.
+func countersign(t *testing.T, cert *x509.Certificate, key *ecdsa.PrivateKey, info pkcs7SignerInfo, tamper func(*pkcs7SignerInfo)) []byte {
+	t.Helper()
+	sum := sha256.Sum256(info.EncryptedDigest)
+	counterInfo := testSignerInfo(t, cert, key, [][]byte{
+		testAttribute(t, oidSigningTime, mustMarshal(t, testTimestampTime)),
+		testAttribute(t, oidMessageDigest, mustMarshal(t, sum[:])),
+	})
+	if tamper != nil {
+		tamper(&counterInfo)
+	}
+	return testAttribute(t, oidCounterSignature, mustMarshal(t, counterInfo))
+}
+
+// testSignature holds what goes into a signature built by sign.
+type testSignature struct {
+	// countersigner and its key sign the timestamp, the TSA when nil
+	countersigner    *x509.Certificate
+	countersignerKey *ecdsa.PrivateKey
+	tamper           func(*pkcs7SignerInfo)
+	noTimestamp      bool
+}
+
+// sign appends a certificate table with an Authenticode signature of image
+// and points the Certificate Table directory entry at it.
+func (pki *testPKI) sign(t *testing.T, image []byte, opts testSignature) []byte {
+	t.Helper()
+	p, err := ParseBytes(image)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	digest, err := p.AuthenticodeDigest(crypto.SHA256)
+	if err != nil {
+		t.Fatalf("AuthenticodeDigest: %v", err)
+	}
+
+	indirect := mustMarshal(t, spcIndirectDataContent{
+		Data:          asn1.RawValue{FullBytes: mustMarshal(t, struct{ Type asn1.ObjectIdentifier }{oidTestSpcPeImage})},
+		MessageDigest: digestInfo{DigestAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidTestSHA256}, Digest: digest},
+	})
+	var content asn1.RawValue
+	asn1.Unmarshal(indirect, &content)
+	contentSum := sha256.Sum256(content.Bytes)
+
+	attributes := [][]byte{testAttribute(t, oidMessageDigest, mustMarshal(t, contentSum[:]))}
+	info := testSignerInfo(t, pki.signer, pki.signerKey, attributes)
+	certificates := [][]byte{pki.signer.Raw, pki.tsa.Raw}
+	if !opts.noTimestamp {
+		cert, key := pki.tsa, pki.tsaKey
+		if opts.countersigner != nil {
+			cert, key = opts.countersigner, opts.countersignerKey
+			certificates = append(certificates, cert.Raw)
+		}
+		info.UnauthenticatedAttributes = explicitTag(1, countersign(t, cert, key, info, opts.tamper))
+	}
+
+	signedData := mustMarshal(t, pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidTestSHA256}},
+		ContentInfo:      pkcs7ContentInfo{ContentType: oidSpcIndirectData, Content: explicitTag(0, indirect)},
+		Certificates:     explicitTag(0, bytes.Join(certificates, nil)),
+		SignerInfos:      []pkcs7SignerInfo{info},
+	})
+	der := mustMarshal(t, pkcs7ContentInfo{ContentType: oidSignedData, Content: explicitTag(0, signedData)})
+
+	length := uint32(binary.Size(WIN_CERTIFICATE{}) + len(der))
+	table := new(bytes.Buffer)
+	binary.Write(table, binary.LittleEndian, WIN_CERTIFICATE{Length: length, Revision: 0x200, CertificateType: WIN_CERT_TYPE_PKCS_SIGNED_DATA})
+	table.Write(der)
+	table.Write(make([]byte, alignUp(length, 8)-length))
+
+	certDir, _ := p.Directory("Certificate Table")
+	signed := append([]byte(nil), image...)
+	binary.LittleEndian.PutUint32(signed[certDir.Offset:], uint32(len(image)))
+	binary.LittleEndian.PutUint32(signed[certDir.Offset+4:], uint32(table.Len()))
+	return append(signed, table.Bytes()...)
+}
+
+func signTestImage() []byte {
+	return testImage{
+		checkSum: 0x1234,
+		sections: []testSection{{name: ".text", data: []byte{0x48, 0x31, 0xC0, 0xC3}, characteristics: 0x60000020}},
+	}.bytes()
+}
+
+func TestAuthenticodeDigest(t *testing.T) {
+	image := signTestImage()
+	p, err := ParseBytes(image)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	digest, err := p.AuthenticodeDigest(crypto.SHA256)
+	if err != nil {
+		t.Fatalf("AuthenticodeDigest: %v", err)
+	}
+	const want = "577cad254edc52ead9ece7bc66b02dcce6c4d0f4f4de5ea066a714db385d36e1"
+	if got := hex.EncodeToString(digest); got != want {
+		t.Errorf("AuthenticodeDigest = %s, want %s", got, want)
+	}
+
+	// The CheckSum, the directory entry and the table itself are not hashed
+	signed := newTestPKI(t).sign(t, image, testSignature{})
+	binary.LittleEndian.PutUint32(signed[p.OptionalHeaderOffset()+checkSumFieldOffset:], 0xFFFFFFFF)
+	p, err = ParseBytes(signed)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	if digest, err := p.AuthenticodeDigest(crypto.SHA256); err != nil || hex.EncodeToString(digest) != want {
+		t.Errorf("signed image digest = %x, %v", digest, err)
+	}
+
+	// Anything else is
+	signed[testFileAlignment] ^= 0xFF
+	p, _ = ParseBytes(signed)
+	if digest, _ := p.AuthenticodeDigest(crypto.SHA256); hex.EncodeToString(digest) == want {
+		t.Error("changing the code did not change the digest")
+	}
+}
+
+func TestAuthenticodeDigestCorrupt(t *testing.T) {
+	signed := newTestPKI(t).sign(t, signTestImage(), testSignature{})
+	p, err := ParseBytes(signed)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	certDir, _ := p.Directory("Certificate Table")
+
+	tests := []struct {
+		name           string
+		offset, length uint32
+	}{
+		{"table past the end", certDir.VirtualAddress, certDir.Size + 1},
+		{"table before the directory entry", 0x10, certDir.Size},
+		{"table wraps", 0xFFFFFFF0, 0x20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := append([]byte(nil), signed...)
+			binary.LittleEndian.PutUint32(image[certDir.Offset:], tt.offset)
+			binary.LittleEndian.PutUint32(image[certDir.Offset+4:], tt.length)
+			p, err := ParseBytes(image)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			if _, err := p.AuthenticodeDigest(crypto.SHA256); err == nil {
+				t.Error("AuthenticodeDigest succeeded")
+			}
+		})
+	}
+}
+
+func TestVerifyAuthenticode(t *testing.T) {
+	pki := newTestPKI(t)
+	untrustedTSA, untrustedTSAKey := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "Untrusted Timestamping"},
+		NotBefore:    time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2049, 1, 1, 0, 0, 0, 0, time.UTC),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}, nil, nil)
+
+	tests := []struct {
+		name      string
+		signature testSignature
+		roots     *x509.CertPool
+		verdict   string
+		// whether the timestamp is trusted, and so the expired signer is too
+		timestamped bool
+	}{
+		{"timestamped", testSignature{}, pki.roots(), AuthenticodeValid, true},
+		{"no trust anchors", testSignature{}, nil, AuthenticodeUntrusted, false},
+		{"no timestamp", testSignature{noTimestamp: true}, pki.roots(), AuthenticodeUntrusted, false},
+		{"timestamp by an untrusted authority", testSignature{countersigner: untrustedTSA, countersignerKey: untrustedTSAKey}, pki.roots(), AuthenticodeUntrusted, false},
+		{"timestamp by the code signing certificate", testSignature{countersigner: pki.signer, countersignerKey: pki.signerKey}, pki.roots(), AuthenticodeUntrusted, false},
+		{"timestamp with a bad signature", testSignature{tamper: func(info *pkcs7SignerInfo) {
+			info.EncryptedDigest[len(info.EncryptedDigest)-1] ^= 0xFF
+		}}, pki.roots(), AuthenticodeUntrusted, false},
+		{"timestamp over another digest", testSignature{tamper: func(info *pkcs7SignerInfo) {
+			// Re-sign attributes whose messageDigest hashes something else
+			other := sha256.Sum256([]byte("another signature"))
+			*info = testSignerInfo(t, pki.tsa, pki.tsaKey, [][]byte{
+				testAttribute(t, oidSigningTime, mustMarshal(t, testTimestampTime)),
+				testAttribute(t, oidMessageDigest, mustMarshal(t, other[:])),
+			})
+		}}, pki.roots(), AuthenticodeUntrusted, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(pki.sign(t, signTestImage(), tt.signature))
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			results, err := p.VerifyAuthenticode(tt.roots)
+			if err != nil {
+				t.Fatalf("VerifyAuthenticode: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			result := results[0]
+			if !result.DigestMatches || !result.MessageDigestMatches || result.SignatureErr != nil {
+				t.Fatalf("signature does not check out: %+v", result)
+			}
+			if result.Verdict != tt.verdict {
+				t.Errorf("verdict %s, want %s (chain: %v, timestamp: %v)", result.Verdict, tt.verdict, result.ChainErr, result.TimestampErr)
+			}
+			if tt.timestamped != result.Timestamp.Equal(testTimestampTime) {
+				t.Errorf("timestamp %v, %v", result.Timestamp, result.TimestampErr)
+			}
+			if !tt.timestamped && tt.roots != nil && !tt.signature.noTimestamp && result.TimestampErr == nil {
+				t.Error("countersignature was not trusted but there is no TimestampErr")
+			}
+		})
+	}
+}
+
+func TestVerifyAuthenticodeTampered(t *testing.T) {
+	pki := newTestPKI(t)
+	signed := pki.sign(t, signTestImage(), testSignature{})
+	signed[testFileAlignment] ^= 0xFF
+
+	p, err := ParseBytes(signed)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	results, err := p.VerifyAuthenticode(pki.roots())
+	if err != nil {
+		t.Fatalf("VerifyAuthenticode: %v", err)
+	}
+	if len(results) != 1 || results[0].DigestMatches || results[0].Verdict != AuthenticodeDigestMismatch {
+		t.Errorf("results = %+v", results)
+	}
+	if verdict := AuthenticodeVerdict(results); verdict != AuthenticodeDigestMismatch {
+		t.Errorf("AuthenticodeVerdict = %s", verdict)
+	}
+}
+
+// Without a timestamp the chain is checked at the given time, or not at all.
+func TestVerifyAuthenticodeAt(t *testing.T) {
+	pki := newTestPKI(t)
+	p, err := ParseBytes(pki.sign(t, signTestImage(), testSignature{noTimestamp: true}))
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		verdict string
+	}{
+		{"signer valid", testSignerNotBefore.AddDate(0, 6, 0), AuthenticodeValid},
+		{"signer expired", testSignerNotBefore.AddDate(2, 0, 0), AuthenticodeUntrusted},
+		{"no time", time.Time{}, AuthenticodeUntrusted},
+	}
+	for _, tt := range tests {
+		results, err := p.VerifyAuthenticodeAt(pki.roots(), tt.at)
+		if err != nil {
+			t.Fatalf("%s: VerifyAuthenticodeAt: %v", tt.name, err)
+		}
+		if len(results) != 1 || results[0].Verdict != tt.verdict {
+			t.Errorf("%s: results = %+v, want verdict %s", tt.name, results, tt.verdict)
+		}
+	}
+
+	// The same file gives the same result whenever it is checked
+	first, _ := p.VerifyAuthenticodeAt(pki.roots(), time.Time{})
+	second, _ := p.VerifyAuthenticodeAt(pki.roots(), time.Time{})
+	if first[0].ChainErr == nil || first[0].ChainErr.Error() != second[0].ChainErr.Error() {
+		t.Errorf("chain errors %v and %v", first[0].ChainErr, second[0].ChainErr)
+	}
+}
+
+func TestSignatures(t *testing.T) {
+	pki := newTestPKI(t)
+	p, err := ParseBytes(pki.sign(t, signTestImage(), testSignature{}))
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+
+	signatures, err := p.Signatures()
+	if err != nil {
+		t.Fatalf("Signatures: %v", err)
+	}
+	if len(signatures) != 1 {
+		t.Fatalf("got %d signatures, want 1", len(signatures))
+	}
+	signature := signatures[0]
+	if signature.ContentType != "SPC_INDIRECT_DATA" || signature.FileDigestAlgorithm != "SHA256" || len(signature.Certificates) != 2 {
+		t.Errorf("signature = %+v", signature)
+	}
+	if len(signature.Signers) != 1 || signature.Signers[0].Certificate != signature.Certificates[0] {
+		t.Fatalf("signer certificate not found")
+	}
+	countersignatures := signature.Signers[0].Countersignatures
+	if len(countersignatures) != 1 || countersignatures[0].Type != "Authenticode" || !countersignatures[0].Time.Equal(testTimestampTime) {
+		t.Errorf("countersignatures = %+v", countersignatures)
+	}
+	if signature.Offset == 0 || signature.CertificateOffset(pki.tsa) == 0 {
+		t.Errorf("signature at 0x%X, TSA certificate at 0x%X", signature.Offset, signature.CertificateOffset(pki.tsa))
+	}
+}
+
+func TestCertificateTableCorrupt(t *testing.T) {
+	pki := newTestPKI(t)
+	signed := pki.sign(t, signTestImage(), testSignature{})
+	p, err := ParseBytes(signed)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	certDir, _ := p.Directory("Certificate Table")
+	table := certDir.VirtualAddress
+
+	badLength := append([]byte(nil), signed...)
+	binary.LittleEndian.PutUint32(badLength[table:], 4)
+
+	// Truncating the DER keeps the entry but not a signature
+	badDER := append([]byte(nil), signed...)
+	badDER[table+8+1] ^= 0x7F
+
+	tests := []struct {
+		name      string
+		data      []byte
+		tableErr  bool
+		signature bool
+	}{
+		{"length shorter than the header", badLength, true, false},
+		{"bad DER", badDER, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(tt.data)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			entries, err := p.CertificateTable()
+			if (err != nil) != tt.tableErr {
+				t.Errorf("CertificateTable error %v", err)
+			}
+			for _, entry := range entries {
+				if (entry.Signature != nil) != tt.signature || entry.Err == nil {
+					t.Errorf("entry = %+v", entry)
+				}
+			}
+		})
+	}
+}
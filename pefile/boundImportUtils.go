@@ -0,0 +1,133 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+type IMAGE_BOUND_IMPORT_DESCRIPTOR struct {
+	TimeDateStamp               uint32
+	OffsetModuleName            uint16
+	NumberOfModuleForwarderRefs uint16
+}
+
+type IMAGE_BOUND_FORWARDER_REF struct {
+	TimeDateStamp    uint32
+	OffsetModuleName uint16
+	Reserved         uint16
+}
+
+// boundImportMarker is the TimeDateStamp of an import descriptor whose DLL
+// is bound through the bound import directory rather than old-style.
+const boundImportMarker = 0xFFFFFFFF
+
+type BoundForwarderRef struct {
+	Offset     uint32 // file offset of the forwarder ref
+	Ref        IMAGE_BOUND_FORWARDER_REF
+	ModuleName string
+}
+
+type BoundImport struct {
+	Offset     uint32 // file offset of the descriptor
+	Descriptor IMAGE_BOUND_IMPORT_DESCRIPTOR
+	ModuleName string
+	Forwarders []BoundForwarderRef
+	// Import is the Import Table descriptor of the same DLL, or nil when the
+	// DLL is not imported.
+	Import *ImportDescriptor
+}
+
+// TimestampMatches reports whether the Import Table descriptor of the module
+// agrees with the binding: it is either marked as bound through this
+// directory or carries the same timestamp.
+func (b *BoundImport) TimestampMatches() bool {
+	if b.Import == nil {
+		return false
+	}
+	stamp := b.Import.Descriptor.TimeDateStamp
+	return stamp == boundImportMarker || stamp == b.Descriptor.TimeDateStamp
+}
+
+// BoundImports decodes the bound import directory. Module names are stored
+// as offsets from the start of the directory, which normally sits in the
+// headers past the section table.
+func (p *PeFull) BoundImports() ([]BoundImport, error) {
+	boundDir, ok := p.Directory("Bound Import")
+	if !ok || !boundDir.Present() {
+		return nil, fmt.Errorf("no bound import directory")
+	}
+
+	start, ok := p.FileBackedOffset(boundDir.VirtualAddress, boundDir.Size)
+	if !ok {
+		// The headers are mapped at RVA 0, so there RVA and file offset agree
+		if boundDir.VirtualAddress >= p.SizeOfHeaders() {
+			return nil, fmt.Errorf("bound import directory at RVA 0x%X is not backed by the file", boundDir.VirtualAddress)
+		}
+		start = boundDir.VirtualAddress
+	}
+	end := uint64(start) + uint64(boundDir.Size)
+	if end > uint64(len(p.FileData)) {
+		return nil, fmt.Errorf("bound import directory at 0x%X out of bounds", start)
+	}
+	directory := p.FileData[start:end]
+
+	imports, _ := p.ImportTable()
+	readName := func(offset uint16) (string, error) {
+		if int(offset) >= len(directory) {
+			return "", fmt.Errorf("module name offset 0x%X out of bounds", offset)
+		}
+		name := directory[offset:]
+		if n := bytes.IndexByte(name, 0); n >= 0 {
+			name = name[:n]
+		}
+		return string(name), nil
+	}
+
+	var bound []BoundImport
+	descriptorSize := uint32(binary.Size(IMAGE_BOUND_IMPORT_DESCRIPTOR{}))
+	forwarderSize := uint32(binary.Size(IMAGE_BOUND_FORWARDER_REF{}))
+	for offset := uint32(0); offset+descriptorSize <= uint32(len(directory)); {
+		var descriptor IMAGE_BOUND_IMPORT_DESCRIPTOR
+		reader := bytes.NewReader(directory[offset:])
+		if err := binary.Read(reader, binary.LittleEndian, &descriptor); err != nil {
+			return bound, err
+		}
+		if descriptor == (IMAGE_BOUND_IMPORT_DESCRIPTOR{}) {
+			break
+		}
+
+		entry := BoundImport{Offset: start + offset, Descriptor: descriptor}
+		var err error
+		if entry.ModuleName, err = readName(descriptor.OffsetModuleName); err != nil {
+			return bound, fmt.Errorf("bound import descriptor at 0x%X: %v", entry.Offset, err)
+		}
+		// Descriptors whose DLL name couldn't be read have an empty one
+		for i := range imports {
+			if imports[i].DllName != "" && strings.EqualFold(imports[i].DllName, entry.ModuleName) {
+				entry.Import = &imports[i]
+				break
+			}
+		}
+		offset += descriptorSize
+
+		// The forwarder refs of a module follow its descriptor
+		for i := 0; i < int(descriptor.NumberOfModuleForwarderRefs); i++ {
+			forwarder := BoundForwarderRef{Offset: start + offset}
+			if err := binary.Read(reader, binary.LittleEndian, &forwarder.Ref); err != nil {
+				bound = append(bound, entry)
+				return bound, fmt.Errorf("forwarder refs of %s: %v", entry.ModuleName, err)
+			}
+			if forwarder.ModuleName, err = readName(forwarder.Ref.OffsetModuleName); err != nil {
+				bound = append(bound, entry)
+				return bound, fmt.Errorf("forwarder ref at 0x%X: %v", forwarder.Offset, err)
+			}
+			entry.Forwarders = append(entry.Forwarders, forwarder)
+			offset += forwarderSize
+		}
+		bound = append(bound, entry)
+	}
+
+	return bound, nil
+}
@@ -0,0 +1,128 @@
+package pefile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// boundDirectory is where boundImportTestImage puts the bound import
+// directory: in the headers, right after the section table.
+const boundDirectory = testLfanew + 4 + 20 + 0xF0 + 40
+
+// boundImportTestImage adds a bound import directory to the importTestImage.
+// KERNEL32.dll is bound with a forwarder ref to ntdll.dll and its import
+// descriptor marked as bound, USER32.dll is bound with a timestamp its
+// import descriptor doesn't carry.
+func boundImportTestImage() []byte {
+	d := newTestData(boundDirectory)
+	d.putStruct([]IMAGE_BOUND_IMPORT_DESCRIPTOR{{0x11111111, 32, 1}})
+	d.putStruct(IMAGE_BOUND_FORWARDER_REF{0x22222222, 45, 0})
+	d.putStruct([]IMAGE_BOUND_IMPORT_DESCRIPTOR{{0x33333333, 55, 0}, {}})
+	d.put([]byte("kernel32.dll\x00ntdll.dll\x00user32.dll\x00"))
+
+	image := importTestImage()
+	image.dirs[11] = DataDirectory{VirtualAddress: boundDirectory, Size: uint32(len(d.bytes()))}
+	data := image.bytes()
+	copy(data[boundDirectory:], d.bytes())
+
+	kernel32 := testFileAlignment + image.dirs[1].VirtualAddress - testSectionRVA(0)
+	binary.LittleEndian.PutUint32(data[kernel32+4:], boundImportMarker)
+	return data
+}
+
+func TestBoundImports(t *testing.T) {
+	p, err := ParseBytes(boundImportTestImage())
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+
+	bound, err := p.BoundImports()
+	if err != nil {
+		t.Fatalf("BoundImports: %v", err)
+	}
+	if len(bound) != 2 {
+		t.Fatalf("got %d bound imports, want 2", len(bound))
+	}
+
+	kernel32 := bound[0]
+	if kernel32.ModuleName != "kernel32.dll" || kernel32.Offset != boundDirectory || kernel32.Descriptor.TimeDateStamp != 0x11111111 {
+		t.Errorf("bound import 0 = %+v", kernel32)
+	}
+	if kernel32.Import == nil || kernel32.Import.DllName != "KERNEL32.dll" || !kernel32.TimestampMatches() {
+		t.Errorf("kernel32.dll is not matched with its import descriptor: %+v", kernel32.Import)
+	}
+	if len(kernel32.Forwarders) != 1 || kernel32.Forwarders[0].ModuleName != "ntdll.dll" || kernel32.Forwarders[0].Offset != boundDirectory+8 {
+		t.Errorf("forwarders = %+v", kernel32.Forwarders)
+	}
+
+	user32 := bound[1]
+	if user32.ModuleName != "user32.dll" || user32.Import == nil || user32.TimestampMatches() {
+		t.Errorf("bound import 1 = %+v", user32)
+	}
+}
+
+// An empty module name must not be matched with an import descriptor whose
+// DLL name could not be read.
+func TestBoundImportsEmptyName(t *testing.T) {
+	data := boundImportTestImage()
+	binary.LittleEndian.PutUint16(data[boundDirectory+16+4:], 32+12)
+
+	p, err := ParseBytes(data)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	bound, err := p.BoundImports()
+	if err != nil {
+		t.Fatalf("BoundImports: %v", err)
+	}
+	if bound[1].ModuleName != "" || bound[1].Import != nil {
+		t.Errorf("bound import 1 = %q, matched with %+v", bound[1].ModuleName, bound[1].Import)
+	}
+}
+
+func TestBoundImportsCorrupt(t *testing.T) {
+	// A module name past the end of the directory
+	badName := boundImportTestImage()
+	binary.LittleEndian.PutUint16(badName[boundDirectory+4:], 0x100)
+
+	// USER32.dll's forwarder refs run into the null descriptor and past it
+	badForwarder := boundImportTestImage()
+	binary.LittleEndian.PutUint16(badForwarder[boundDirectory+16+6:], 8)
+
+	outOfBounds := boundImportTestImage()
+	sizeOffset := testLfanew + 4 + 20 + 0x70 + 11*8 + 4
+	binary.LittleEndian.PutUint32(outOfBounds[sizeOffset:], 0x7FFF0000)
+
+	// An RVA past the headers and in no section, whose file offset holds a
+	// copy of the directory in the raw padding of .idata
+	unmapped := boundImportTestImage()
+	size := binary.LittleEndian.Uint32(unmapped[sizeOffset:])
+	copy(unmapped[0x380:], unmapped[boundDirectory:boundDirectory+size])
+	binary.LittleEndian.PutUint32(unmapped[sizeOffset-4:], 0x380)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		count int
+	}{
+		{"module name out of bounds", badName, 0},
+		{"forwarder refs out of bounds", badForwarder, 2},
+		{"directory out of bounds", outOfBounds, 0},
+		{"directory outside the headers", unmapped, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(tt.data)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			bound, err := p.BoundImports()
+			if err == nil {
+				t.Error("BoundImports succeeded")
+			}
+			if len(bound) != tt.count {
+				t.Errorf("got %d bound imports, want %d", len(bound), tt.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,624 @@
+package pefile
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+	"unicode/utf16"
+)
+
+type WIN_CERTIFICATE struct {
+	Length          uint32
+	Revision        uint16
+	CertificateType uint16
+}
+
+const (
+	WIN_CERT_REVISION_1_0 = 0x0100
+	WIN_CERT_REVISION_2_0 = 0x0200
+)
+
+const (
+	WIN_CERT_TYPE_X509             = 0x0001
+	WIN_CERT_TYPE_PKCS_SIGNED_DATA = 0x0002
+	WIN_CERT_TYPE_RESERVED_1       = 0x0003
+	WIN_CERT_TYPE_TS_STACK_SIGNED  = 0x0004
+)
+
+// maxNestedSignatures bounds the recursion into nested signatures.
+const maxNestedSignatures = 8
+
+var (
+	oidSignedData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidSpcIndirectData  = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 4}
+	oidMessageDigest    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidCounterSignature = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 6}
+	oidSpcSpOpusInfo    = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 1, 12}
+	oidRFC3161Timestamp = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 3, 3, 1}
+	oidNestedSignature  = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 2, 4, 1}
+	oidTSTInfo          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+)
+
+var algorithmNames = map[string]string{
+	"1.2.840.113549.2.5":        "MD5",
+	"1.3.14.3.2.26":             "SHA1",
+	"2.16.840.1.101.3.4.2.1":    "SHA256",
+	"2.16.840.1.101.3.4.2.2":    "SHA384",
+	"2.16.840.1.101.3.4.2.3":    "SHA512",
+	"1.2.840.113549.1.1.1":      "RSA",
+	"1.2.840.113549.1.1.4":      "MD5-RSA",
+	"1.2.840.113549.1.1.5":      "SHA1-RSA",
+	"1.2.840.113549.1.1.10":     "RSA-PSS",
+	"1.2.840.113549.1.1.11":     "SHA256-RSA",
+	"1.2.840.113549.1.1.12":     "SHA384-RSA",
+	"1.2.840.113549.1.1.13":     "SHA512-RSA",
+	"1.2.840.10040.4.1":         "DSA",
+	"1.2.840.10045.2.1":         "ECDSA",
+	"1.2.840.10045.4.1":         "ECDSA-SHA1",
+	"1.2.840.10045.4.3.2":       "ECDSA-SHA256",
+	"1.2.840.10045.4.3.3":       "ECDSA-SHA384",
+	"1.2.840.10045.4.3.4":       "ECDSA-SHA512",
+	"1.3.6.1.4.1.311.2.1.4":     "SPC_INDIRECT_DATA",
+	"1.2.840.113549.1.7.1":      "data",
+	"1.2.840.113549.1.9.16.1.4": "TSTInfo",
+}
+
+// AlgorithmName returns the usual name of a digest, signature or content
+// type OID, or the dotted OID when it is not known.
+func AlgorithmName(oid asn1.ObjectIdentifier) string {
+	if name, ok := algorithmNames[oid.String()]; ok {
+		return name
+	}
+	return oid.String()
+}
+
+// CertificateTypeName decodes WIN_CERTIFICATE.wCertificateType.
+func CertificateTypeName(certType uint16) string {
+	switch certType {
+	case WIN_CERT_TYPE_X509:
+		return "X509"
+	case WIN_CERT_TYPE_PKCS_SIGNED_DATA:
+		return "PKCS_SIGNED_DATA"
+	case WIN_CERT_TYPE_RESERVED_1:
+		return "RESERVED_1"
+	case WIN_CERT_TYPE_TS_STACK_SIGNED:
+		return "TS_STACK_SIGNED"
+	}
+	return fmt.Sprintf("0x%04X", certType)
+}
+
+// ASN.1 layouts of the PKCS#7 structures Authenticode uses.
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional,tag:0"` // [0] EXPLICIT, the wrapper is kept
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue     `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue     `asn1:"optional,tag:1"`
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7SignerInfo struct {
+	Version                   int
+	SignerIdentifier          asn1.RawValue
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type pkcs7IssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type pkcs7Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+type spcIndirectDataContent struct {
+	Data          asn1.RawValue
+	MessageDigest digestInfo
+}
+
+type digestInfo struct {
+	DigestAlgorithm pkix.AlgorithmIdentifier
+	Digest          []byte
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint digestInfo
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+}
+
+// CertificateEntry is one WIN_CERTIFICATE of the certificate table.
+type CertificateEntry struct {
+	Offset    uint32 // file offset of the WIN_CERTIFICATE header
+	Header    WIN_CERTIFICATE
+	Data      []byte // bCertificate
+	Signature *Signature
+	Err       error
+}
+
+// Signature is a decoded PKCS#7 SignedData blob.
+type Signature struct {
+	Offset          uint32 // file offset of the DER encoding
+	Raw             []byte
+	Version         int
+	DigestAlgorithm string
+	ContentType     string
+
+	// The image hash Authenticode signed, from SpcIndirectDataContent
+	FileDigestAlgorithm string
+	FileDigest          []byte
+	// Content is the DER body of the SpcIndirectDataContent, which the
+	// signer's messageDigest attribute is a hash of.
+	Content []byte
+
+	Certificates []*x509.Certificate
+	Signers      []SignerInfo
+	Nested       []*Signature
+}
+
+// SignerInfo is one signer of a SignedData, or the signer of a
+// countersignature.
+type SignerInfo struct {
+	Version             int
+	Issuer              string
+	SerialNumber        *big.Int
+	SubjectKeyID        []byte // set instead of Issuer and SerialNumber by version 3 signers
+	Certificate         *x509.Certificate
+	DigestAlgorithm     string
+	EncryptionAlgorithm string
+	EncryptedDigest     []byte
+	MessageDigest       []byte
+	SigningTime         time.Time
+	ProgramName         string
+	MoreInfo            string
+	// AuthenticatedAttributes is the DER of the signed attributes, which is
+	// what the encrypted digest signs once retagged as a SET.
+	AuthenticatedAttributes []byte
+	Countersignatures       []Countersignature
+}
+
+// Countersignature is a timestamp applied to a signer's encrypted digest,
+// either a legacy Authenticode countersignature or an RFC 3161 token.
+type Countersignature struct {
+	Type         string
+	Time         time.Time
+	Signer       SignerInfo
+	Certificates []*x509.Certificate // certificates shipped with an RFC 3161 token
+	Policy       string
+	SerialNumber *big.Int
+	// Content is the DER of an RFC 3161 token's TSTInfo, which the token
+	// signer's messageDigest attribute is a hash of. The TSTInfo in turn
+	// carries Imprint, the hash of the encrypted digest it timestamps.
+	Content          []byte
+	ImprintAlgorithm string
+	Imprint          []byte
+}
+
+// CertificateTable decodes the WIN_CERTIFICATE entries of the security
+// directory, whose VirtualAddress is a file offset rather than an RVA.
+func (p *PeFull) CertificateTable() ([]CertificateEntry, error) {
+	certDir, ok := p.Directory("Certificate Table")
+	if !ok || !certDir.Present() {
+		return nil, fmt.Errorf("no certificate table")
+	}
+
+	offset := certDir.VirtualAddress
+	end := uint64(offset) + uint64(certDir.Size)
+	if end > uint64(len(p.FileData)) {
+		return nil, fmt.Errorf("certificate table at 0x%X out of bounds", offset)
+	}
+
+	var entries []CertificateEntry
+	headerSize := uint32(binary.Size(WIN_CERTIFICATE{}))
+	for uint64(offset)+uint64(headerSize) <= end {
+		entry := CertificateEntry{Offset: offset}
+		if err := binary.Read(bytes.NewReader(p.FileData[offset:]), binary.LittleEndian, &entry.Header); err != nil {
+			return entries, err
+		}
+		length := entry.Header.Length
+		if length < headerSize || uint64(offset)+uint64(length) > end {
+			return entries, fmt.Errorf("bad certificate length %d at 0x%X", length, offset)
+		}
+		entry.Data = p.FileData[offset+headerSize : offset+length]
+
+		if entry.Header.CertificateType == WIN_CERT_TYPE_PKCS_SIGNED_DATA {
+			entry.Signature, entry.Err = parseSignature(entry.Data, offset+headerSize, 0)
+			if entry.Signature != nil {
+				locateNestedSignatures(entry.Signature, entry.Data, offset+headerSize)
+			}
+		}
+		entries = append(entries, entry)
+
+		// Entries are padded to 8 bytes
+		offset += (length + 7) &^ 7
+	}
+
+	return entries, nil
+}
+
+// Signatures returns every signature of the certificate table, with nested
+// signatures following the one they are attached to.
+func (p *PeFull) Signatures() ([]*Signature, error) {
+	entries, err := p.CertificateTable()
+	var signatures []*Signature
+	var walk func(signature *Signature)
+	walk = func(signature *Signature) {
+		signatures = append(signatures, signature)
+		for _, nested := range signature.Nested {
+			walk(nested)
+		}
+	}
+	for _, entry := range entries {
+		if entry.Signature != nil {
+			walk(entry.Signature)
+		}
+	}
+	return signatures, err
+}
+
+// locateNestedSignatures fills in the file offsets of the signatures nested
+// inside signature, whose DER data starts at file offset offset.
+func locateNestedSignatures(signature *Signature, data []byte, offset uint32) {
+	for _, nested := range signature.Nested {
+		if i := bytes.Index(data, nested.Raw); i >= 0 {
+			nested.Offset = offset + uint32(i)
+		}
+		locateNestedSignatures(nested, data, offset)
+	}
+}
+
+// parseSignature decodes a DER ContentInfo holding a PKCS#7 SignedData.
+// offset is the file offset of der, or 0 when it is not known.
+func parseSignature(der []byte, offset uint32, depth int) (*Signature, error) {
+	var content pkcs7ContentInfo
+	rest, err := asn1.Unmarshal(der, &content)
+	if err != nil {
+		return nil, fmt.Errorf("bad PKCS#7 ContentInfo: %v", err)
+	}
+	if !content.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unexpected PKCS#7 content type %s", content.ContentType)
+	}
+
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(content.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf("bad PKCS#7 SignedData: %v", err)
+	}
+
+	signature := &Signature{
+		Offset:      offset,
+		Raw:         der[:len(der)-len(rest)],
+		Version:     signedData.Version,
+		ContentType: AlgorithmName(signedData.ContentInfo.ContentType),
+	}
+	if len(signedData.DigestAlgorithms) > 0 {
+		signature.DigestAlgorithm = AlgorithmName(signedData.DigestAlgorithms[0].Algorithm)
+	}
+
+	// A certificate Go refuses is skipped rather than failing the whole
+	// signature, the error is still reported once everything else is read
+	var certErr error
+	for der := signedData.Certificates.Bytes; len(der) > 0; {
+		var value asn1.RawValue
+		rest, err := asn1.Unmarshal(der, &value)
+		if err != nil {
+			return signature, fmt.Errorf("bad certificate set: %v", err)
+		}
+		der = rest
+		// Tagged choices are attribute certificates, which are not shown
+		if value.Class != asn1.ClassUniversal {
+			continue
+		}
+		cert, err := x509.ParseCertificate(value.FullBytes)
+		if err != nil {
+			if certErr == nil {
+				certErr = fmt.Errorf("bad certificate: %v", err)
+			}
+		} else {
+			signature.Certificates = append(signature.Certificates, cert)
+		}
+	}
+
+	if signedData.ContentInfo.ContentType.Equal(oidSpcIndirectData) {
+		var inner asn1.RawValue
+		var indirect spcIndirectDataContent
+		if _, err := asn1.Unmarshal(signedData.ContentInfo.Content.Bytes, &inner); err != nil {
+			return signature, fmt.Errorf("bad SpcIndirectDataContent: %v", err)
+		}
+		if _, err := asn1.Unmarshal(inner.FullBytes, &indirect); err != nil {
+			return signature, fmt.Errorf("bad SpcIndirectDataContent: %v", err)
+		}
+		signature.FileDigestAlgorithm = AlgorithmName(indirect.MessageDigest.DigestAlgorithm.Algorithm)
+		signature.FileDigest = indirect.MessageDigest.Digest
+		signature.Content = inner.Bytes
+	}
+
+	for _, info := range signedData.SignerInfos {
+		signer, nested, err := parseSignerInfo(info, signature.Certificates, depth)
+		signature.Signers = append(signature.Signers, signer)
+		signature.Nested = append(signature.Nested, nested...)
+		if err != nil {
+			return signature, err
+		}
+	}
+
+	return signature, certErr
+}
+
+// parseSignerInfo decodes a SignerInfo and its attributes, returning any
+// nested signatures found among the unauthenticated attributes.
+func parseSignerInfo(info pkcs7SignerInfo, certificates []*x509.Certificate, depth int) (SignerInfo, []*Signature, error) {
+	signer := SignerInfo{
+		Version:             info.Version,
+		DigestAlgorithm:     AlgorithmName(info.DigestAlgorithm.Algorithm),
+		EncryptionAlgorithm: AlgorithmName(info.DigestEncryptionAlgorithm.Algorithm),
+		EncryptedDigest:     info.EncryptedDigest,
+	}
+
+	// Version 3 signers name their certificate by subject key identifier
+	if info.SignerIdentifier.Class == asn1.ClassContextSpecific && info.SignerIdentifier.Tag == 0 {
+		signer.SubjectKeyID = info.SignerIdentifier.Bytes
+	} else {
+		var issuerAndSerial pkcs7IssuerAndSerial
+		if _, err := asn1.Unmarshal(info.SignerIdentifier.FullBytes, &issuerAndSerial); err != nil {
+			return signer, nil, fmt.Errorf("bad signer identifier: %v", err)
+		}
+		signer.SerialNumber = issuerAndSerial.SerialNumber
+		var issuer pkix.RDNSequence
+		if _, err := asn1.Unmarshal(issuerAndSerial.Issuer.FullBytes, &issuer); err == nil {
+			var name pkix.Name
+			name.FillFromRDNSequence(&issuer)
+			signer.Issuer = name.String()
+		}
+	}
+	signer.Certificate = findSignerCertificate(certificates, signer)
+
+	if len(info.AuthenticatedAttributes.FullBytes) > 0 {
+		signer.AuthenticatedAttributes = info.AuthenticatedAttributes.FullBytes
+		attributes, err := parseAttributes(info.AuthenticatedAttributes.Bytes)
+		if err != nil {
+			return signer, nil, err
+		}
+		for _, attribute := range attributes {
+			switch {
+			case attribute.Type.Equal(oidMessageDigest):
+				asn1.Unmarshal(attribute.Values.Bytes, &signer.MessageDigest)
+			case attribute.Type.Equal(oidSigningTime):
+				asn1.Unmarshal(attribute.Values.Bytes, &signer.SigningTime)
+			case attribute.Type.Equal(oidSpcSpOpusInfo):
+				signer.ProgramName, signer.MoreInfo = parseOpusInfo(attribute.Values.Bytes)
+			}
+		}
+	}
+
+	var nested []*Signature
+	if len(info.UnauthenticatedAttributes.Bytes) == 0 {
+		return signer, nil, nil
+	}
+	attributes, err := parseAttributes(info.UnauthenticatedAttributes.Bytes)
+	if err != nil {
+		return signer, nil, err
+	}
+	// A damaged timestamp should not hide the nested signatures after it,
+	// so the first error is kept and returned at the end
+	var firstErr error
+	keep := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, attribute := range attributes {
+		switch {
+		case attribute.Type.Equal(oidCounterSignature):
+			var counterInfo pkcs7SignerInfo
+			if _, err := asn1.Unmarshal(attribute.Values.Bytes, &counterInfo); err != nil {
+				keep(fmt.Errorf("bad countersignature: %v", err))
+				continue
+			}
+			counterSigner, _, err := parseSignerInfo(counterInfo, certificates, depth)
+			signer.Countersignatures = append(signer.Countersignatures, Countersignature{
+				Type:   "Authenticode",
+				Time:   counterSigner.SigningTime,
+				Signer: counterSigner,
+			})
+			if err != nil {
+				keep(err)
+			}
+		case attribute.Type.Equal(oidRFC3161Timestamp):
+			countersignature, err := parseTimestampToken(attribute.Values.Bytes, depth)
+			signer.Countersignatures = append(signer.Countersignatures, countersignature)
+			if err != nil {
+				keep(err)
+			}
+		case attribute.Type.Equal(oidNestedSignature):
+			if depth >= maxNestedSignatures {
+				keep(fmt.Errorf("signatures nested more than %d deep", maxNestedSignatures))
+				continue
+			}
+			// Each value of the attribute is a whole signature
+			for values := attribute.Values.Bytes; len(values) > 0; {
+				var value asn1.RawValue
+				rest, err := asn1.Unmarshal(values, &value)
+				if err != nil {
+					keep(fmt.Errorf("bad nested signature: %v", err))
+					break
+				}
+				signature, err := parseSignature(value.FullBytes, 0, depth+1)
+				if signature != nil {
+					nested = append(nested, signature)
+				}
+				if err != nil {
+					keep(err)
+				}
+				values = rest
+			}
+		}
+	}
+	return signer, nested, firstErr
+}
+
+// parseAttributes decodes the contents of a SET OF Attribute.
+func parseAttributes(der []byte) ([]pkcs7Attribute, error) {
+	var attributes []pkcs7Attribute
+	for len(der) > 0 {
+		var attribute pkcs7Attribute
+		rest, err := asn1.Unmarshal(der, &attribute)
+		if err != nil {
+			return attributes, fmt.Errorf("bad PKCS#7 attribute: %v", err)
+		}
+		attributes = append(attributes, attribute)
+		der = rest
+	}
+	return attributes, nil
+}
+
+// parseTimestampToken decodes an RFC 3161 timestamp token, a SignedData
+// whose content is a TSTInfo.
+func parseTimestampToken(der []byte, depth int) (Countersignature, error) {
+	countersignature := Countersignature{Type: "RFC 3161"}
+	token, tokenErr := parseSignature(der, 0, depth+1)
+	if token == nil {
+		return countersignature, fmt.Errorf("bad timestamp token: %v", tokenErr)
+	}
+	countersignature.Certificates = token.Certificates
+	if len(token.Signers) > 0 {
+		countersignature.Signer = token.Signers[0]
+	}
+
+	// Re-read the content, which parseSignature only decodes for
+	// Authenticode signatures
+	var content pkcs7ContentInfo
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(der, &content); err != nil {
+		return countersignature, err
+	}
+	if _, err := asn1.Unmarshal(content.Content.Bytes, &signedData); err != nil {
+		return countersignature, err
+	}
+	if !signedData.ContentInfo.ContentType.Equal(oidTSTInfo) {
+		return countersignature, fmt.Errorf("unexpected timestamp content type %s", signedData.ContentInfo.ContentType)
+	}
+	var tstInfoDER []byte
+	if _, err := asn1.Unmarshal(signedData.ContentInfo.Content.Bytes, &tstInfoDER); err != nil {
+		return countersignature, fmt.Errorf("bad TSTInfo: %v", err)
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(tstInfoDER, &info); err != nil {
+		return countersignature, fmt.Errorf("bad TSTInfo: %v", err)
+	}
+	countersignature.Time = info.GenTime
+	countersignature.Content = tstInfoDER
+	countersignature.ImprintAlgorithm = AlgorithmName(info.MessageImprint.DigestAlgorithm.Algorithm)
+	countersignature.Imprint = info.MessageImprint.Digest
+	countersignature.Policy = info.Policy.String()
+	countersignature.SerialNumber = info.SerialNumber
+	if tokenErr != nil {
+		return countersignature, fmt.Errorf("bad timestamp token: %v", tokenErr)
+	}
+	return countersignature, nil
+}
+
+// parseOpusInfo decodes SpcSpOpusInfo, the program name and URL the
+// publisher attached to the signature.
+func parseOpusInfo(der []byte) (string, string) {
+	var opus struct {
+		ProgramName asn1.RawValue `asn1:"optional,tag:0"`
+		MoreInfo    asn1.RawValue `asn1:"optional,tag:1"`
+	}
+	if _, err := asn1.Unmarshal(der, &opus); err != nil {
+		return "", ""
+	}
+	// Both fields are EXPLICIT tagged CHOICEs
+	var programName, moreInfo asn1.RawValue
+	asn1.Unmarshal(opus.ProgramName.Bytes, &programName)
+	asn1.Unmarshal(opus.MoreInfo.Bytes, &moreInfo)
+	return spcString(programName), spcLink(moreInfo)
+}
+
+// spcString decodes SpcString, a CHOICE of a BMPString [0] or an
+// IA5String [1].
+func spcString(value asn1.RawValue) string {
+	switch value.Tag {
+	case 0:
+		units := make([]uint16, len(value.Bytes)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(value.Bytes[2*i:])
+		}
+		return string(utf16.Decode(units))
+	case 1:
+		return string(value.Bytes)
+	}
+	return ""
+}
+
+// spcLink decodes SpcLink, of which only the url [0] choice is shown.
+func spcLink(value asn1.RawValue) string {
+	if value.Tag == 0 {
+		return string(value.Bytes)
+	}
+	return ""
+}
+
+// findSignerCertificate returns the certificate a signer identifies, or nil.
+func findSignerCertificate(certificates []*x509.Certificate, signer SignerInfo) *x509.Certificate {
+	for _, cert := range certificates {
+		if signer.SubjectKeyID != nil {
+			if bytes.Equal(cert.SubjectKeyId, signer.SubjectKeyID) {
+				return cert
+			}
+			continue
+		}
+		if signer.SerialNumber != nil && cert.SerialNumber.Cmp(signer.SerialNumber) == 0 && cert.Issuer.String() == signer.Issuer {
+			return cert
+		}
+	}
+	return nil
+}
+
+// CertificateOffset returns the file offset of a certificate embedded in
+// the signature, including those of its timestamp tokens, or 0.
+func (s *Signature) CertificateOffset(cert *x509.Certificate) uint32 {
+	if i := bytes.Index(s.Raw, cert.Raw); i >= 0 && s.Offset != 0 {
+		return s.Offset + uint32(i)
+	}
+	return 0
+}
+
+// CertificateChain follows the issuers of cert through certificates, as far
+// as they are included, starting with cert itself.
+func CertificateChain(cert *x509.Certificate, certificates []*x509.Certificate) []*x509.Certificate {
+	var chain []*x509.Certificate
+	for cert != nil && len(chain) <= len(certificates) {
+		chain = append(chain, cert)
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			break
+		}
+		var issuer *x509.Certificate
+		for _, candidate := range certificates {
+			if bytes.Equal(candidate.RawSubject, cert.RawIssuer) && candidate != cert {
+				issuer = candidate
+				break
+			}
+		}
+		cert = issuer
+	}
+	return chain
+}
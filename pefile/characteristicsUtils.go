@@ -0,0 +1,134 @@
+package pefile
+
+import "fmt"
+
+type flagName struct {
+	Flag uint32
+	Name string
+}
+
+var fileCharacteristicNames = []flagName{
+	{0x0001, "RELOCS_STRIPPED"},
+	{0x0002, "EXECUTABLE_IMAGE"},
+	{0x0004, "LINE_NUMS_STRIPPED"},
+	{0x0008, "LOCAL_SYMS_STRIPPED"},
+	{0x0010, "AGGRESSIVE_WS_TRIM"},
+	{0x0020, "LARGE_ADDRESS_AWARE"},
+	{0x0080, "BYTES_REVERSED_LO"},
+	{0x0100, "32BIT_MACHINE"},
+	{0x0200, "DEBUG_STRIPPED"},
+	{0x0400, "REMOVABLE_RUN_FROM_SWAP"},
+	{0x0800, "NET_RUN_FROM_SWAP"},
+	{0x1000, "SYSTEM"},
+	{0x2000, "DLL"},
+	{0x4000, "UP_SYSTEM_ONLY"},
+	{0x8000, "BYTES_REVERSED_HI"},
+}
+
+// fileCharacteristicsReserved is the bit of the file header Characteristics
+// that winnt.h reserves.
+const fileCharacteristicsReserved = 0x0040
+
+var dllCharacteristicNames = []flagName{
+	{0x0020, "HIGH_ENTROPY_VA"},
+	{0x0040, "DYNAMIC_BASE"},
+	{0x0080, "FORCE_INTEGRITY"},
+	{0x0100, "NX_COMPAT"},
+	{0x0200, "NO_ISOLATION"},
+	{0x0400, "NO_SEH"},
+	{0x0800, "NO_BIND"},
+	{0x1000, "APPCONTAINER"},
+	{0x2000, "WDM_DRIVER"},
+	{0x4000, "GUARD_CF"},
+	{0x8000, "TERMINAL_SERVER_AWARE"},
+}
+
+// dllCharacteristicsReserved are the low bits of DllCharacteristics, which
+// the PE format reserves and requires to be zero.
+const dllCharacteristicsReserved = 0x001F
+
+var sectionCharacteristicNames = []flagName{
+	{0x00000008, "TYPE_NO_PAD"},
+	{0x00000020, "CNT_CODE"},
+	{0x00000040, "CNT_INITIALIZED_DATA"},
+	{0x00000080, "CNT_UNINITIALIZED_DATA"},
+	{0x00000200, "LNK_INFO"},
+	{0x00000800, "LNK_REMOVE"},
+	{0x00001000, "LNK_COMDAT"},
+	{0x00004000, "NO_DEFER_SPEC_EXC"},
+	{0x00008000, "GPREL"},
+	{0x01000000, "LNK_NRELOC_OVFL"},
+	{0x02000000, "MEM_DISCARDABLE"},
+	{0x04000000, "MEM_NOT_CACHED"},
+	{0x08000000, "MEM_NOT_PAGED"},
+	{0x10000000, "MEM_SHARED"},
+	{0x20000000, "MEM_EXECUTE"},
+	{0x40000000, "MEM_READ"},
+	{0x80000000, "MEM_WRITE"},
+}
+
+const (
+	// sectionCharacteristicsReserved are the section flags the PE format
+	// reserves, among them the old TYPE_*, LNK_OTHER, MEM_16BIT, MEM_LOCKED
+	// and MEM_PRELOAD bits.
+	sectionCharacteristicsReserved = 0x000F2517
+	sectionAlignMask               = 0x00F00000
+	sectionAlignShift              = 20
+)
+
+// Section flags that mark code, so that callers don't need debug/pe for them.
+const (
+	IMAGE_SCN_CNT_CODE    = 0x00000020
+	IMAGE_SCN_MEM_EXECUTE = 0x20000000
+)
+
+// decodeFlags names the set bits of flags. Reserved bits and bits without a
+// name are listed in hex so they stand out.
+func decodeFlags(flags uint32, names []flagName, reserved uint32) []string {
+	var decoded []string
+	for _, f := range names {
+		if flags&f.Flag != 0 {
+			decoded = append(decoded, f.Name)
+			flags &^= f.Flag
+		}
+	}
+	if flags&reserved != 0 {
+		decoded = append(decoded, fmt.Sprintf("RESERVED 0x%X", flags&reserved))
+		flags &^= reserved
+	}
+	if flags != 0 {
+		decoded = append(decoded, fmt.Sprintf("UNKNOWN 0x%X", flags))
+	}
+	return decoded
+}
+
+// FileCharacteristicNames decodes the IMAGE_FILE_* bits of the file header
+// Characteristics.
+func FileCharacteristicNames(flags uint16) []string {
+	return decodeFlags(uint32(flags), fileCharacteristicNames, fileCharacteristicsReserved)
+}
+
+// DllCharacteristicNames decodes the IMAGE_DLLCHARACTERISTICS_* bits of the
+// optional header DllCharacteristics.
+func DllCharacteristicNames(flags uint16) []string {
+	return decodeFlags(uint32(flags), dllCharacteristicNames, dllCharacteristicsReserved)
+}
+
+// SectionCharacteristicNames decodes the IMAGE_SCN_* bits of a section
+// header, with the alignment code in bits 20-23 shown as ALIGN_<n>BYTES.
+// Alignment code 0xF is not defined and is reported as reserved.
+func SectionCharacteristicNames(flags uint32) []string {
+	var align string
+	switch code := flags & sectionAlignMask >> sectionAlignShift; {
+	case code == 0:
+	case code < 0xF:
+		align = fmt.Sprintf("ALIGN_%dBYTES", 1<<(code-1))
+		flags &^= sectionAlignMask
+	}
+
+	decoded := decodeFlags(flags, sectionCharacteristicNames, sectionCharacteristicsReserved|sectionAlignMask)
+	if align != "" {
+		decoded = append(decoded, align)
+	}
+	return decoded
+}
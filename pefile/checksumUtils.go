@@ -0,0 +1,42 @@
+package pefile
+
+import "encoding/binary"
+
+// StoredCheckSum returns the CheckSum field of the optional header.
+func (p *PeFull) StoredCheckSum() uint32 {
+	switch hdr := p.PeFile.OptionalHeader.(type) {
+	case *OptionalHeader32:
+		return hdr.CheckSum
+	case *OptionalHeader64:
+		return hdr.CheckSum
+	}
+	return 0
+}
+
+// ComputeCheckSum computes the image checksum the way ImageHlp's
+// CheckSumMappedFile does: a 16-bit one's complement style sum of the whole
+// file with the CheckSum field taken as zero, plus the file length.
+func (p *PeFull) ComputeCheckSum() uint32 {
+	data := p.FileData
+	checkSumOffset := int(p.OptionalHeaderOffset()) + checkSumFieldOffset
+
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		if i >= checkSumOffset && i < checkSumOffset+4 {
+			continue
+		}
+		sum += uint32(binary.LittleEndian.Uint16(data[i:]))
+		sum = sum&0xFFFF + sum>>16
+	}
+	// An odd trailing byte is summed as if padded with a zero
+	if len(data)%2 != 0 {
+		sum += uint32(data[len(data)-1])
+		sum = sum&0xFFFF + sum>>16
+	}
+	return sum + uint32(len(data))
+}
+
+// CheckSumMatches reports whether the stored CheckSum is the computed one.
+func (p *PeFull) CheckSumMatches() bool {
+	return p.StoredCheckSum() == p.ComputeCheckSum()
+}
@@ -0,0 +1,57 @@
+package pefile
+
+import "testing"
+
+// checksumTestImage has a section with enough non-zero bytes to carry the
+// 16-bit sum many times, followed by overlay.
+func checksumTestImage(overlay []byte) testImage {
+	text := make([]byte, 0x400)
+	for i := range text {
+		text[i] = byte(i * 7)
+	}
+	return testImage{
+		timeDateStamp: 0x5F5E1000,
+		sections:      []testSection{{name: ".text", data: text, characteristics: 0x60000020}},
+		overlay:       overlay,
+	}
+}
+
+// The expected values were computed with an independent implementation
+// that reproduces the CheckSum of signed Windows binaries.
+func TestComputeCheckSum(t *testing.T) {
+	tests := []struct {
+		name    string
+		overlay []byte
+		want    uint32
+	}{
+		{"even length", nil, 0x791A},
+		{"odd length", []byte{0xAB, 0xCD, 0xEF}, 0x47B8},
+	}
+	for _, tt := range tests {
+		p := checksumTestImage(tt.overlay).parse(t)
+		if got := p.ComputeCheckSum(); got != tt.want {
+			t.Errorf("%s: ComputeCheckSum = 0x%X, want 0x%X", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The stored CheckSum itself does not take part in the sum.
+func TestCheckSumMatches(t *testing.T) {
+	for _, pe32 := range []bool{false, true} {
+		image := checksumTestImage(nil)
+		image.pe32 = pe32
+		want := image.parse(t).ComputeCheckSum()
+
+		image.checkSum = want
+		p := image.parse(t)
+		if p.StoredCheckSum() != want || p.ComputeCheckSum() != want || !p.CheckSumMatches() {
+			t.Errorf("PE32 %v: stored 0x%X, computed 0x%X, want both 0x%X", pe32, p.StoredCheckSum(), p.ComputeCheckSum(), want)
+		}
+
+		image.checkSum = 0xDEADBEEF
+		p = image.parse(t)
+		if p.StoredCheckSum() != 0xDEADBEEF || p.ComputeCheckSum() != want || p.CheckSumMatches() {
+			t.Errorf("PE32 %v: stored 0x%X, computed 0x%X matches", pe32, p.StoredCheckSum(), p.ComputeCheckSum())
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// IMAGE_COR20_HEADER is the CLR runtime header of a managed image. The data
+// directories it holds are flattened into RVA/size pairs.
+type IMAGE_COR20_HEADER struct {
+	Cb                          uint32
+	MajorRuntimeVersion         uint16
+	MinorRuntimeVersion         uint16
+	MetaDataRVA                 uint32
+	MetaDataSize                uint32
+	Flags                       uint32
+	EntryPointToken             uint32 // an RVA with COMIMAGE_FLAGS_NATIVE_ENTRYPOINT
+	ResourcesRVA                uint32
+	ResourcesSize               uint32
+	StrongNameSignatureRVA      uint32
+	StrongNameSignatureSize     uint32
+	CodeManagerTableRVA         uint32
+	CodeManagerTableSize        uint32
+	VTableFixupsRVA             uint32
+	VTableFixupsSize            uint32
+	ExportAddressTableJumpsRVA  uint32
+	ExportAddressTableJumpsSize uint32
+	ManagedNativeHeaderRVA      uint32
+	ManagedNativeHeaderSize     uint32
+}
+
+const COMIMAGE_FLAGS_NATIVE_ENTRYPOINT = 0x00000010
+
+var comImageFlagNames = []struct {
+	Flag uint32
+	Name string
+}{
+	{0x00000001, "ILONLY"},
+	{0x00000002, "32BITREQUIRED"},
+	{0x00000004, "IL_LIBRARY"},
+	{0x00000008, "STRONGNAMESIGNED"},
+	{COMIMAGE_FLAGS_NATIVE_ENTRYPOINT, "NATIVE_ENTRYPOINT"},
+	{0x00010000, "TRACKDEBUGDATA"},
+	{0x00020000, "32BITPREFERRED"},
+}
+
+// ComImageFlagNames decodes the COMIMAGE_FLAGS_* bits of the CLR header
+// Flags; unknown bits are listed in hex.
+func ComImageFlagNames(flags uint32) []string {
+	var names []string
+	for _, f := range comImageFlagNames {
+		if flags&f.Flag != 0 {
+			names = append(names, f.Name)
+			flags &^= f.Flag
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("0x%X", flags))
+	}
+	return names
+}
+
+type ClrHeader struct {
+	Offset uint32 // file offset of the header
+	Header IMAGE_COR20_HEADER
+}
+
+// ClrHeader reads the IMAGE_COR20_HEADER the CLR Runtime Header directory
+// points to.
+func (p *PeFull) ClrHeader() (*ClrHeader, error) {
+	clrDir, ok := p.Directory("CLR Runtime Header")
+	if !ok || !clrDir.Present() {
+		return nil, fmt.Errorf("no CLR runtime header")
+	}
+
+	size := uint32(binary.Size(IMAGE_COR20_HEADER{}))
+	offset, ok := p.FileBackedOffset(clrDir.VirtualAddress, size)
+	if !ok {
+		return nil, fmt.Errorf("CLR runtime header at RVA 0x%X out of bounds", clrDir.VirtualAddress)
+	}
+
+	clr := &ClrHeader{Offset: offset}
+	reader := bytes.NewReader(p.FileData[offset:])
+	if err := binary.Read(reader, binary.LittleEndian, &clr.Header); err != nil {
+		return nil, err
+	}
+	return clr, nil
+}
+
+// MetadataRoot is the fixed part of the metadata root, ahead of the version
+// string.
+type MetadataRoot struct {
+	Signature    uint32
+	MajorVersion uint16
+	MinorVersion uint16
+	Reserved     uint32
+	Length       uint32
+}
+
+// metadataSignature is "BSJB", the signature of the metadata root.
+const metadataSignature = 0x424A5342
+
+type MetadataStream struct {
+	HeaderOffset uint32 // file offset of the stream header
+	Offset       uint32 // from the start of the metadata root
+	Size         uint32
+	Name         string
+	FileOffset   uint32
+	Data         []byte
+}
+
+type Metadata struct {
+	Offset      uint32 // file offset of the metadata root
+	Root        MetadataRoot
+	Version     string
+	Flags       uint16
+	Streams     []MetadataStream
+	Tables      *MetadataTables // nil when there is no #~ stream
+	TablesErr   error
+	strings     []byte
+	userStrings []byte
+	guids       []byte
+	blobs       []byte
+}
+
+// Stream returns the stream with the given name, or nil.
+func (m *Metadata) Stream(name string) *MetadataStream {
+	for i := range m.Streams {
+		if m.Streams[i].Name == name {
+			return &m.Streams[i]
+		}
+	}
+	return nil
+}
+
+// Metadata decodes the metadata root the CLR header points to, its stream
+// headers and the tables of its #~ stream.
+func (p *PeFull) Metadata() (*Metadata, error) {
+	clr, err := p.ClrHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	header := clr.Header
+	offset, ok := p.FileBackedOffset(header.MetaDataRVA, header.MetaDataSize)
+	if !ok {
+		return nil, fmt.Errorf("metadata at RVA 0x%X out of bounds", header.MetaDataRVA)
+	}
+	data := p.FileData[offset : offset+header.MetaDataSize]
+
+	metadata := &Metadata{Offset: offset}
+	reader := bytes.NewReader(data)
+	if err := binary.Read(reader, binary.LittleEndian, &metadata.Root); err != nil {
+		return nil, err
+	}
+	if metadata.Root.Signature != metadataSignature {
+		return nil, fmt.Errorf("bad metadata signature 0x%X", metadata.Root.Signature)
+	}
+
+	// The version string is padded to a multiple of four bytes
+	pos := uint32(binary.Size(metadata.Root))
+	if uint64(pos)+uint64(metadata.Root.Length)+4 > uint64(len(data)) {
+		return nil, fmt.Errorf("metadata version string out of bounds")
+	}
+	metadata.Version = cString(data[pos : pos+metadata.Root.Length])
+	pos += metadata.Root.Length
+	metadata.Flags = binary.LittleEndian.Uint16(data[pos:])
+	streamCount := binary.LittleEndian.Uint16(data[pos+2:])
+	pos += 4
+
+	for i := 0; i < int(streamCount); i++ {
+		if uint64(pos)+8 > uint64(len(data)) {
+			return metadata, fmt.Errorf("stream header %d out of bounds", i)
+		}
+		stream := MetadataStream{
+			HeaderOffset: offset + pos,
+			Offset:       binary.LittleEndian.Uint32(data[pos:]),
+			Size:         binary.LittleEndian.Uint32(data[pos+4:]),
+		}
+		pos += 8
+		nameEnd := bytes.IndexByte(data[pos:], 0)
+		if nameEnd < 0 {
+			return metadata, fmt.Errorf("stream header %d has no name", i)
+		}
+		stream.Name = string(data[pos : pos+uint32(nameEnd)])
+		pos += (uint32(nameEnd) + 4) &^ 3
+		if uint64(stream.Offset)+uint64(stream.Size) > uint64(len(data)) {
+			return metadata, fmt.Errorf("stream %s out of bounds", stream.Name)
+		}
+		stream.FileOffset = offset + stream.Offset
+		stream.Data = data[stream.Offset : stream.Offset+stream.Size]
+		metadata.Streams = append(metadata.Streams, stream)
+	}
+
+	for _, stream := range metadata.Streams {
+		switch stream.Name {
+		case "#Strings":
+			metadata.strings = stream.Data
+		case "#US":
+			metadata.userStrings = stream.Data
+		case "#GUID":
+			metadata.guids = stream.Data
+		case "#Blob":
+			metadata.blobs = stream.Data
+		}
+	}
+	// "#-" is the uncompressed form of the tables stream
+	for _, stream := range metadata.Streams {
+		if stream.Name == "#~" || stream.Name == "#-" {
+			metadata.Tables, metadata.TablesErr = parseMetadataTables(stream)
+			break
+		}
+	}
+
+	return metadata, nil
+}
+
+func cString(data []byte) string {
+	if n := bytes.IndexByte(data, 0); n >= 0 {
+		data = data[:n]
+	}
+	return string(data)
+}
+
+// String reads the #Strings heap entry at index.
+func (m *Metadata) String(index uint32) string {
+	if index >= uint32(len(m.strings)) {
+		return ""
+	}
+	return cString(m.strings[index:])
+}
+
+// Guid formats the 1-based #GUID heap entry index, or returns "" for none.
+func (m *Metadata) Guid(index uint32) string {
+	if index == 0 || uint64(index)*16 > uint64(len(m.guids)) {
+		return ""
+	}
+	return formatGuid(m.guids[(index-1)*16 : index*16])
+}
+
+func formatGuid(g []byte) string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}",
+		binary.LittleEndian.Uint32(g), binary.LittleEndian.Uint16(g[4:]), binary.LittleEndian.Uint16(g[6:]), g[8:10], g[10:16])
+}
+
+// Blob reads the #Blob heap entry at index.
+func (m *Metadata) Blob(index uint32) []byte {
+	blob, _, ok := readHeapBlob(m.blobs, index)
+	if !ok {
+		return nil
+	}
+	return blob
+}
+
+// readHeapBlob reads the blob at index of a #Blob or #US heap, which starts
+// with its ECMA-335 compressed length, and returns the index past it.
+func readHeapBlob(heap []byte, index uint32) ([]byte, uint32, bool) {
+	if index >= uint32(len(heap)) {
+		return nil, 0, false
+	}
+	b := heap[index:]
+	var length, prefix uint32
+	switch {
+	case b[0]&0x80 == 0:
+		length, prefix = uint32(b[0]), 1
+	case b[0]&0xC0 == 0x80 && len(b) >= 2:
+		length, prefix = uint32(b[0]&0x3F)<<8|uint32(b[1]), 2
+	case b[0]&0xE0 == 0xC0 && len(b) >= 4:
+		length, prefix = uint32(b[0]&0x1F)<<24|uint32(b[1])<<16|uint32(b[2])<<8|uint32(b[3]), 4
+	default:
+		return nil, 0, false
+	}
+	if uint64(prefix)+uint64(length) > uint64(len(b)) {
+		return nil, 0, false
+	}
+	return b[prefix : prefix+length], index + prefix + length, true
+}
+
+// HeapEntry is one entry of a metadata heap.
+type HeapEntry struct {
+	Index      uint32 // heap index that tables and tokens refer to
+	FileOffset uint32
+	Size       uint32
+	Value      string
+}
+
+// HeapEntries lists the entries of the #Strings, #US, #GUID or #Blob
+// stream.
+func (m *Metadata) HeapEntries(name string) ([]HeapEntry, error) {
+	stream := m.Stream(name)
+	if stream == nil {
+		return nil, fmt.Errorf("no %s stream", name)
+	}
+
+	var entries []HeapEntry
+	switch name {
+	case "#Strings":
+		for index := uint32(0); index < stream.Size; {
+			value := cString(stream.Data[index:])
+			entries = append(entries, HeapEntry{index, stream.FileOffset + index, uint32(len(value)) + 1, value})
+			index += uint32(len(value)) + 1
+		}
+	case "#GUID":
+		for index := uint32(0); index+16 <= stream.Size; index += 16 {
+			entries = append(entries, HeapEntry{index/16 + 1, stream.FileOffset + index, 16, formatGuid(stream.Data[index:])})
+		}
+	case "#US", "#Blob":
+		for index := uint32(0); index < stream.Size; {
+			blob, next, ok := readHeapBlob(stream.Data, index)
+			if !ok {
+				return entries, fmt.Errorf("bad %s entry at index 0x%X", name, index)
+			}
+			entry := HeapEntry{index, stream.FileOffset + index, next - index, ""}
+			if name == "#US" {
+				entry.Value = decodeUserString(blob)
+			} else {
+				entry.Value = formatBlob(blob)
+			}
+			entries = append(entries, entry)
+			index = next
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a heap", name)
+	}
+	return entries, nil
+}
+
+// decodeUserString decodes a #US entry: UTF-16 text followed by a flag byte.
+func decodeUserString(blob []byte) string {
+	units := make([]uint16, len(blob)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(blob[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// blobPreview is how many bytes of a blob are shown.
+const blobPreview = 32
+
+func formatBlob(blob []byte) string {
+	if len(blob) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, min(len(blob), blobPreview))
+	for _, b := range blob[:min(len(blob), blobPreview)] {
+		parts = append(parts, fmt.Sprintf("%02X", b))
+	}
+	preview := strings.Join(parts, " ")
+	if len(blob) > blobPreview {
+		preview += fmt.Sprintf(" ... (%d bytes)", len(blob))
+	}
+	return preview
+}
@@ -0,0 +1,346 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testStream is a metadata stream for testMetadata.
+type testStream struct {
+	name string
+	data []byte
+}
+
+// testMetadata lays out a metadata root with the given streams, each padded
+// to four bytes.
+func testMetadata(streams []testStream) []byte {
+	headers := newTestData(0)
+	headers.putStruct(MetadataRoot{Signature: metadataSignature, MajorVersion: 1, MinorVersion: 1, Length: 12})
+	headers.put([]byte("v4.0.30319\x00\x00"))
+	headers.putStruct([]uint16{0, uint16(len(streams))})
+	headersSize := len(headers.bytes())
+	for _, stream := range streams {
+		headersSize += 8 + int(alignUp(uint32(len(stream.name)+1), 4))
+	}
+
+	body := newTestData(uint32(headersSize))
+	for _, stream := range streams {
+		offset := body.put(stream.data)
+		body.align(4)
+		headers.putStruct([]uint32{offset, uint32(len(stream.data))})
+		headers.put([]byte(stream.name + "\x00"))
+		headers.align(4)
+	}
+	return append(headers.bytes(), body.bytes()...)
+}
+
+// testTablesStream builds a #~ stream holding the tables in valid with the
+// given row counts, followed by rows.
+func testTablesStream(heapSizes uint8, valid uint64, rowCounts []uint32, rows ...any) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, MetadataTablesHeader{MajorVersion: 2, HeapSizes: heapSizes, Reserved2: 1, Valid: valid})
+	binary.Write(buf, binary.LittleEndian, rowCounts)
+	if heapSizes&heapSizesExtraData != 0 {
+		buf.Write([]byte{0xEE, 0xEE, 0xEE, 0xEE})
+	}
+	for _, row := range rows {
+		binary.Write(buf, binary.LittleEndian, row)
+	}
+	return buf.Bytes()
+}
+
+// The #Strings heap offsets of testStrings.
+const (
+	stringModule    = 1
+	stringTestDll   = 10
+	stringProgram   = 19
+	stringApp       = 27
+	stringMain      = 31
+	stringObject    = 36
+	stringSystem    = 43
+	testStringsSize = 50
+)
+
+const testStrings = "\x00<Module>\x00test.dll\x00Program\x00App\x00Main\x00Object\x00System\x00"
+
+// testMetadataStreams describes a module with a Program class in the App
+// namespace deriving from System.Object, and its Main method.
+func testMetadataStreams() []testStream {
+	tables := testTablesStream(0,
+		1<<MetadataModule|1<<MetadataTypeRef|1<<MetadataTypeDef|1<<MetadataMethodDef,
+		[]uint32{1, 1, 2, 1},
+		// Module: Generation, Name, Mvid, EncId, EncBaseId
+		[]uint16{0, stringTestDll, 1, 0, 0},
+		// TypeRef: ResolutionScope (Module 1), TypeName, TypeNamespace
+		[]uint16{1<<2 | 0, stringObject, stringSystem},
+		// TypeDef: Flags, TypeName, TypeNamespace, Extends, FieldList, MethodList
+		uint32(0), []uint16{stringModule, 0, 0, 1, 1},
+		uint32(0x00100001), []uint16{stringProgram, stringApp, 1<<2 | 1, 1, 1},
+		// MethodDef: RVA, ImplFlags, Flags, Name, Signature, ParamList
+		uint32(0x2050), []uint16{0, 0x96, stringMain, 1, 1},
+	)
+
+	longBlob := append([]byte{0x80, 0x40}, bytes.Repeat([]byte{0xAB}, 0x40)...)
+	return []testStream{
+		{"#~", tables},
+		{"#Strings", []byte(testStrings)},
+		{"#US", []byte{0, 5, 'H', 0, 'i', 0, 0}},
+		{"#GUID", []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}},
+		{"#Blob", append([]byte{0, 3, 0, 0, 1}, longBlob...)},
+	}
+}
+
+// clrTestImage places the CLR header followed by the metadata in .text.
+func clrTestImage(metadata []byte) testImage {
+	text := newTestData(testSectionRVA(0))
+	header := text.put(make([]byte, binary.Size(IMAGE_COR20_HEADER{})))
+	text.align(4)
+	root := text.put(metadata)
+
+	section := text.bytes()
+	cor20 := newTestData(header)
+	cor20.putStruct(IMAGE_COR20_HEADER{
+		Cb:                  72,
+		MajorRuntimeVersion: 2,
+		MinorRuntimeVersion: 5,
+		MetaDataRVA:         root,
+		MetaDataSize:        uint32(len(metadata)),
+		Flags:               0x00000009,
+		EntryPointToken:     Token(MetadataMethodDef, 1),
+	})
+	copy(section, cor20.bytes())
+
+	return testImage{
+		pe32:     true,
+		sections: []testSection{{name: ".text", data: section, characteristics: 0x60000020}},
+		dirs:     map[int]DataDirectory{14: {VirtualAddress: header, Size: 72}},
+	}
+}
+
+func TestClrHeader(t *testing.T) {
+	clr, err := clrTestImage(testMetadata(testMetadataStreams())).parse(t).ClrHeader()
+	if err != nil {
+		t.Fatalf("ClrHeader: %v", err)
+	}
+	if clr.Offset != testFileAlignment || clr.Header.MinorRuntimeVersion != 5 || clr.Header.EntryPointToken != 0x06000001 {
+		t.Errorf("CLR header at 0x%X = %+v", clr.Offset, clr.Header)
+	}
+	if got, want := ComImageFlagNames(clr.Header.Flags|0x100), []string{"ILONLY", "STRONGNAMESIGNED", "0x100"}; !slices.Equal(got, want) {
+		t.Errorf("ComImageFlagNames = %q, want %q", got, want)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	metadata, err := clrTestImage(testMetadata(testMetadataStreams())).parse(t).Metadata()
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if metadata.Version != "v4.0.30319" || len(metadata.Streams) != 5 {
+		t.Fatalf("metadata %q with %d streams", metadata.Version, len(metadata.Streams))
+	}
+	strs := metadata.Stream("#Strings")
+	if strs == nil || strs.Size != testStringsSize || strs.FileOffset != metadata.Offset+strs.Offset || string(strs.Data) != testStrings {
+		t.Errorf("#Strings stream = %+v", strs)
+	}
+	if metadata.Stream("#Pdb") != nil {
+		t.Error("found a stream that doesn't exist")
+	}
+
+	if metadata.TablesErr != nil || metadata.Tables == nil || len(metadata.Tables.Tables) != 4 {
+		t.Fatalf("tables = %+v, %v", metadata.Tables, metadata.TablesErr)
+	}
+	typeDef := metadata.Tables.Table(MetadataTypeDef)
+	if typeDef == nil || typeDef.RowCount != 2 || typeDef.RowSize != 14 {
+		t.Fatalf("TypeDef table = %+v", typeDef)
+	}
+	if metadata.Tables.Table(MetadataField) != nil {
+		t.Error("found a table that isn't present")
+	}
+
+	tests := []struct {
+		table  int
+		row    int
+		column string
+		want   string
+	}{
+		{MetadataModule, 1, "Name", "test.dll"},
+		{MetadataModule, 1, "Mvid", "{03020100-0504-0706-0809-0A0B0C0D0E0F}"},
+		{MetadataModule, 1, "EncId", ""},
+		{MetadataTypeRef, 1, "ResolutionScope", "Module[1] test.dll"},
+		{MetadataTypeDef, 1, "Extends", "null"},
+		{MetadataTypeDef, 2, "Extends", "TypeRef[1] System.Object"},
+		{MetadataTypeDef, 2, "MethodList", "MethodDef[1] Main"},
+		{MetadataTypeDef, 2, "Flags", "0x100001"},
+		{MetadataMethodDef, 1, "Signature", "00 00 01"},
+		{MetadataMethodDef, 1, "ParamList", "Param[1]"},
+	}
+	for _, tt := range tests {
+		table := metadata.Tables.Table(tt.table)
+		i := slices.IndexFunc(table.Columns, func(c MetadataColumn) bool { return c.Name == tt.column })
+		if got := metadata.FormatValue(table.Columns[i], table.Rows[tt.row-1][i]); got != tt.want {
+			t.Errorf("%s[%d].%s = %q, want %q", table.Name, tt.row, tt.column, got, tt.want)
+		}
+	}
+
+	if got := metadata.RowName(MetadataTypeDef, 2); got != "App.Program" {
+		t.Errorf("RowName = %q", got)
+	}
+	if got := metadata.TokenName(Token(MetadataTypeDef, 3)); got != "TypeDef[3]" {
+		t.Errorf("TokenName of a missing row = %q", got)
+	}
+	if got := MetadataTableName(0x3F); got != "Table 0x3F" {
+		t.Errorf("MetadataTableName = %q", got)
+	}
+}
+
+func TestMetadataHeaps(t *testing.T) {
+	metadata, err := clrTestImage(testMetadata(testMetadataStreams())).parse(t).Metadata()
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+
+	heaps := []struct {
+		name   string
+		values []string
+	}{
+		{"#Strings", []string{"", "<Module>", "test.dll", "Program", "App", "Main", "Object", "System"}},
+		{"#US", []string{"", "Hi"}},
+		{"#GUID", []string{"{03020100-0504-0706-0809-0A0B0C0D0E0F}"}},
+		{"#Blob", []string{"", "00 00 01", strings.Repeat("AB ", 31) + "AB ... (64 bytes)"}},
+	}
+	for _, heap := range heaps {
+		entries, err := metadata.HeapEntries(heap.name)
+		if err != nil {
+			t.Errorf("HeapEntries(%s): %v", heap.name, err)
+			continue
+		}
+		var values []string
+		for _, entry := range entries {
+			values = append(values, entry.Value)
+		}
+		if !slices.Equal(values, heap.values) {
+			t.Errorf("%s = %q, want %q", heap.name, values, heap.values)
+		}
+	}
+	if entries, _ := metadata.HeapEntries("#Blob"); entries[2].Index != 5 || entries[2].Size != 0x42 {
+		t.Errorf("long blob = %+v", entries[2])
+	}
+
+	if _, err := metadata.HeapEntries("#~"); err == nil {
+		t.Error("HeapEntries of the tables stream succeeded")
+	}
+	if metadata.String(testStringsSize) != "" || metadata.Guid(2) != "" || metadata.Blob(0x1000) != nil {
+		t.Error("heap index past the end of the heap resolved")
+	}
+}
+
+// Wide heap indexes and the extra data after the row counts change where
+// the rows are and how wide their columns are.
+func TestMetadataTablesWide(t *testing.T) {
+	data := testTablesStream(heapSizesStrings|heapSizesExtraData,
+		1<<MetadataModuleRef|1<<MetadataTypeSpec,
+		[]uint32{1, 0x10000},
+		uint32(0x12345678))
+	data = append(data, make([]byte, 2*0x10000)...)
+
+	tables, err := parseMetadataTables(MetadataStream{Data: data, FileOffset: 0x1000})
+	if err != nil {
+		t.Fatalf("parseMetadataTables: %v", err)
+	}
+	moduleRef := tables.Table(MetadataModuleRef)
+	if moduleRef.RowSize != 4 || moduleRef.Rows[0][0] != 0x12345678 || moduleRef.Offset != 0x1000+24+8+4 {
+		t.Errorf("ModuleRef table = %+v", moduleRef)
+	}
+	if typeSpec := tables.Table(MetadataTypeSpec); typeSpec.RowSize != 2 || len(typeSpec.Rows) != 0x10000 {
+		t.Errorf("TypeSpec table has %d rows of %d bytes", len(typeSpec.Rows), typeSpec.RowSize)
+	}
+
+	// A TypeDef table past 0x3FFF rows widens the TypeDefOrRef coded index,
+	// and past 0xFFFF rows the simple indexes into it
+	for _, rows := range []uint32{0x3FFF, 0x4000, 0x10000} {
+		data := testTablesStream(0, 1<<MetadataTypeDef|1<<MetadataInterfaceImpl, []uint32{rows, 0})
+		tables, err := parseMetadataTables(MetadataStream{Data: append(data, make([]byte, rows*20)...)})
+		if err != nil {
+			t.Fatalf("%d TypeDef rows: %v", rows, err)
+		}
+		interfaceImpl := tables.Table(MetadataInterfaceImpl)
+		want := []uint32{2, 2}
+		if rows >= 0x4000 {
+			want[1] = 4
+		}
+		if rows >= 0x10000 {
+			want[0] = 4
+		}
+		if got := []uint32{interfaceImpl.Columns[0].Size, interfaceImpl.Columns[1].Size}; !slices.Equal(got, want) {
+			t.Errorf("%d TypeDef rows: InterfaceImpl columns of %v bytes, want %v", rows, got, want)
+		}
+	}
+}
+
+func TestMetadataCorrupt(t *testing.T) {
+	badSignature := testMetadata(testMetadataStreams())
+	badSignature[0] = 'X'
+
+	// The #Blob stream running past the metadata
+	streamOutOfBounds := testMetadata(testMetadataStreams())
+	blobSize := bytes.Index(streamOutOfBounds, []byte("#Blob\x00")) - 4
+	binary.LittleEndian.PutUint32(streamOutOfBounds[blobSize:], 0x1000)
+
+	unmapped := clrTestImage(testMetadata(testMetadataStreams()))
+	unmapped.dirs[14] = DataDirectory{VirtualAddress: unmappedRVA, Size: 72}
+
+	tests := []struct {
+		name     string
+		image    testImage
+		err      string
+		metadata bool // whether the metadata is still returned
+	}{
+		{"bad signature", clrTestImage(badSignature), "signature", false},
+		{"truncated root", clrTestImage(testMetadata(nil)[:20]), "version string", false},
+		{"stream out of bounds", clrTestImage(streamOutOfBounds), "#Blob out of bounds", true},
+		{"unmapped CLR header", unmapped, "out of bounds", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := tt.image.parse(t).Metadata()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Metadata error %v, want %q", err, tt.err)
+			}
+			if (metadata != nil) != tt.metadata {
+				t.Errorf("Metadata = %+v", metadata)
+			}
+		})
+	}
+}
+
+// A broken tables stream leaves the heaps usable.
+func TestMetadataTablesCorrupt(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables []byte
+		err    string
+	}{
+		{"truncated rows", testMetadataStreams()[0].data[:50], "out of bounds"},
+		{"truncated row counts", testMetadataStreams()[0].data[:30], "row counts"},
+		{"unknown table", testTablesStream(0, 1<<0x30, []uint32{1}), "unknown metadata table"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams := testMetadataStreams()
+			streams[0].data = tt.tables
+			metadata, err := clrTestImage(testMetadata(streams)).parse(t).Metadata()
+			if err != nil {
+				t.Fatalf("Metadata: %v", err)
+			}
+			if metadata.TablesErr == nil || !strings.Contains(metadata.TablesErr.Error(), tt.err) {
+				t.Errorf("TablesErr = %v, want %q", metadata.TablesErr, tt.err)
+			}
+			if metadata.String(stringMain) != "Main" {
+				t.Error("the #Strings heap is not usable")
+			}
+		})
+	}
+}
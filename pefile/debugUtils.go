@@ -0,0 +1,385 @@
+package pefile
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type IMAGE_DEBUG_DIRECTORY struct {
+	Characteristics  uint32
+	TimeDateStamp    uint32
+	MajorVersion     uint16
+	MinorVersion     uint16
+	Type             uint32
+	SizeOfData       uint32
+	AddressOfRawData uint32
+	PointerToRawData uint32
+}
+
+const (
+	IMAGE_DEBUG_TYPE_UNKNOWN               = 0
+	IMAGE_DEBUG_TYPE_COFF                  = 1
+	IMAGE_DEBUG_TYPE_CODEVIEW              = 2
+	IMAGE_DEBUG_TYPE_FPO                   = 3
+	IMAGE_DEBUG_TYPE_MISC                  = 4
+	IMAGE_DEBUG_TYPE_EXCEPTION             = 5
+	IMAGE_DEBUG_TYPE_FIXUP                 = 6
+	IMAGE_DEBUG_TYPE_OMAP_TO_SRC           = 7
+	IMAGE_DEBUG_TYPE_OMAP_FROM_SRC         = 8
+	IMAGE_DEBUG_TYPE_BORLAND               = 9
+	IMAGE_DEBUG_TYPE_RESERVED10            = 10
+	IMAGE_DEBUG_TYPE_CLSID                 = 11
+	IMAGE_DEBUG_TYPE_VC_FEATURE            = 12
+	IMAGE_DEBUG_TYPE_POGO                  = 13
+	IMAGE_DEBUG_TYPE_ILTCG                 = 14
+	IMAGE_DEBUG_TYPE_MPX                   = 15
+	IMAGE_DEBUG_TYPE_REPRO                 = 16
+	IMAGE_DEBUG_TYPE_EMBEDDED_PORTABLE_PDB = 17
+	IMAGE_DEBUG_TYPE_SPGO                  = 18
+	IMAGE_DEBUG_TYPE_PDBCHECKSUM           = 19
+	IMAGE_DEBUG_TYPE_EX_DLLCHARACTERISTICS = 20
+	IMAGE_DEBUG_TYPE_R2R_PERFMAP           = 21
+)
+
+var debugTypeNames = map[uint32]string{
+	IMAGE_DEBUG_TYPE_UNKNOWN:               "UNKNOWN",
+	IMAGE_DEBUG_TYPE_COFF:                  "COFF",
+	IMAGE_DEBUG_TYPE_CODEVIEW:              "CODEVIEW",
+	IMAGE_DEBUG_TYPE_FPO:                   "FPO",
+	IMAGE_DEBUG_TYPE_MISC:                  "MISC",
+	IMAGE_DEBUG_TYPE_EXCEPTION:             "EXCEPTION",
+	IMAGE_DEBUG_TYPE_FIXUP:                 "FIXUP",
+	IMAGE_DEBUG_TYPE_OMAP_TO_SRC:           "OMAP_TO_SRC",
+	IMAGE_DEBUG_TYPE_OMAP_FROM_SRC:         "OMAP_FROM_SRC",
+	IMAGE_DEBUG_TYPE_BORLAND:               "BORLAND",
+	IMAGE_DEBUG_TYPE_RESERVED10:            "RESERVED10",
+	IMAGE_DEBUG_TYPE_CLSID:                 "CLSID",
+	IMAGE_DEBUG_TYPE_VC_FEATURE:            "VC_FEATURE",
+	IMAGE_DEBUG_TYPE_POGO:                  "POGO",
+	IMAGE_DEBUG_TYPE_ILTCG:                 "ILTCG",
+	IMAGE_DEBUG_TYPE_MPX:                   "MPX",
+	IMAGE_DEBUG_TYPE_REPRO:                 "REPRO",
+	IMAGE_DEBUG_TYPE_EMBEDDED_PORTABLE_PDB: "EMBEDDED_PORTABLE_PDB",
+	IMAGE_DEBUG_TYPE_SPGO:                  "SPGO",
+	IMAGE_DEBUG_TYPE_PDBCHECKSUM:           "PDBCHECKSUM",
+	IMAGE_DEBUG_TYPE_EX_DLLCHARACTERISTICS: "EX_DLLCHARACTERISTICS",
+	IMAGE_DEBUG_TYPE_R2R_PERFMAP:           "R2R_PERFMAP",
+}
+
+// DebugTypeName decodes an IMAGE_DEBUG_TYPE_* value.
+func DebugTypeName(debugType uint32) string {
+	if name, ok := debugTypeNames[debugType]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", debugType)
+}
+
+// exDllCharacteristicsNames are the IMAGE_DLLCHARACTERISTICS_EX_* flags, by bit.
+var exDllCharacteristicsNames = []struct {
+	Flag uint32
+	Name string
+}{
+	{0x01, "CET_COMPAT"},
+	{0x02, "CET_COMPAT_STRICT_MODE"},
+	{0x04, "CET_SET_CONTEXT_IP_VALIDATION_RELAXED_MODE"},
+	{0x08, "CET_DYNAMIC_APIS_ALLOW_IN_PROC"},
+	{0x10, "CET_RESERVED_1"},
+	{0x20, "CET_RESERVED_2"},
+	{0x40, "FORWARD_CFI_COMPAT"},
+	{0x80, "HOTPATCH_COMPATIBLE"},
+}
+
+// CodeViewInfo is a CodeView record pointing at the PDB of the image, either
+// an RSDS (PDB 7.0) or an NB10 (PDB 2.0) record.
+type CodeViewInfo struct {
+	Signature string // "RSDS" or "NB10"
+	GUID      [16]byte
+	Offset    uint32 // NB10 only
+	TimeStamp uint32 // NB10 only
+	Age       uint32
+	PdbPath   string
+}
+
+// GUIDString formats the RSDS GUID the way Windows does.
+func (c *CodeViewInfo) GUIDString() string {
+	g := c.GUID
+	return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}",
+		binary.LittleEndian.Uint32(g[0:]), binary.LittleEndian.Uint16(g[4:]), binary.LittleEndian.Uint16(g[6:]), g[8:10], g[10:])
+}
+
+// SymbolServerKey returns the id a symbol server files the PDB under: the
+// GUID (or NB10 timestamp) followed by the age.
+func (c *CodeViewInfo) SymbolServerKey() string {
+	if c.Signature == "NB10" {
+		return fmt.Sprintf("%08X%X", c.TimeStamp, c.Age)
+	}
+	return strings.NewReplacer("{", "", "}", "", "-", "").Replace(c.GUIDString()) + fmt.Sprintf("%X", c.Age)
+}
+
+// PogoEntry is one section contribution of a POGO record.
+type PogoEntry struct {
+	Offset uint32 // file offset of the entry
+	RVA    uint32
+	Size   uint32
+	Name   string
+}
+
+// PogoInfo is the profile guided / link time code generation record.
+type PogoInfo struct {
+	Signature string // "LTCG", "PGU", "PGI" or "PGO"
+	Entries   []PogoEntry
+}
+
+// VCFeatureInfo holds the VC_FEATURE counters the compiler leaves behind.
+type VCFeatureInfo struct {
+	PreVC11 uint32
+	CCpp    uint32 // C/C++ object files
+	Gs      uint32 // objects compiled with /GS
+	Sdl     uint32 // objects compiled with /sdl
+	GuardN  uint32
+}
+
+// EmbeddedPdbInfo describes a portable PDB compressed into the image.
+type EmbeddedPdbInfo struct {
+	UncompressedSize uint32
+	CompressedSize   uint32
+	Valid            bool // the data inflates to a metadata blob
+}
+
+// PdbChecksumInfo is a PDBCHECKSUM record.
+type PdbChecksumInfo struct {
+	Algorithm string
+	Checksum  []byte
+}
+
+// DebugEntry is one IMAGE_DEBUG_DIRECTORY entry together with its decoded
+// data. At most one of the record pointers is set, depending on Type.
+type DebugEntry struct {
+	Offset      uint32 // file offset of the directory entry
+	Directory   IMAGE_DEBUG_DIRECTORY
+	TypeName    string
+	Data        []byte // raw data, nil when it is not in the file
+	CodeView    *CodeViewInfo
+	Pogo        *PogoInfo
+	VCFeature   *VCFeatureInfo
+	Repro       []byte // the REPRO hash, empty for a bare deterministic build
+	ExDllFlags  []string
+	EmbeddedPdb *EmbeddedPdbInfo
+	PdbChecksum *PdbChecksumInfo
+	Err         error // set when the data could not be decoded
+}
+
+// DebugDirectory decodes every IMAGE_DEBUG_DIRECTORY entry of the image.
+func (p *PeFull) DebugDirectory() ([]DebugEntry, error) {
+	debugDir, ok := p.Directory("Debug")
+	if !ok || !debugDir.Present() {
+		return nil, fmt.Errorf("no debug directory")
+	}
+
+	offset, err := RvaToOffset(p.PeFile, debugDir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []DebugEntry
+	entrySize := uint32(binary.Size(IMAGE_DEBUG_DIRECTORY{}))
+	for i := uint32(0); i < debugDir.Size/entrySize; i++ {
+		entryOffset := offset + i*entrySize
+		if uint64(entryOffset)+uint64(entrySize) > uint64(len(p.FileData)) {
+			return entries, fmt.Errorf("debug directory entry at 0x%X out of bounds", entryOffset)
+		}
+
+		entry := DebugEntry{Offset: entryOffset}
+		if err := binary.Read(bytes.NewReader(p.FileData[entryOffset:]), binary.LittleEndian, &entry.Directory); err != nil {
+			return entries, err
+		}
+		entry.TypeName = DebugTypeName(entry.Directory.Type)
+
+		// PointerToRawData is used because AddressOfRawData is 0 for data
+		// that is not mapped
+		start := uint64(entry.Directory.PointerToRawData)
+		end := start + uint64(entry.Directory.SizeOfData)
+		if start != 0 && end <= uint64(len(p.FileData)) {
+			entry.Data = p.FileData[start:end]
+		}
+
+		entry.Err = entry.decode()
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// CodeView returns the first CodeView record of the image.
+func (p *PeFull) CodeView() (*CodeViewInfo, error) {
+	entries, err := p.DebugDirectory()
+	for _, entry := range entries {
+		if entry.CodeView != nil {
+			return entry.CodeView, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("no CodeView record")
+	}
+	return nil, err
+}
+
+// IsReproducible reports whether the image has a REPRO debug entry, in which
+// case its TimeDateStamp fields hold a hash of the build rather than a time.
+func (p *PeFull) IsReproducible() bool {
+	entries, _ := p.DebugDirectory()
+	for _, entry := range entries {
+		if entry.Directory.Type == IMAGE_DEBUG_TYPE_REPRO {
+			return true
+		}
+	}
+	return false
+}
+
+// decode fills in the record that matches the entry type.
+func (e *DebugEntry) decode() error {
+	data := e.Data
+	if data == nil {
+		if e.Directory.SizeOfData == 0 {
+			return nil
+		}
+		return fmt.Errorf("debug data at 0x%X out of bounds", e.Directory.PointerToRawData)
+	}
+
+	switch e.Directory.Type {
+	case IMAGE_DEBUG_TYPE_CODEVIEW:
+		return e.decodeCodeView(data)
+	case IMAGE_DEBUG_TYPE_POGO:
+		return e.decodePogo(data)
+	case IMAGE_DEBUG_TYPE_VC_FEATURE:
+		if len(data) < 20 {
+			return fmt.Errorf("VC_FEATURE record too short")
+		}
+		e.VCFeature = &VCFeatureInfo{
+			PreVC11: binary.LittleEndian.Uint32(data[0:]),
+			CCpp:    binary.LittleEndian.Uint32(data[4:]),
+			Gs:      binary.LittleEndian.Uint32(data[8:]),
+			Sdl:     binary.LittleEndian.Uint32(data[12:]),
+			GuardN:  binary.LittleEndian.Uint32(data[16:]),
+		}
+	case IMAGE_DEBUG_TYPE_REPRO:
+		// A length prefixed hash; older linkers leave the data empty
+		if len(data) >= 4 {
+			hashSize := binary.LittleEndian.Uint32(data)
+			if uint64(hashSize)+4 > uint64(len(data)) {
+				return fmt.Errorf("REPRO hash size %d out of bounds", hashSize)
+			}
+			e.Repro = data[4 : 4+hashSize]
+		}
+	case IMAGE_DEBUG_TYPE_EX_DLLCHARACTERISTICS:
+		if len(data) < 4 {
+			return fmt.Errorf("EX_DLLCHARACTERISTICS record too short")
+		}
+		flags := binary.LittleEndian.Uint32(data)
+		for _, f := range exDllCharacteristicsNames {
+			if flags&f.Flag != 0 {
+				e.ExDllFlags = append(e.ExDllFlags, f.Name)
+				flags &^= f.Flag
+			}
+		}
+		if flags != 0 {
+			e.ExDllFlags = append(e.ExDllFlags, fmt.Sprintf("0x%X", flags))
+		}
+	case IMAGE_DEBUG_TYPE_EMBEDDED_PORTABLE_PDB:
+		return e.decodeEmbeddedPdb(data)
+	case IMAGE_DEBUG_TYPE_PDBCHECKSUM:
+		name, rest, found := bytes.Cut(data, []byte{0})
+		if !found {
+			return fmt.Errorf("PDBCHECKSUM algorithm name not terminated")
+		}
+		e.PdbChecksum = &PdbChecksumInfo{Algorithm: string(name), Checksum: rest}
+	}
+	return nil
+}
+
+func (e *DebugEntry) decodeCodeView(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("CodeView record too short")
+	}
+
+	info := &CodeViewInfo{Signature: string(data[:4])}
+	var path []byte
+	switch info.Signature {
+	case "RSDS":
+		if len(data) < 24 {
+			return fmt.Errorf("RSDS record too short")
+		}
+		copy(info.GUID[:], data[4:20])
+		info.Age = binary.LittleEndian.Uint32(data[20:])
+		path = data[24:]
+	case "NB10":
+		if len(data) < 16 {
+			return fmt.Errorf("NB10 record too short")
+		}
+		info.Offset = binary.LittleEndian.Uint32(data[4:])
+		info.TimeStamp = binary.LittleEndian.Uint32(data[8:])
+		info.Age = binary.LittleEndian.Uint32(data[12:])
+		path = data[16:]
+	default:
+		return fmt.Errorf("unknown CodeView signature %q", info.Signature)
+	}
+	if i := bytes.IndexByte(path, 0); i >= 0 {
+		path = path[:i]
+	}
+	info.PdbPath = string(path)
+
+	e.CodeView = info
+	return nil
+}
+
+func (e *DebugEntry) decodePogo(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("POGO record too short")
+	}
+
+	// The signature is stored as a little endian FOURCC
+	signature := string(bytes.TrimRight([]byte{data[3], data[2], data[1], data[0]}, "\x00"))
+	info := &PogoInfo{Signature: signature}
+	pos := 4
+	for pos+8 < len(data) {
+		entry := PogoEntry{
+			Offset: e.Directory.PointerToRawData + uint32(pos),
+			RVA:    binary.LittleEndian.Uint32(data[pos:]),
+			Size:   binary.LittleEndian.Uint32(data[pos+4:]),
+		}
+		name := data[pos+8:]
+		end := bytes.IndexByte(name, 0)
+		if end < 0 {
+			break
+		}
+		entry.Name = string(name[:end])
+		info.Entries = append(info.Entries, entry)
+		pos = alignDword(pos + 8 + end + 1)
+	}
+
+	e.Pogo = info
+	return nil
+}
+
+func (e *DebugEntry) decodeEmbeddedPdb(data []byte) error {
+	if len(data) < 8 || string(data[:4]) != "MPDB" {
+		return fmt.Errorf("missing MPDB signature")
+	}
+
+	info := &EmbeddedPdbInfo{
+		UncompressedSize: binary.LittleEndian.Uint32(data[4:]),
+		CompressedSize:   uint32(len(data) - 8),
+	}
+	// Only the metadata signature is needed to tell whether it inflates
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(flate.NewReader(bytes.NewReader(data[8:])), header); err == nil {
+		info.Valid = string(header) == "BSJB"
+	}
+
+	e.EmbeddedPdb = info
+	return nil
+}
@@ -0,0 +1,205 @@
+package pefile
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+// testDebugRecord is the type and data of a debug directory entry.
+type testDebugRecord struct {
+	debugType uint32
+	data      []byte
+}
+
+func testEmbeddedPdb() []byte {
+	compressed := new(bytes.Buffer)
+	w, _ := flate.NewWriter(compressed, flate.BestCompression)
+	w.Write([]byte("BSJB metadata"))
+	w.Close()
+	return append(binary.LittleEndian.AppendUint32([]byte("MPDB"), 13), compressed.Bytes()...)
+}
+
+// debugTestImage places a debug directory with an entry per record, and
+// the records after it, in the only section of the image.
+func debugTestImage(records []testDebugRecord) testImage {
+	rdata := newTestData(testSectionRVA(0))
+	dir := rdata.put(make([]byte, len(records)*binary.Size(IMAGE_DEBUG_DIRECTORY{})))
+	entries := make([]IMAGE_DEBUG_DIRECTORY, len(records))
+	for i, record := range records {
+		rdata.align(4)
+		rva := rdata.put(record.data)
+		entries[i] = IMAGE_DEBUG_DIRECTORY{
+			Type:             record.debugType,
+			SizeOfData:       uint32(len(record.data)),
+			AddressOfRawData: rva,
+			PointerToRawData: testFileAlignment + rva - testSectionRVA(0),
+		}
+	}
+
+	section := rdata.bytes()
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, entries)
+	copy(section, buf.Bytes())
+	return testImage{
+		sections: []testSection{{name: ".rdata", data: section, characteristics: 0x40000040}},
+		dirs:     map[int]DataDirectory{6: {VirtualAddress: dir, Size: uint32(buf.Len())}},
+	}
+}
+
+func TestDebugDirectory(t *testing.T) {
+	guid := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	rsds := append(append([]byte("RSDS"), guid...), 3, 0, 0, 0)
+	rsds = append(rsds, "C:\\build\\test.pdb\x00"...)
+	nb10 := binary.LittleEndian.AppendUint32([]byte("NB10"), 0)
+	nb10 = binary.LittleEndian.AppendUint32(nb10, 0x12345678)
+	nb10 = binary.LittleEndian.AppendUint32(nb10, 2)
+	nb10 = append(nb10, "old.pdb\x00"...)
+
+	pogo := []byte("GCTL")
+	pogo = binary.LittleEndian.AppendUint32(pogo, 0x1000)
+	pogo = binary.LittleEndian.AppendUint32(pogo, 0x20)
+	pogo = append(pogo, ".text$mn\x00\x00\x00\x00"...)
+	pogo = binary.LittleEndian.AppendUint32(pogo, 0x2000)
+	pogo = binary.LittleEndian.AppendUint32(pogo, 0x8)
+	pogo = append(pogo, ".rdata\x00\x00"...)
+
+	vcFeature := make([]byte, 20)
+	for i := range 5 {
+		binary.LittleEndian.PutUint32(vcFeature[i*4:], uint32(i+1))
+	}
+	repro := append(binary.LittleEndian.AppendUint32(nil, 4), 0xDE, 0xAD, 0xBE, 0xEF)
+
+	p := debugTestImage([]testDebugRecord{
+		{IMAGE_DEBUG_TYPE_CODEVIEW, rsds},
+		{IMAGE_DEBUG_TYPE_CODEVIEW, nb10},
+		{IMAGE_DEBUG_TYPE_POGO, pogo},
+		{IMAGE_DEBUG_TYPE_VC_FEATURE, vcFeature},
+		{IMAGE_DEBUG_TYPE_REPRO, repro},
+		{IMAGE_DEBUG_TYPE_EX_DLLCHARACTERISTICS, []byte{0x41, 0x01, 0, 0}},
+		{IMAGE_DEBUG_TYPE_EMBEDDED_PORTABLE_PDB, testEmbeddedPdb()},
+		{IMAGE_DEBUG_TYPE_PDBCHECKSUM, []byte("SHA256\x00\x01\x02")},
+		{IMAGE_DEBUG_TYPE_ILTCG, nil},
+	}).parse(t)
+
+	entries, err := p.DebugDirectory()
+	if err != nil {
+		t.Fatalf("DebugDirectory: %v", err)
+	}
+	if len(entries) != 9 {
+		t.Fatalf("got %d entries, want 9", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Err != nil {
+			t.Errorf("entry %d (%s): %v", i, entry.TypeName, entry.Err)
+		}
+	}
+
+	cv := entries[0].CodeView
+	if cv == nil || cv.PdbPath != "C:\\build\\test.pdb" || cv.Age != 3 {
+		t.Fatalf("RSDS = %+v", cv)
+	}
+	if got := cv.GUIDString(); got != "{03020100-0504-0706-0809-0A0B0C0D0E0F}" {
+		t.Errorf("GUIDString = %s", got)
+	}
+	if got := cv.SymbolServerKey(); got != "030201000504070608090A0B0C0D0E0F3" {
+		t.Errorf("SymbolServerKey = %s", got)
+	}
+	if cv := entries[1].CodeView; cv == nil || cv.PdbPath != "old.pdb" || cv.SymbolServerKey() != "123456782" {
+		t.Errorf("NB10 = %+v", cv)
+	}
+	if first, err := p.CodeView(); err != nil || first.PdbPath != cv.PdbPath {
+		t.Errorf("CodeView = %+v, %v", first, err)
+	}
+
+	pogoInfo := entries[2].Pogo
+	if pogoInfo == nil || pogoInfo.Signature != "LTCG" || len(pogoInfo.Entries) != 2 {
+		t.Fatalf("POGO = %+v", pogoInfo)
+	}
+	if got := pogoInfo.Entries[1]; got.RVA != 0x2000 || got.Size != 8 || got.Name != ".rdata" ||
+		got.Offset != entries[2].Directory.PointerToRawData+4+8+12 {
+		t.Errorf("POGO entry 1 = %+v", got)
+	}
+
+	if got := entries[3].VCFeature; got == nil || *got != (VCFeatureInfo{1, 2, 3, 4, 5}) {
+		t.Errorf("VC_FEATURE = %+v", got)
+	}
+	if !bytes.Equal(entries[4].Repro, []byte{0xDE, 0xAD, 0xBE, 0xEF}) || !p.IsReproducible() {
+		t.Errorf("REPRO = % X", entries[4].Repro)
+	}
+	if want := []string{"CET_COMPAT", "FORWARD_CFI_COMPAT", "0x100"}; !slices.Equal(entries[5].ExDllFlags, want) {
+		t.Errorf("EX_DLLCHARACTERISTICS = %q, want %q", entries[5].ExDllFlags, want)
+	}
+	if pdb := entries[6].EmbeddedPdb; pdb == nil || !pdb.Valid || pdb.UncompressedSize != 13 {
+		t.Errorf("embedded PDB = %+v", pdb)
+	}
+	if sum := entries[7].PdbChecksum; sum == nil || sum.Algorithm != "SHA256" || !bytes.Equal(sum.Checksum, []byte{1, 2}) {
+		t.Errorf("PDBCHECKSUM = %+v", sum)
+	}
+	if entries[8].TypeName != "ILTCG" || len(entries[8].Data) != 0 {
+		t.Errorf("ILTCG = %+v", entries[8])
+	}
+}
+
+// A record that can't be decoded is flagged on its own entry.
+func TestDebugDirectoryBadRecords(t *testing.T) {
+	image := debugTestImage([]testDebugRecord{
+		{IMAGE_DEBUG_TYPE_CODEVIEW, []byte("XXXX0123456789")},
+		{IMAGE_DEBUG_TYPE_CODEVIEW, []byte("RSDS0123")},
+		{IMAGE_DEBUG_TYPE_VC_FEATURE, []byte{1, 2, 3}},
+		{IMAGE_DEBUG_TYPE_REPRO, []byte{0xFF, 0, 0, 0, 1}},
+		{IMAGE_DEBUG_TYPE_EMBEDDED_PORTABLE_PDB, []byte("MPDX0000")},
+		{IMAGE_DEBUG_TYPE_PDBCHECKSUM, []byte("SHA256")},
+		{IMAGE_DEBUG_TYPE_CODEVIEW, []byte("RSDS")},
+	})
+	// Point the last record past the end of the file
+	data := image.bytes()
+	last := testFileAlignment + 6*binary.Size(IMAGE_DEBUG_DIRECTORY{})
+	binary.LittleEndian.PutUint32(data[last+24:], 0x7FFFFFF0)
+
+	p, err := ParseBytes(data)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	entries, err := p.DebugDirectory()
+	if err != nil {
+		t.Fatalf("DebugDirectory: %v", err)
+	}
+	for i, entry := range entries {
+		if entry.Err == nil {
+			t.Errorf("entry %d (%s) decoded without an error", i, entry.TypeName)
+		}
+	}
+	if entries[6].Data != nil {
+		t.Error("data past the end of the file was read")
+	}
+	if _, err := p.CodeView(); err == nil {
+		t.Error("CodeView succeeded")
+	}
+}
+
+func TestDebugDirectoryCorrupt(t *testing.T) {
+	records := []testDebugRecord{{IMAGE_DEBUG_TYPE_REPRO, nil}, {IMAGE_DEBUG_TYPE_REPRO, nil}}
+	unmapped := debugTestImage(records)
+	unmapped.dirs[6] = DataDirectory{VirtualAddress: unmappedRVA, Size: 28}
+
+	p := unmapped.parse(t)
+	if _, err := p.DebugDirectory(); err == nil {
+		t.Error("DebugDirectory of an unmapped directory succeeded")
+	}
+	if p.IsReproducible() {
+		t.Error("IsReproducible without a readable directory")
+	}
+
+	truncated := debugTestImage(records).bytes()[:testFileAlignment+28+10]
+	p, err := ParseBytes(truncated)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	entries, err := p.DebugDirectory()
+	if err == nil || len(entries) != 1 {
+		t.Errorf("truncated directory gave %d entries, %v", len(entries), err)
+	}
+}
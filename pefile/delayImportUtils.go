@@ -0,0 +1,97 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// IMAGE_DELAYLOAD_DESCRIPTOR is the ImgDelayDescr of delayimp.h.
+type IMAGE_DELAYLOAD_DESCRIPTOR struct {
+	Attributes                 uint32
+	DllNameRVA                 uint32
+	ModuleHandleRVA            uint32
+	ImportAddressTableRVA      uint32
+	ImportNameTableRVA         uint32
+	BoundImportAddressTableRVA uint32
+	UnloadInformationTableRVA  uint32
+	TimeDateStamp              uint32
+}
+
+// dlattrRva is set in Attributes when the descriptor holds RVAs. Without it
+// the descriptor comes from an old linker and holds VAs instead.
+const dlattrRva = 0x1
+
+type DelayImportDescriptor struct {
+	Offset     uint32 // file offset of the descriptor
+	Descriptor IMAGE_DELAYLOAD_DESCRIPTOR
+	RvaBased   bool
+	DllName    string
+	Functions  []ImportFunction
+	Err        error // set when the DLL name or its functions could not be read
+}
+
+// ToRVA converts an address of the descriptor to an RVA, subtracting the
+// image base for VA-based descriptors.
+func (d *DelayImportDescriptor) ToRVA(address uint32, imageBase uint64) uint32 {
+	if d.RvaBased || address == 0 {
+		return address
+	}
+	return address - uint32(imageBase)
+}
+
+// DelayImportTable decodes every delay-load descriptor up to the terminating
+// null descriptor, together with the functions each DLL provides.
+func (p *PeFull) DelayImportTable() ([]DelayImportDescriptor, error) {
+	delayDir, ok := p.Directory("Delay Import Descriptor")
+	if !ok || !delayDir.Present() {
+		return nil, fmt.Errorf("no delay import table")
+	}
+
+	offset, err := RvaToOffset(p.PeFile, delayDir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	imageBase := p.ImageBase()
+	var imports []DelayImportDescriptor
+	descriptorSize := uint32(binary.Size(IMAGE_DELAYLOAD_DESCRIPTOR{}))
+	for ; uint64(offset)+uint64(descriptorSize) <= uint64(len(p.FileData)); offset += descriptorSize {
+		var descriptor IMAGE_DELAYLOAD_DESCRIPTOR
+		reader := bytes.NewReader(p.FileData[offset:])
+		if err := binary.Read(reader, binary.LittleEndian, &descriptor); err != nil {
+			return imports, err
+		}
+		if descriptor == (IMAGE_DELAYLOAD_DESCRIPTOR{}) {
+			break
+		}
+
+		delayImport := DelayImportDescriptor{
+			Offset:     offset,
+			Descriptor: descriptor,
+			RvaBased:   descriptor.Attributes&dlattrRva != 0,
+		}
+		delayImport.DllName, delayImport.Err = ReadStringFromRVA(p.PeFile, p.FileData, delayImport.ToRVA(descriptor.DllNameRVA, imageBase))
+		if delayImport.Err != nil {
+			delayImport.Err = fmt.Errorf("delay import descriptor at 0x%X: %v", offset, delayImport.Err)
+			imports = append(imports, delayImport)
+			continue
+		}
+
+		// Hint/name entries of VA-based name tables hold VAs as well
+		var nameBase uint64
+		if !delayImport.RvaBased {
+			nameBase = imageBase
+		}
+		delayImport.Functions, err = p.readImportThunks(
+			delayImport.ToRVA(descriptor.ImportNameTableRVA, imageBase),
+			delayImport.ToRVA(descriptor.ImportAddressTableRVA, imageBase),
+			nameBase)
+		if err != nil {
+			delayImport.Err = fmt.Errorf("delay imports of %s: %v", delayImport.DllName, err)
+		}
+		imports = append(imports, delayImport)
+	}
+
+	return imports, nil
+}
@@ -0,0 +1,112 @@
+package pefile
+
+import "testing"
+
+// delayImportTestImage has three RVA-based descriptors: ADVAPI32.dll, which
+// decodes fine, one whose DLL name is unmapped and SHELL32.dll whose name
+// table is unmapped.
+func delayImportTestImage() testImage {
+	d := newTestData(testSectionRVA(0))
+	advapi32 := d.putString("ADVAPI32.dll")
+	shell32 := d.putString("SHELL32.dll")
+	regOpenKey := d.putHintName(1, "RegOpenKeyExW")
+	names := d.putThunks64(uint64(regOpenKey), 0x8000000000000007)
+	iat := d.putThunks64(0x140001000, 0x140001010)
+	handle := d.putStruct(uint64(0))
+
+	descriptors := d.putStruct([]IMAGE_DELAYLOAD_DESCRIPTOR{
+		{Attributes: dlattrRva, DllNameRVA: advapi32, ModuleHandleRVA: handle, ImportAddressTableRVA: iat, ImportNameTableRVA: names},
+		{Attributes: dlattrRva, DllNameRVA: unmappedRVA, ImportAddressTableRVA: iat, ImportNameTableRVA: names},
+		{Attributes: dlattrRva, DllNameRVA: shell32, ImportAddressTableRVA: iat, ImportNameTableRVA: unmappedRVA},
+		{},
+	})
+
+	return testImage{
+		sections: []testSection{{name: ".didat", data: d.bytes(), characteristics: 0xC0000040}},
+		dirs:     map[int]DataDirectory{13: {VirtualAddress: descriptors, Size: 4 * 32}},
+	}
+}
+
+func TestDelayImportTable(t *testing.T) {
+	imports, err := delayImportTestImage().parse(t).DelayImportTable()
+	if err != nil {
+		t.Fatalf("DelayImportTable: %v", err)
+	}
+	if len(imports) != 3 {
+		t.Fatalf("got %d descriptors, want 3", len(imports))
+	}
+
+	advapi32 := imports[0]
+	if advapi32.DllName != "ADVAPI32.dll" || !advapi32.RvaBased || advapi32.Err != nil || len(advapi32.Functions) != 2 {
+		t.Fatalf("descriptor 0 = %+v", advapi32)
+	}
+	if f := advapi32.Functions[0]; f.ByOrdinal || f.Name != "RegOpenKeyExW" || f.Hint != 1 || f.IatRVA != advapi32.Descriptor.ImportAddressTableRVA {
+		t.Errorf("function 0 = %+v", f)
+	}
+	if f := advapi32.Functions[1]; !f.ByOrdinal || f.Ordinal != 7 {
+		t.Errorf("function 1 = %+v", f)
+	}
+
+	// The bad descriptors are kept, with their errors
+	if imports[1].Err == nil || imports[1].DllName != "" {
+		t.Errorf("descriptor 1 = %+v, want an error", imports[1])
+	}
+	if imports[2].Err == nil || imports[2].DllName != "SHELL32.dll" {
+		t.Errorf("descriptor 2 = %+v, want an error", imports[2])
+	}
+}
+
+// Descriptors of old linkers hold VAs, and so do their hint/name entries.
+func TestDelayImportTableVABased(t *testing.T) {
+	const imageBase = 0x400000
+	d := newTestData(testSectionRVA(0))
+	user32 := d.putString("USER32.dll")
+	messageBox := d.putHintName(2, "MessageBoxA")
+	d.align(4)
+	names := d.putStruct([]uint32{imageBase + messageBox, 0})
+	iat := d.putStruct([]uint32{imageBase + 0x1000, 0})
+	descriptors := d.putStruct([]IMAGE_DELAYLOAD_DESCRIPTOR{
+		{DllNameRVA: imageBase + user32, ImportAddressTableRVA: imageBase + iat, ImportNameTableRVA: imageBase + names},
+		{},
+	})
+
+	p := testImage{
+		pe32:     true,
+		sections: []testSection{{name: ".data", data: d.bytes(), characteristics: 0xC0000040}},
+		dirs:     map[int]DataDirectory{13: {VirtualAddress: descriptors, Size: 2 * 32}},
+	}.parse(t)
+
+	imports, err := p.DelayImportTable()
+	if err != nil {
+		t.Fatalf("DelayImportTable: %v", err)
+	}
+	if len(imports) != 1 || imports[0].RvaBased || imports[0].Err != nil || imports[0].DllName != "USER32.dll" {
+		t.Fatalf("descriptors = %+v", imports)
+	}
+	if got := imports[0].ToRVA(imports[0].Descriptor.ImportAddressTableRVA, p.ImageBase()); got != iat {
+		t.Errorf("IAT RVA = 0x%X, want 0x%X", got, iat)
+	}
+	if functions := imports[0].Functions; len(functions) != 1 || functions[0].Name != "MessageBoxA" || functions[0].IatRVA != iat {
+		t.Errorf("functions = %+v", functions)
+	}
+}
+
+func TestDelayImportTableCorrupt(t *testing.T) {
+	unmapped := delayImportTestImage()
+	unmapped.dirs[13] = DataDirectory{VirtualAddress: unmappedRVA, Size: 32}
+	if _, err := unmapped.parse(t).DelayImportTable(); err == nil {
+		t.Error("DelayImportTable of an unmapped directory succeeded")
+	}
+
+	// Without a null descriptor the walk stops at the end of the file
+	image := delayImportTestImage()
+	data := image.bytes()
+	descriptors := testFileAlignment + image.dirs[13].VirtualAddress - testSectionRVA(0)
+	p, err := ParseBytes(data[:descriptors+32+16])
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	if imports, err := p.DelayImportTable(); err != nil || len(imports) != 1 {
+		t.Errorf("truncated table gave %d descriptors, %v", len(imports), err)
+	}
+}
@@ -0,0 +1,104 @@
+package pefile
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// HighEntropy is the entropy, in bits per byte, above which a region is
+// most likely compressed or encrypted.
+const HighEntropy = 7.2
+
+// RegionStats holds the hashes and entropy of one region of the file.
+type RegionStats struct {
+	Name    string
+	Offset  uint32 // file offset of the region
+	Size    uint32 // bytes actually present in the file
+	MD5     string
+	SHA256  string
+	Entropy float64 // Shannon entropy in bits per byte, 0 to 8
+}
+
+// Entropy computes the Shannon entropy of data in bits per byte.
+func Entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	total := float64(len(data))
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / total
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// regionStats hashes size bytes at offset, clipped to the end of the file.
+func (p *PeFull) regionStats(name string, offset uint32, size uint32) RegionStats {
+	start := min(uint64(offset), uint64(len(p.FileData)))
+	end := min(uint64(offset)+uint64(size), uint64(len(p.FileData)))
+	data := p.FileData[start:end]
+
+	md5Sum := md5.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+	return RegionStats{
+		Name:    name,
+		Offset:  offset,
+		Size:    uint32(len(data)),
+		MD5:     hex.EncodeToString(md5Sum[:]),
+		SHA256:  hex.EncodeToString(sha256Sum[:]),
+		Entropy: Entropy(data),
+	}
+}
+
+// SectionStats returns the stats of the raw data of each section, in
+// section table order.
+func (p *PeFull) SectionStats() []RegionStats {
+	stats := make([]RegionStats, 0, len(p.PeFile.Sections))
+	for _, sh := range p.PeFile.Sections {
+		stats = append(stats, p.regionStats(sh.Name, sh.Offset, sh.Size))
+	}
+	return stats
+}
+
+// HeaderStats returns the stats of the headers, the first SizeOfHeaders
+// bytes of the file.
+func (p *PeFull) HeaderStats() RegionStats {
+	return p.regionStats("Headers", 0, p.SizeOfHeaders())
+}
+
+// OverlayOffset returns the file offset where the overlay starts: past the
+// raw data of every section and past the certificate table, which is the
+// one directory addressed by file offset.
+func (p *PeFull) OverlayOffset() uint32 {
+	end := uint64(p.SizeOfHeaders())
+	for _, sh := range p.PeFile.Sections {
+		if sh.Size != 0 {
+			end = max(end, uint64(sh.Offset)+uint64(sh.Size))
+		}
+	}
+	if certDir, ok := p.Directory("Certificate Table"); ok && certDir.Present() {
+		end = max(end, uint64(certDir.VirtualAddress)+uint64(certDir.Size))
+	}
+	return uint32(min(end, uint64(len(p.FileData))))
+}
+
+// OverlayStats returns the stats of the data appended past the end of the
+// image.
+func (p *PeFull) OverlayStats() (RegionStats, error) {
+	offset := p.OverlayOffset()
+	if offset >= uint32(len(p.FileData)) {
+		return RegionStats{}, fmt.Errorf("no overlay")
+	}
+	return p.regionStats("Overlay", offset, uint32(len(p.FileData))-offset), nil
+}
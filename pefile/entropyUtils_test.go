@@ -0,0 +1,91 @@
+package pefile
+
+import (
+	"math"
+	"testing"
+)
+
+// allBytes holds each byte value once, the most entropy 256 bytes can have.
+func allBytes() []byte {
+	data := make([]byte, 256)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+func TestEntropy(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want float64
+	}{
+		{"empty", nil, 0},
+		{"all zero", make([]byte, 0x1000), 0},
+		{"two values", []byte{0, 1, 0, 1}, 1},
+		{"256 distinct bytes", allBytes(), 8},
+		{"256 distinct bytes repeated", append(allBytes(), allBytes()...), 8},
+	}
+	for _, tt := range tests {
+		if got := Entropy(tt.data); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Entropy = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// entropyTestImage has a section of every byte value, one of zeros and one
+// with no raw data at all.
+func entropyTestImage(overlay []byte) testImage {
+	return testImage{
+		sections: []testSection{
+			{name: ".text", data: append(allBytes(), allBytes()...), characteristics: 0x60000020},
+			{name: ".data", data: make([]byte, testFileAlignment), characteristics: 0xC0000040},
+			{name: ".textbss", virtualSize: 0x1000, characteristics: 0xE00000A0},
+		},
+		overlay: overlay,
+	}
+}
+
+func TestSectionStats(t *testing.T) {
+	stats := entropyTestImage(nil).parse(t).SectionStats()
+	if len(stats) != 3 {
+		t.Fatalf("got %d stats, want 3", len(stats))
+	}
+
+	text := stats[0]
+	if text.Name != ".text" || text.Offset != 0x200 || text.Size != 0x200 || text.Entropy != 8 {
+		t.Errorf(".text stats = %+v", text)
+	}
+
+	data := stats[1]
+	if data.Name != ".data" || data.Offset != 0x400 || data.Size != 0x200 || data.Entropy != 0 {
+		t.Errorf(".data stats = %+v", data)
+	}
+	// Hashes of 512 zero bytes
+	if data.MD5 != "bf619eac0cdf3f68d496ea9344137e8b" || data.SHA256 != "076a27c79e5ace2a3d47f9dd2e83e4ff6ea8872b3c2218f66c92b89b55f36560" {
+		t.Errorf(".data hashes = %s %s", data.MD5, data.SHA256)
+	}
+
+	// Nothing of the uninitialized section is in the file
+	if bss := stats[2]; bss.Size != 0 || bss.Entropy != 0 {
+		t.Errorf(".textbss stats = %+v", bss)
+	}
+}
+
+func TestOverlayStats(t *testing.T) {
+	p := entropyTestImage(allBytes()).parse(t)
+	overlay, err := p.OverlayStats()
+	if err != nil {
+		t.Fatalf("OverlayStats: %v", err)
+	}
+	if overlay.Name != "Overlay" || overlay.Offset != 0x600 || overlay.Size != 256 || overlay.Entropy != 8 {
+		t.Errorf("overlay stats = %+v", overlay)
+	}
+	if overlay.MD5 != "e2c865db4162bed963bfaa9ef6ac18f0" {
+		t.Errorf("overlay MD5 = %s", overlay.MD5)
+	}
+
+	if _, err := entropyTestImage(nil).parse(t).OverlayStats(); err == nil {
+		t.Error("OverlayStats found an overlay in an image without one")
+	}
+}
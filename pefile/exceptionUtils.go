@@ -0,0 +1,720 @@
+package pefile
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+)
+
+type IMAGE_RUNTIME_FUNCTION_ENTRY struct {
+	BeginAddress      uint32
+	EndAddress        uint32
+	UnwindInfoAddress uint32
+}
+
+type IMAGE_ARM64_RUNTIME_FUNCTION_ENTRY struct {
+	BeginAddress uint32
+	UnwindData   uint32
+}
+
+// UNWIND_INFO flags
+const (
+	UNW_FLAG_NHANDLER  = 0x0
+	UNW_FLAG_EHANDLER  = 0x1
+	UNW_FLAG_UHANDLER  = 0x2
+	UNW_FLAG_CHAININFO = 0x4
+)
+
+// x64 unwind operation codes. Codes 6 and 7 were SAVE_XMM and SAVE_XMM_FAR
+// in version 1 of UNWIND_INFO.
+const (
+	UWOP_PUSH_NONVOL     = 0
+	UWOP_ALLOC_LARGE     = 1
+	UWOP_ALLOC_SMALL     = 2
+	UWOP_SET_FPREG       = 3
+	UWOP_SAVE_NONVOL     = 4
+	UWOP_SAVE_NONVOL_FAR = 5
+	UWOP_EPILOG          = 6
+	UWOP_SPARE_CODE      = 7
+	UWOP_SAVE_XMM128     = 8
+	UWOP_SAVE_XMM128_FAR = 9
+	UWOP_PUSH_MACHFRAME  = 10
+)
+
+// maxUnwindChain bounds how many chained unwind infos are followed, so that
+// a chain looping back on itself cannot hang the parser.
+const maxUnwindChain = 32
+
+// handlerDataPreview is how many bytes of language specific handler data
+// are kept. Their real size is only known to the handler.
+const handlerDataPreview = 32
+
+var x64Registers = []string{
+	"rax", "rcx", "rdx", "rbx", "rsp", "rbp", "rsi", "rdi",
+	"r8", "r9", "r10", "r11", "r12", "r13", "r14", "r15",
+}
+
+// UnwindCode is one decoded unwind code, spanning one or more slots for
+// x64 or one or more bytes for ARM64.
+type UnwindCode struct {
+	Offset     uint32 // file offset of the code
+	Raw        []byte
+	CodeOffset int // x64: offset in the prolog the code applies after; -1 for ARM64
+	Op         string
+	Text       string // the instruction the code describes, in assembly form
+}
+
+// Arm64EpilogScope is one epilog scope of an unpacked ARM64 .xdata record.
+type Arm64EpilogScope struct {
+	Offset      uint32 // file offset of the scope
+	StartOffset uint32 // offset of the epilog from the start of the function
+	StartIndex  uint32 // index of the first unwind code byte of the epilog
+}
+
+// UnwindInfo is a decoded x64 UNWIND_INFO or ARM64 .xdata record.
+type UnwindInfo struct {
+	Offset uint32 // file offset of the record
+	RVA    uint32
+
+	// x64 fields
+	Version       uint8
+	Flags         uint8
+	SizeOfProlog  uint8
+	CountOfCodes  uint8
+	FrameRegister uint8
+	FrameOffset   uint8
+
+	// ARM64 fields
+	FunctionLength uint32
+	EpilogInHeader bool // the single epilog's first code index is EpilogCount
+	EpilogCount    uint32
+	CodeWords      uint32
+	EpilogScopes   []Arm64EpilogScope
+
+	Codes []UnwindCode
+
+	// Chained is the function whose unwind info continues this one, for x64
+	// UNW_FLAG_CHAININFO records.
+	Chained *RuntimeFunction
+
+	HasHandler     bool
+	HandlerOffset  uint32 // file offset of the handler RVA
+	HandlerRVA     uint32
+	HandlerDataRVA uint32
+	HandlerData    []byte // the first bytes of the handler data
+}
+
+// FlagNames decodes the x64 UNWIND_INFO flags.
+func (u *UnwindInfo) FlagNames() []string {
+	var names []string
+	if u.Flags&UNW_FLAG_EHANDLER != 0 {
+		names = append(names, "EHANDLER")
+	}
+	if u.Flags&UNW_FLAG_UHANDLER != 0 {
+		names = append(names, "UHANDLER")
+	}
+	if u.Flags&UNW_FLAG_CHAININFO != 0 {
+		names = append(names, "CHAININFO")
+	}
+	if rest := u.Flags &^ (UNW_FLAG_EHANDLER | UNW_FLAG_UHANDLER | UNW_FLAG_CHAININFO); rest != 0 {
+		names = append(names, fmt.Sprintf("0x%X", rest))
+	}
+	if len(names) == 0 {
+		names = append(names, "NHANDLER")
+	}
+	return names
+}
+
+// FrameRegisterName returns the x64 frame pointer register, or "" when the
+// function does not use one.
+func (u *UnwindInfo) FrameRegisterName() string {
+	if u.FrameRegister == 0 {
+		return ""
+	}
+	return x64Registers[u.FrameRegister]
+}
+
+// Arm64PackedUnwind is the unwind data packed into an ARM64 .pdata entry.
+type Arm64PackedUnwind struct {
+	Flag           uint8 // 1 for a function with prolog and epilog, 2 for a fragment without
+	FunctionLength uint32
+	RegF           uint8 // number of saved d8-d15 registers, less one when any are saved
+	RegI           uint8 // number of saved x19-x28 registers
+	H              bool  // x0-x7 are homed
+	CR             uint8
+	FrameSize      uint32
+}
+
+// CRName decodes the CR field of packed ARM64 unwind data.
+func (a *Arm64PackedUnwind) CRName() string {
+	switch a.CR {
+	case 0:
+		return "unchained, lr not saved"
+	case 1:
+		return "unchained, lr saved"
+	case 2:
+		return "chained, return address signed"
+	default:
+		return "chained"
+	}
+}
+
+// RuntimeFunction is one entry of the exception directory.
+type RuntimeFunction struct {
+	Offset       uint32 // file offset of the .pdata entry
+	BeginAddress uint32
+	EndAddress   uint32 // for ARM64 derived from the function length
+	UnwindData   uint32 // UnwindInfoAddress for x64, the raw UnwindData word for ARM64
+	Section      string
+	Packed       *Arm64PackedUnwind
+	Unwind       *UnwindInfo
+	Err          error
+}
+
+// ExceptionTable decodes the RUNTIME_FUNCTION entries of the exception
+// directory and the unwind information of each. x64 and ARM64 images are
+// supported.
+func (p *PeFull) ExceptionTable() ([]RuntimeFunction, error) {
+	excDir, ok := p.Directory("Exception Table")
+	if !ok || !excDir.Present() {
+		return nil, fmt.Errorf("no exception directory")
+	}
+
+	machine := p.PeFile.FileHeader.Machine
+	var entrySize uint32
+	switch machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		entrySize = uint32(binary.Size(IMAGE_RUNTIME_FUNCTION_ENTRY{}))
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		entrySize = uint32(binary.Size(IMAGE_ARM64_RUNTIME_FUNCTION_ENTRY{}))
+	default:
+		return nil, fmt.Errorf("exception directory format of machine %s is not supported", MachineName(machine))
+	}
+
+	offset, err := RvaToOffset(p.PeFile, excDir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var functions []RuntimeFunction
+	end := uint64(offset) + uint64(excDir.Size)
+	for ; uint64(offset)+uint64(entrySize) <= end; offset += entrySize {
+		if uint64(offset)+uint64(entrySize) > uint64(len(p.FileData)) {
+			return functions, fmt.Errorf("exception directory entry at 0x%X out of bounds", offset)
+		}
+		var function RuntimeFunction
+		if machine == pe.IMAGE_FILE_MACHINE_AMD64 {
+			function = p.readX64RuntimeFunction(offset, 0)
+		} else {
+			function = p.readArm64RuntimeFunction(offset)
+		}
+		functions = append(functions, function)
+	}
+
+	return functions, nil
+}
+
+func (p *PeFull) readX64RuntimeFunction(offset uint32, depth int) RuntimeFunction {
+	data := p.FileData[offset:]
+	function := RuntimeFunction{
+		Offset:       offset,
+		BeginAddress: binary.LittleEndian.Uint32(data),
+		EndAddress:   binary.LittleEndian.Uint32(data[4:]),
+		UnwindData:   binary.LittleEndian.Uint32(data[8:]),
+	}
+	if section := p.SectionForRVA(function.BeginAddress); section != nil {
+		function.Section = section.Name
+	}
+	if function.UnwindData == 0 {
+		return function
+	}
+
+	// An odd address points at another RUNTIME_FUNCTION whose unwind
+	// info this function shares.
+	if function.UnwindData&1 != 0 {
+		target, err := p.chainedRuntimeFunction(function.UnwindData&^1, depth)
+		if err != nil {
+			function.Err = err
+			return function
+		}
+		function.Unwind = &UnwindInfo{RVA: function.UnwindData, Chained: target}
+		return function
+	}
+
+	function.Unwind, function.Err = p.readX64UnwindInfo(function.UnwindData, depth)
+	return function
+}
+
+// chainedRuntimeFunction reads the RUNTIME_FUNCTION at rva that a chained
+// unwind info refers to.
+func (p *PeFull) chainedRuntimeFunction(rva uint32, depth int) (*RuntimeFunction, error) {
+	if depth >= maxUnwindChain {
+		return nil, fmt.Errorf("unwind chain longer than %d entries", maxUnwindChain)
+	}
+	offset, ok := p.FileBackedOffset(rva, uint32(binary.Size(IMAGE_RUNTIME_FUNCTION_ENTRY{})))
+	if !ok {
+		return nil, fmt.Errorf("chained function entry at RVA 0x%X is not in the file", rva)
+	}
+	chained := p.readX64RuntimeFunction(offset, depth+1)
+	return &chained, nil
+}
+
+func (p *PeFull) readX64UnwindInfo(rva uint32, depth int) (*UnwindInfo, error) {
+	offset, ok := p.FileBackedOffset(rva, 4)
+	if !ok {
+		return nil, fmt.Errorf("unwind info at RVA 0x%X is not in the file", rva)
+	}
+	data := p.FileData[offset:]
+	info := &UnwindInfo{
+		Offset:        offset,
+		RVA:           rva,
+		Version:       data[0] & 0x7,
+		Flags:         data[0] >> 3,
+		SizeOfProlog:  data[1],
+		CountOfCodes:  data[2],
+		FrameRegister: data[3] & 0xF,
+		FrameOffset:   data[3] >> 4,
+	}
+
+	// The code array is padded to an even number of slots
+	slots := (uint32(info.CountOfCodes) + 1) &^ 1
+	if _, ok := p.FileBackedOffset(rva, 4+slots*2); !ok {
+		return info, fmt.Errorf("unwind codes at RVA 0x%X are not in the file", rva+4)
+	}
+	codes := data[4 : 4+uint32(info.CountOfCodes)*2]
+	for i := 0; i < len(codes); {
+		code, used := decodeX64UnwindCode(info, codes[i:])
+		code.Offset = offset + 4 + uint32(i)
+		info.Codes = append(info.Codes, code)
+		i += used * 2
+	}
+
+	tail := rva + 4 + slots*2
+	if info.Flags&UNW_FLAG_CHAININFO != 0 {
+		chained, err := p.chainedRuntimeFunction(tail, depth)
+		info.Chained = chained
+		return info, err
+	}
+
+	if info.Flags&(UNW_FLAG_EHANDLER|UNW_FLAG_UHANDLER) != 0 {
+		return info, p.readUnwindHandler(info, tail)
+	}
+	return info, nil
+}
+
+// readUnwindHandler reads the exception handler RVA at rva and the start of
+// the handler data that follows it.
+func (p *PeFull) readUnwindHandler(info *UnwindInfo, rva uint32) error {
+	handlerOffset, ok := p.FileBackedOffset(rva, 4)
+	if !ok {
+		return fmt.Errorf("exception handler at RVA 0x%X is not in the file", rva)
+	}
+	info.HasHandler = true
+	info.HandlerOffset = handlerOffset
+	info.HandlerRVA = binary.LittleEndian.Uint32(p.FileData[handlerOffset:])
+	info.HandlerDataRVA = rva + 4
+	if dataOffset, ok := p.FileBackedOffset(info.HandlerDataRVA, 1); ok {
+		dataEnd := min(uint64(dataOffset)+handlerDataPreview, uint64(len(p.FileData)))
+		info.HandlerData = p.FileData[dataOffset:dataEnd]
+	}
+	return nil
+}
+
+// decodeX64UnwindCode decodes the unwind code at the start of codes and
+// returns it with the number of slots it takes up.
+func decodeX64UnwindCode(info *UnwindInfo, codes []byte) (UnwindCode, int) {
+	op := codes[1] & 0xF
+	opInfo := codes[1] >> 4
+	code := UnwindCode{CodeOffset: int(codes[0])}
+
+	// slot reads the 16-bit operand slot n places after the code, and
+	// slot32 the 32-bit operand spanning the next two slots.
+	slot := func(n int) (uint32, bool) {
+		if len(codes) < 2*n+2 {
+			return 0, false
+		}
+		return uint32(binary.LittleEndian.Uint16(codes[2*n:])), true
+	}
+	slot32 := func() (uint32, bool) {
+		if len(codes) < 6 {
+			return 0, false
+		}
+		return binary.LittleEndian.Uint32(codes[2:]), true
+	}
+
+	used := 1
+	var operand uint32
+	ok := true
+	switch op {
+	case UWOP_PUSH_NONVOL:
+		code.Op = "UWOP_PUSH_NONVOL"
+		code.Text = "push " + x64Registers[opInfo]
+	case UWOP_ALLOC_LARGE:
+		code.Op = "UWOP_ALLOC_LARGE"
+		if opInfo == 0 {
+			used = 2
+			operand, ok = slot(1)
+			operand *= 8
+		} else {
+			used = 3
+			operand, ok = slot32()
+		}
+		code.Text = fmt.Sprintf("sub rsp, 0x%X", operand)
+	case UWOP_ALLOC_SMALL:
+		code.Op = "UWOP_ALLOC_SMALL"
+		code.Text = fmt.Sprintf("sub rsp, 0x%X", uint32(opInfo)*8+8)
+	case UWOP_SET_FPREG:
+		code.Op = "UWOP_SET_FPREG"
+		code.Text = fmt.Sprintf("lea %s, [rsp+0x%X]", x64Registers[info.FrameRegister], uint32(info.FrameOffset)*16)
+	case UWOP_SAVE_NONVOL, UWOP_SAVE_NONVOL_FAR:
+		if op == UWOP_SAVE_NONVOL {
+			code.Op = "UWOP_SAVE_NONVOL"
+			used = 2
+			operand, ok = slot(1)
+			operand *= 8
+		} else {
+			code.Op = "UWOP_SAVE_NONVOL_FAR"
+			used = 3
+			operand, ok = slot32()
+		}
+		code.Text = fmt.Sprintf("mov [rsp+0x%X], %s", operand, x64Registers[opInfo])
+	case UWOP_EPILOG:
+		if info.Version < 2 {
+			code.Op = "UWOP_SAVE_XMM"
+			used = 2
+			operand, ok = slot(1)
+			code.Text = fmt.Sprintf("movlpd [rsp+0x%X], xmm%d", operand*8, opInfo)
+			break
+		}
+		code.Op = "UWOP_EPILOG"
+		used = 2
+		code.Text = fmt.Sprintf("epilog at offset 0x%X from the end", uint32(opInfo)<<8|uint32(codes[0]))
+	case UWOP_SPARE_CODE:
+		if info.Version < 2 {
+			code.Op = "UWOP_SAVE_XMM_FAR"
+			used = 3
+			operand, ok = slot32()
+			code.Text = fmt.Sprintf("movlpd [rsp+0x%X], xmm%d", operand, opInfo)
+			break
+		}
+		code.Op = "UWOP_SPARE_CODE"
+		used = 3
+	case UWOP_SAVE_XMM128, UWOP_SAVE_XMM128_FAR:
+		if op == UWOP_SAVE_XMM128 {
+			code.Op = "UWOP_SAVE_XMM128"
+			used = 2
+			operand, ok = slot(1)
+			operand *= 16
+		} else {
+			code.Op = "UWOP_SAVE_XMM128_FAR"
+			used = 3
+			operand, ok = slot32()
+		}
+		code.Text = fmt.Sprintf("movaps [rsp+0x%X], xmm%d", operand, opInfo)
+	case UWOP_PUSH_MACHFRAME:
+		code.Op = "UWOP_PUSH_MACHFRAME"
+		code.Text = "push machine frame"
+		if opInfo == 1 {
+			code.Text += " with error code"
+		}
+	default:
+		code.Op = fmt.Sprintf("UWOP_%d", op)
+	}
+
+	if !ok || 2*used > len(codes) {
+		code.Text = "truncated unwind code"
+		used = len(codes) / 2
+	}
+	code.Raw = codes[:2*used]
+	return code, used
+}
+
+func (p *PeFull) readArm64RuntimeFunction(offset uint32) RuntimeFunction {
+	data := p.FileData[offset:]
+	function := RuntimeFunction{
+		Offset:       offset,
+		BeginAddress: binary.LittleEndian.Uint32(data),
+		UnwindData:   binary.LittleEndian.Uint32(data[4:]),
+	}
+	if section := p.SectionForRVA(function.BeginAddress); section != nil {
+		function.Section = section.Name
+	}
+
+	switch flag := uint8(function.UnwindData & 3); flag {
+	case 0:
+		function.Unwind, function.Err = p.readArm64UnwindInfo(function.UnwindData)
+		if function.Unwind != nil {
+			function.EndAddress = function.BeginAddress + function.Unwind.FunctionLength
+		}
+	case 1, 2:
+		word := function.UnwindData
+		function.Packed = &Arm64PackedUnwind{
+			Flag:           flag,
+			FunctionLength: (word >> 2 & 0x7FF) * 4,
+			RegF:           uint8(word >> 13 & 0x7),
+			RegI:           uint8(word >> 16 & 0xF),
+			H:              word>>20&1 != 0,
+			CR:             uint8(word >> 21 & 0x3),
+			FrameSize:      (word >> 23) * 16,
+		}
+		function.EndAddress = function.BeginAddress + function.Packed.FunctionLength
+	default:
+		function.Err = fmt.Errorf("reserved unwind data flag 3")
+	}
+	return function
+}
+
+func (p *PeFull) readArm64UnwindInfo(rva uint32) (*UnwindInfo, error) {
+	offset, ok := p.FileBackedOffset(rva, 4)
+	if !ok {
+		return nil, fmt.Errorf("unwind info at RVA 0x%X is not in the file", rva)
+	}
+	header := binary.LittleEndian.Uint32(p.FileData[offset:])
+	info := &UnwindInfo{
+		Offset:         offset,
+		RVA:            rva,
+		FunctionLength: (header & 0x3FFFF) * 4,
+		Version:        uint8(header >> 18 & 0x3),
+		HasHandler:     header>>20&1 != 0,
+		EpilogInHeader: header>>21&1 != 0,
+		EpilogCount:    header >> 22 & 0x1F,
+		CodeWords:      header >> 27,
+	}
+
+	// Counts too large for the header move to an extension word
+	next := rva + 4
+	if info.EpilogCount == 0 && info.CodeWords == 0 {
+		extOffset, ok := p.FileBackedOffset(next, 4)
+		if !ok {
+			return info, fmt.Errorf("unwind info extension at RVA 0x%X is not in the file", next)
+		}
+		ext := binary.LittleEndian.Uint32(p.FileData[extOffset:])
+		info.EpilogCount = ext & 0xFFFF
+		info.CodeWords = ext >> 16 & 0xFF
+		next += 4
+	}
+
+	if !info.EpilogInHeader {
+		scopesOffset, ok := p.FileBackedOffset(next, info.EpilogCount*4)
+		if !ok {
+			return info, fmt.Errorf("epilog scopes at RVA 0x%X are not in the file", next)
+		}
+		for i := uint32(0); i < info.EpilogCount; i++ {
+			scopeOffset := scopesOffset + i*4
+			scope := binary.LittleEndian.Uint32(p.FileData[scopeOffset:])
+			info.EpilogScopes = append(info.EpilogScopes, Arm64EpilogScope{
+				Offset:      scopeOffset,
+				StartOffset: (scope & 0x3FFFF) * 4,
+				StartIndex:  scope >> 22,
+			})
+		}
+		next += info.EpilogCount * 4
+	}
+
+	codesOffset, ok := p.FileBackedOffset(next, info.CodeWords*4)
+	if !ok {
+		return info, fmt.Errorf("unwind codes at RVA 0x%X are not in the file", next)
+	}
+	codes := p.FileData[codesOffset : codesOffset+info.CodeWords*4]
+	for i := 0; i < len(codes); {
+		code, used := decodeArm64UnwindCode(codes[i:])
+		code.Offset = codesOffset + uint32(i)
+		info.Codes = append(info.Codes, code)
+		i += used
+	}
+	next += info.CodeWords * 4
+
+	if info.HasHandler {
+		return info, p.readUnwindHandler(info, next)
+	}
+	return info, nil
+}
+
+// arm64Register names general purpose register n the way disassemblers do.
+func arm64Register(n uint32) string {
+	switch n {
+	case 29:
+		return "fp"
+	case 30:
+		return "lr"
+	}
+	return fmt.Sprintf("x%d", n)
+}
+
+// arm64CodeSize returns the length in bytes of the unwind code that starts
+// with b.
+func arm64CodeSize(b byte) int {
+	switch {
+	case b < 0xC0:
+		return 1
+	case b < 0xE0:
+		return 2
+	case b == 0xE0:
+		return 4
+	case b == 0xE2:
+		return 2
+	case b == 0xE7:
+		return 3
+	case b == 0xF8:
+		return 2
+	case b == 0xF9:
+		return 3
+	case b == 0xFA:
+		return 4
+	case b == 0xFB:
+		return 5
+	}
+	return 1
+}
+
+// decodeArm64UnwindCode decodes the ARM64 unwind code at the start of codes
+// and returns it with its length in bytes.
+func decodeArm64UnwindCode(codes []byte) (UnwindCode, int) {
+	size := arm64CodeSize(codes[0])
+	code := UnwindCode{CodeOffset: -1}
+	if size > len(codes) {
+		size = len(codes)
+		code.Raw = codes
+		code.Op = "truncated"
+		return code, size
+	}
+	code.Raw = codes[:size]
+
+	b := uint32(codes[0])
+	var w uint32 // the code as a big endian number, as the docs lay out the bits
+	for _, c := range code.Raw {
+		w = w<<8 | uint32(c)
+	}
+
+	switch {
+	case b < 0x20:
+		code.Op = "alloc_s"
+		code.Text = fmt.Sprintf("sub sp, sp, #0x%X", (b&0x1F)*16)
+	case b < 0x40:
+		code.Op = "save_r19r20_x"
+		code.Text = fmt.Sprintf("stp x19, x20, [sp, #-0x%X]!", (b&0x1F)*8)
+	case b < 0x80:
+		code.Op = "save_fplr"
+		code.Text = fmt.Sprintf("stp fp, lr, [sp, #0x%X]", (b&0x3F)*8)
+	case b < 0xC0:
+		code.Op = "save_fplr_x"
+		code.Text = fmt.Sprintf("stp fp, lr, [sp, #-0x%X]!", (b&0x3F+1)*8)
+	case b < 0xC8:
+		code.Op = "alloc_m"
+		code.Text = fmt.Sprintf("sub sp, sp, #0x%X", (w&0x7FF)*16)
+	case b < 0xCC:
+		reg := 19 + (w >> 6 & 0xF)
+		code.Op = "save_regp"
+		code.Text = fmt.Sprintf("stp %s, %s, [sp, #0x%X]", arm64Register(reg), arm64Register(reg+1), (w&0x3F)*8)
+	case b < 0xD0:
+		reg := 19 + (w >> 6 & 0xF)
+		code.Op = "save_regp_x"
+		code.Text = fmt.Sprintf("stp %s, %s, [sp, #-0x%X]!", arm64Register(reg), arm64Register(reg+1), (w&0x3F+1)*8)
+	case b < 0xD4:
+		code.Op = "save_reg"
+		code.Text = fmt.Sprintf("str %s, [sp, #0x%X]", arm64Register(19+(w>>6&0xF)), (w&0x3F)*8)
+	case b < 0xD6:
+		code.Op = "save_reg_x"
+		code.Text = fmt.Sprintf("str %s, [sp, #-0x%X]!", arm64Register(19+(w>>5&0xF)), (w&0x1F+1)*8)
+	case b < 0xD8:
+		code.Op = "save_lrpair"
+		code.Text = fmt.Sprintf("stp %s, lr, [sp, #0x%X]", arm64Register(19+2*(w>>6&0x7)), (w&0x3F)*8)
+	case b < 0xDA:
+		reg := 8 + (w >> 6 & 0x7)
+		code.Op = "save_fregp"
+		code.Text = fmt.Sprintf("stp d%d, d%d, [sp, #0x%X]", reg, reg+1, (w&0x3F)*8)
+	case b < 0xDC:
+		reg := 8 + (w >> 6 & 0x7)
+		code.Op = "save_fregp_x"
+		code.Text = fmt.Sprintf("stp d%d, d%d, [sp, #-0x%X]!", reg, reg+1, (w&0x3F+1)*8)
+	case b < 0xDE:
+		code.Op = "save_freg"
+		code.Text = fmt.Sprintf("str d%d, [sp, #0x%X]", 8+(w>>6&0x7), (w&0x3F)*8)
+	case b == 0xDE:
+		code.Op = "save_freg_x"
+		code.Text = fmt.Sprintf("str d%d, [sp, #-0x%X]!", 8+(w>>5&0x7), (w&0x1F+1)*8)
+	case b == 0xDF:
+		code.Op = "alloc_z"
+		code.Text = fmt.Sprintf("addvl sp, sp, #-%d", w&0xFF)
+	case b == 0xE0:
+		code.Op = "alloc_l"
+		code.Text = fmt.Sprintf("sub sp, sp, #0x%X", (w&0xFFFFFF)*16)
+	case b == 0xE1:
+		code.Op = "set_fp"
+		code.Text = "mov fp, sp"
+	case b == 0xE2:
+		code.Op = "add_fp"
+		code.Text = fmt.Sprintf("add fp, sp, #0x%X", (w&0xFF)*8)
+	case b == 0xE3:
+		code.Op = "nop"
+		code.Text = "nop"
+	case b == 0xE4:
+		code.Op = "end"
+	case b == 0xE5:
+		code.Op = "end_c"
+	case b == 0xE6:
+		code.Op = "save_next"
+		code.Text = "save the next register pair"
+	case b == 0xE7:
+		code.Op = "save_any_reg"
+		code.Text = arm64SaveAnyReg(w)
+	case b == 0xE8:
+		code.Op = "MSFT_OP_TRAP_FRAME"
+	case b == 0xE9:
+		code.Op = "MSFT_OP_MACHINE_FRAME"
+	case b == 0xEA:
+		code.Op = "MSFT_OP_CONTEXT"
+	case b == 0xEB:
+		code.Op = "MSFT_OP_EC_CONTEXT"
+	case b == 0xEC:
+		code.Op = "MSFT_OP_CLEAR_UNWOUND_TO_CALL"
+	case b == 0xFC:
+		code.Op = "pac_sign_lr"
+		code.Text = "pacibsp"
+	default:
+		code.Op = "reserved"
+	}
+	return code, size
+}
+
+// arm64SaveAnyReg decodes the 3 byte save_any_reg code 11100111 0pxrrrrr
+// ffoooooo: p saves a pair, x pre-decrements sp, ff picks X, D or Q
+// registers and o is the scaled offset.
+func arm64SaveAnyReg(w uint32) string {
+	pair := w>>14&1 != 0
+	writeback := w>>13&1 != 0
+	reg := w >> 8 & 0x1F
+	kind := w >> 6 & 0x3
+	offset := w & 0x3F
+
+	var prefix string
+	scale := uint32(8)
+	switch kind {
+	case 0:
+		prefix = "x"
+	case 1:
+		prefix = "d"
+	case 2:
+		prefix = "q"
+		scale = 16
+	default:
+		return "reserved register class"
+	}
+	name := func(n uint32) string {
+		if prefix == "x" {
+			return arm64Register(n)
+		}
+		return fmt.Sprintf("%s%d", prefix, n)
+	}
+
+	regs := name(reg)
+	op := "str"
+	if pair {
+		op = "stp"
+		regs += ", " + name(reg+1)
+	}
+	if writeback {
+		return fmt.Sprintf("%s %s, [sp, #-0x%X]!", op, regs, (offset+1)*16)
+	}
+	return fmt.Sprintf("%s %s, [sp, #0x%X]", op, regs, offset*scale)
+}
@@ -0,0 +1,239 @@
+package pefile
+
+import (
+	"debug/pe"
+	"strings"
+	"testing"
+)
+
+// x64ExceptionTestImage has a .text section with the unwind infos and a
+// .pdata section with these functions:
+//
+//	0: an EHANDLER unwind info with two codes
+//	1: a CHAININFO unwind info continuing function 0
+//	2: an unwind info RVA that is not mapped
+//	3: no unwind info
+//	4: an odd unwind address pointing at function 0's entry
+//	5: a chained unwind info that chains to itself
+func x64ExceptionTestImage() testImage {
+	text := newTestData(testSectionRVA(0))
+	text.put(make([]byte, 0x60))
+
+	unwind := text.put([]byte{
+		0x01 | UNW_FLAG_EHANDLER<<3, 5, 2, 0,
+		0x05, 0x32, // sub rsp, 0x20 after 5 bytes
+		0x01, 0x50, // push rbp after 1 byte
+	})
+	text.putStruct(uint32(0x1008))           // handler
+	text.put([]byte{0xAA, 0xBB, 0xCC, 0xDD}) // handler data
+
+	pdata := newTestData(testSectionRVA(1))
+	first := IMAGE_RUNTIME_FUNCTION_ENTRY{0x1000, 0x1010, unwind}
+	chained := text.put([]byte{0x01 | UNW_FLAG_CHAININFO<<3, 0, 0, 0})
+	text.putStruct(first)
+	loop := text.next()
+	text.put([]byte{0x01 | UNW_FLAG_CHAININFO<<3, 0, 0, 0})
+	text.putStruct(IMAGE_RUNTIME_FUNCTION_ENTRY{0x1050, 0x1060, loop})
+
+	functions := pdata.putStruct([]IMAGE_RUNTIME_FUNCTION_ENTRY{
+		first,
+		{0x1010, 0x1020, chained},
+		{0x1020, 0x1030, unmappedRVA},
+		{0x1030, 0x1040, 0},
+		{0x1040, 0x1050, testSectionRVA(1) | 1},
+		{0x1050, 0x1060, loop},
+	})
+
+	return testImage{
+		sections: []testSection{
+			{name: ".text", data: text.bytes(), characteristics: 0x60000020},
+			{name: ".pdata", data: pdata.bytes(), characteristics: 0x40000040},
+		},
+		dirs: map[int]DataDirectory{3: {VirtualAddress: functions, Size: 6 * 12}},
+	}
+}
+
+func TestExceptionTableX64(t *testing.T) {
+	p := x64ExceptionTestImage().parse(t)
+
+	functions, err := p.ExceptionTable()
+	if err != nil {
+		t.Fatalf("ExceptionTable: %v", err)
+	}
+	if len(functions) != 6 {
+		t.Fatalf("got %d functions, want 6", len(functions))
+	}
+	for i, function := range functions {
+		if function.BeginAddress != 0x1000+uint32(i)*0x10 || function.Section != ".text" {
+			t.Errorf("function %d at 0x%X in %q", i, function.BeginAddress, function.Section)
+		}
+	}
+
+	unwind := functions[0].Unwind
+	if functions[0].Err != nil || unwind == nil {
+		t.Fatalf("function 0: %v", functions[0].Err)
+	}
+	if unwind.Version != 1 || unwind.SizeOfProlog != 5 || unwind.CountOfCodes != 2 {
+		t.Errorf("unwind info = %+v", unwind)
+	}
+	if names := strings.Join(unwind.FlagNames(), " | "); names != "EHANDLER" {
+		t.Errorf("flags = %s", names)
+	}
+	wantCodes := []struct {
+		op, text string
+	}{
+		{"UWOP_ALLOC_SMALL", "sub rsp, 0x20"},
+		{"UWOP_PUSH_NONVOL", "push rbp"},
+	}
+	if len(unwind.Codes) != len(wantCodes) {
+		t.Fatalf("got %d unwind codes, want %d", len(unwind.Codes), len(wantCodes))
+	}
+	for i, want := range wantCodes {
+		if got := unwind.Codes[i]; got.Op != want.op || got.Text != want.text {
+			t.Errorf("code %d = %s %q, want %s %q", i, got.Op, got.Text, want.op, want.text)
+		}
+	}
+	if !unwind.HasHandler || unwind.HandlerRVA != 0x1008 || len(unwind.HandlerData) == 0 || unwind.HandlerData[0] != 0xAA {
+		t.Errorf("handler 0x%X, data % X", unwind.HandlerRVA, unwind.HandlerData)
+	}
+
+	if chained := functions[1].Unwind; chained == nil || chained.Chained == nil || chained.Chained.BeginAddress != 0x1000 || chained.Chained.Unwind == nil {
+		t.Errorf("function 1 is not chained to function 0: %+v", chained)
+	}
+	if functions[2].Err == nil || functions[2].Unwind != nil {
+		t.Errorf("function 2 = %+v, want an error", functions[2])
+	}
+	if functions[3].Err != nil || functions[3].Unwind != nil {
+		t.Errorf("function 3 = %+v, want no unwind info", functions[3])
+	}
+	if shared := functions[4].Unwind; shared == nil || shared.Chained == nil || shared.Chained.Unwind == nil || len(shared.Chained.Unwind.Codes) != 2 {
+		t.Errorf("function 4 does not share function 0's unwind info: %+v", shared)
+	}
+	// The loop is cut off after maxUnwindChain links, at the last of which
+	// the error is recorded
+	links := 0
+	function := &functions[5]
+	for function.Err == nil && function.Unwind != nil && function.Unwind.Chained != nil {
+		function = function.Unwind.Chained
+		links++
+	}
+	if function.Err == nil || links != maxUnwindChain {
+		t.Errorf("function 5 chains to itself %d times without an error", links)
+	}
+}
+
+func TestExceptionTableArm64(t *testing.T) {
+	text := newTestData(testSectionRVA(0))
+	text.put(make([]byte, 0x100))
+	// FunctionLength 0x80, E set, one epilog starting at code 1, one code word
+	xdata := text.putStruct(uint32(0x20 | 1<<21 | 1<<22 | 1<<27))
+	text.put([]byte{0x02, 0xE4, 0xE4, 0xE4})
+
+	pdata := newTestData(testSectionRVA(1))
+	// Packed: flag 1, FunctionLength 0x40, RegI 2, CR 3, FrameSize 0x20
+	packed := uint32(1 | 0x10<<2 | 2<<16 | 3<<21 | 2<<23)
+	functions := pdata.putStruct([]IMAGE_ARM64_RUNTIME_FUNCTION_ENTRY{
+		{0x1000, packed},
+		{0x1040, xdata},
+		{0x10C0, 3},
+		{0x10D0, unmappedRVA},
+	})
+
+	p := testImage{
+		machine: pe.IMAGE_FILE_MACHINE_ARM64,
+		sections: []testSection{
+			{name: ".text", data: text.bytes(), characteristics: 0x60000020},
+			{name: ".pdata", data: pdata.bytes(), characteristics: 0x40000040},
+		},
+		dirs: map[int]DataDirectory{3: {VirtualAddress: functions, Size: 4 * 8}},
+	}.parse(t)
+
+	got, err := p.ExceptionTable()
+	if err != nil {
+		t.Fatalf("ExceptionTable: %v", err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d functions, want 4", len(got))
+	}
+
+	want := Arm64PackedUnwind{Flag: 1, FunctionLength: 0x40, RegI: 2, CR: 3, FrameSize: 0x20}
+	if got[0].Packed == nil || *got[0].Packed != want || got[0].EndAddress != 0x1040 {
+		t.Errorf("packed function = %+v, %+v", got[0], got[0].Packed)
+	}
+
+	unwind := got[1].Unwind
+	if got[1].Err != nil || unwind == nil {
+		t.Fatalf("xdata function: %v", got[1].Err)
+	}
+	if unwind.FunctionLength != 0x80 || got[1].EndAddress != 0x10C0 || !unwind.EpilogInHeader || unwind.EpilogCount != 1 || unwind.CodeWords != 1 {
+		t.Errorf("xdata = %+v", unwind)
+	}
+	if len(unwind.Codes) != 4 || unwind.Codes[0].Op != "alloc_s" || unwind.Codes[0].Text != "sub sp, sp, #0x20" {
+		t.Errorf("unwind codes = %+v", unwind.Codes)
+	}
+
+	if got[2].Err == nil {
+		t.Error("reserved flag 3 decoded without an error")
+	}
+	if got[3].Err == nil || got[3].Unwind != nil {
+		t.Errorf("unmapped xdata = %+v", got[3])
+	}
+}
+
+func TestExceptionTableCorrupt(t *testing.T) {
+	unsupported := x64ExceptionTestImage()
+	unsupported.machine = pe.IMAGE_FILE_MACHINE_I386
+
+	unmapped := x64ExceptionTestImage()
+	unmapped.dirs[3] = DataDirectory{VirtualAddress: unmappedRVA, Size: 12}
+
+	// A file cut off in the middle of the second entry keeps the first
+	image := x64ExceptionTestImage()
+	truncated := image.bytes()
+	pdata := testFileAlignment + alignUp(uint32(len(image.sections[0].data)), testFileAlignment)
+	truncated = truncated[:pdata+12+6]
+
+	tests := []struct {
+		name  string
+		data  []byte
+		count int
+	}{
+		{"unsupported machine", unsupported.bytes(), 0},
+		{"unmapped directory", unmapped.bytes(), 0},
+		{"truncated entries", truncated, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(tt.data)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			functions, err := p.ExceptionTable()
+			if err == nil {
+				t.Error("ExceptionTable succeeded")
+			}
+			if len(functions) != tt.count {
+				t.Errorf("got %d functions, want %d", len(functions), tt.count)
+			}
+		})
+	}
+}
+
+// The tree has a single node for the exception directory; the functions
+// are listed in its table rather than one node each.
+func TestExceptionTableTree(t *testing.T) {
+	p := x64ExceptionTestImage().parse(t)
+	tree := GetPeTreeMap(p, "test.exe")
+
+	root := tree[tree[""][0]]
+	found := false
+	for _, node := range root {
+		found = found || node == "Exception Table"
+	}
+	if !found {
+		t.Errorf("root nodes = %q, want an Exception Table node", root)
+	}
+	if children := tree["Exception Table"]; len(children) != 0 {
+		t.Errorf("Exception Table has %d child nodes", len(children))
+	}
+}
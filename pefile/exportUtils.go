@@ -0,0 +1,186 @@
+package pefile
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+)
+
+type IMAGE_EXPORT_DIRECTORY struct {
+	Characteristics       uint32
+	TimeDateStamp         uint32
+	MajorVersion          uint16
+	MinorVersion          uint16
+	Name                  uint32
+	Base                  uint32
+	NumberOfFunctions     uint32
+	NumberOfNames         uint32
+	AddressOfFunctions    uint32
+	AddressOfNames        uint32
+	AddressOfNameOrdinals uint32
+}
+
+// ExportFunction is a single slot of the export address table.
+type ExportFunction struct {
+	Offset      uint32 // file offset of the function slot
+	Ordinal     uint32 // biased ordinal (Base + slot index)
+	FunctionRVA uint32
+	NameRVA     uint32 // 0 for exports by ordinal only
+	Name        string
+}
+
+type ExportTable struct {
+	Offset    uint32 // file offset of the export directory
+	Header    IMAGE_EXPORT_DIRECTORY
+	DllName   string
+	Functions []ExportFunction
+}
+
+func GetOffsetArrayUint32(peFile *pe.File, fileData []byte, rva uint32, size uint32) ([]uint32, error) {
+	offset, err := RvaToOffset(peFile, rva)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= uint32(len(fileData)) {
+		return nil, fmt.Errorf("offset 0x%X out of bounds", offset)
+	}
+	if uint64(size)*4 > uint64(len(fileData))-uint64(offset) {
+		return nil, fmt.Errorf("array of %d entries at 0x%X out of bounds", size, offset)
+	}
+
+	arr := make([]uint32, size)
+	addressOfFunctionsReader := bytes.NewReader(fileData[offset:])
+	if err := binary.Read(addressOfFunctionsReader, binary.LittleEndian, &arr); err != nil {
+		return nil, err
+	}
+
+	return arr, nil
+}
+
+func GetOffsetArrayUint16(peFile *pe.File, fileData []byte, rva uint32, size uint32) ([]uint16, error) {
+	offset, err := RvaToOffset(peFile, rva)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= uint32(len(fileData)) {
+		return nil, fmt.Errorf("offset 0x%X out of bounds", offset)
+	}
+	if uint64(size)*2 > uint64(len(fileData))-uint64(offset) {
+		return nil, fmt.Errorf("array of %d entries at 0x%X out of bounds", size, offset)
+	}
+
+	arr := make([]uint16, size)
+	addressOfFunctionsReader := bytes.NewReader(fileData[offset:])
+	if err := binary.Read(addressOfFunctionsReader, binary.LittleEndian, &arr); err != nil {
+		return nil, err
+	}
+
+	return arr, nil
+}
+
+func ReadStringFromRVA(peFile *pe.File, fileData []byte, rva uint32) (string, error) {
+	offset, err := RvaToOffset(peFile, rva)
+	if err != nil {
+		return "", err
+	}
+
+	// Ensure offset is within bounds
+	if offset >= uint32(len(fileData)) {
+		return "", fmt.Errorf("offset out of bounds")
+	}
+
+	// Read until the first null terminator
+	var strBytes []byte
+	for i := offset; i < uint32(len(fileData)); i++ {
+		if fileData[i] == 0 { // Null terminator found
+			break
+		}
+		strBytes = append(strBytes, fileData[i])
+	}
+
+	return string(strBytes), nil
+}
+
+// ExportTable decodes the export directory and its function, name and
+// ordinal arrays.
+func (p *PeFull) ExportTable() (*ExportTable, error) {
+	exportDir, ok := p.Directory("Export Table")
+	if !ok || !exportDir.Present() {
+		return nil, fmt.Errorf("no export table")
+	}
+
+	exportDirRawOffset, err := RvaToOffset(p.PeFile, exportDir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+	if exportDirRawOffset >= uint32(len(p.FileData)) {
+		return nil, fmt.Errorf("export directory offset 0x%X out of bounds", exportDirRawOffset)
+	}
+
+	exports := &ExportTable{Offset: exportDirRawOffset}
+	reader := bytes.NewReader(p.FileData[exportDirRawOffset:])
+	if err := binary.Read(reader, binary.LittleEndian, &exports.Header); err != nil {
+		return nil, err
+	}
+	exportHeader := exports.Header
+	exports.DllName, _ = ReadStringFromRVA(p.PeFile, p.FileData, exportHeader.Name)
+
+	// Read the function/address arrays
+	functions, err := GetOffsetArrayUint32(p.PeFile, p.FileData,
+		exportHeader.AddressOfFunctions,
+		exportHeader.NumberOfFunctions)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := GetOffsetArrayUint32(p.PeFile, p.FileData,
+		exportHeader.AddressOfNames,
+		exportHeader.NumberOfNames)
+	if err != nil {
+		return nil, err
+	}
+
+	nameOrdinals, err := GetOffsetArrayUint16(p.PeFile, p.FileData,
+		exportHeader.AddressOfNameOrdinals,
+		exportHeader.NumberOfNames)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert the Functions RVA to a file offset (for display only)
+	offset, err := RvaToOffset(p.PeFile, exportHeader.AddressOfFunctions)
+	if err != nil {
+		return nil, err
+	}
+
+	// nameOrdinals maps each name to an (unbiased) index into functions.
+	// The table can hold more slots than a name ordinal can address, so
+	// the slots are compared at full width.
+	slotToNameIndex := make(map[uint32]int)
+	for i := range names {
+		slotToNameIndex[uint32(nameOrdinals[i])] = i
+	}
+
+	// Loop over each function “slot” (i is 0-based, ordinal – base)
+	for i := range functions {
+		function := ExportFunction{
+			Offset:      offset,
+			Ordinal:     exportHeader.Base + uint32(i),
+			FunctionRVA: functions[i],
+		}
+
+		if nameIndex, exists := slotToNameIndex[uint32(i)]; exists {
+			// read the ASCII name at names[nameIndex]
+			if nameStr, err := ReadStringFromRVA(p.PeFile, p.FileData, names[nameIndex]); err == nil {
+				function.Name = nameStr
+				function.NameRVA = names[nameIndex]
+			}
+		}
+
+		exports.Functions = append(exports.Functions, function)
+		offset += 4 // each entry is a 4-byte RVA
+	}
+
+	return exports, nil
+}
@@ -0,0 +1,125 @@
+package pefile
+
+import "testing"
+
+// exportTestImage exports count functions from ordinal 5 on, slot 1 by the
+// name "Alpha" and slot 0 by "Beta".
+func exportTestImage(count int) testImage {
+	edata := newTestData(testSectionRVA(0))
+	dir := edata.put(make([]byte, 40))
+	dllName := edata.putString("test.dll")
+	alpha := edata.putString("Alpha")
+	beta := edata.putString("Beta")
+	edata.align(4)
+
+	functions := make([]uint32, count)
+	for i := range functions {
+		functions[i] = 0x5000 + uint32(i)
+	}
+	names := edata.putStruct([]uint32{alpha, beta})
+	ordinals := edata.putStruct([]uint16{1, 0})
+	edata.align(4)
+	addresses := edata.putStruct(functions)
+
+	section := edata.bytes()
+	header := newTestData(dir)
+	header.putStruct(IMAGE_EXPORT_DIRECTORY{
+		Name:                  dllName,
+		Base:                  5,
+		NumberOfFunctions:     uint32(count),
+		NumberOfNames:         2,
+		AddressOfFunctions:    addresses,
+		AddressOfNames:        names,
+		AddressOfNameOrdinals: ordinals,
+	})
+	copy(section[dir-testSectionRVA(0):], header.bytes())
+
+	return testImage{
+		characteristics: 0x2000, // DLL
+		sections:        []testSection{{name: ".edata", data: section, characteristics: 0x40000040}},
+		dirs:            map[int]DataDirectory{0: {VirtualAddress: dir, Size: uint32(len(section))}},
+	}
+}
+
+func TestExportTable(t *testing.T) {
+	exports, err := exportTestImage(3).parse(t).ExportTable()
+	if err != nil {
+		t.Fatalf("ExportTable: %v", err)
+	}
+	if exports.DllName != "test.dll" {
+		t.Errorf("DllName = %q", exports.DllName)
+	}
+
+	tests := []struct {
+		ordinal uint32
+		rva     uint32
+		name    string
+	}{
+		{5, 0x5000, "Beta"},
+		{6, 0x5001, "Alpha"},
+		{7, 0x5002, ""},
+	}
+	if len(exports.Functions) != len(tests) {
+		t.Fatalf("got %d functions, want %d", len(exports.Functions), len(tests))
+	}
+	for i, want := range tests {
+		got := exports.Functions[i]
+		if got.Ordinal != want.ordinal || got.FunctionRVA != want.rva || got.Name != want.name {
+			t.Errorf("function %d = %+v, want %+v", i, got, want)
+		}
+		if i > 0 && got.Offset != exports.Functions[i-1].Offset+4 {
+			t.Errorf("function %d at 0x%X", i, got.Offset)
+		}
+	}
+}
+
+// Name ordinals are 16 bits wide, so slot 0x10000 and above can't have a
+// name, and must not pick up the name of the slot 0x10000 below.
+func TestExportTableManySlots(t *testing.T) {
+	exports, err := exportTestImage(0x10002).parse(t).ExportTable()
+	if err != nil {
+		t.Fatalf("ExportTable: %v", err)
+	}
+	for _, slot := range []int{0x10000, 0x10001} {
+		if name := exports.Functions[slot].Name; name != "" {
+			t.Errorf("slot 0x%X is named %q", slot, name)
+		}
+	}
+	if exports.Functions[0].Name != "Beta" {
+		t.Errorf("slot 0 is named %q", exports.Functions[0].Name)
+	}
+}
+
+func TestExportTableCorrupt(t *testing.T) {
+	missing := exportTestImage(3)
+	delete(missing.dirs, 0)
+
+	unmapped := exportTestImage(3)
+	unmapped.dirs[0] = DataDirectory{VirtualAddress: unmappedRVA, Size: 40}
+
+	// NumberOfFunctions running past the end of the file
+	tooMany := exportTestImage(3).bytes()
+	numberOfFunctions := testFileAlignment + 20
+	tooMany[numberOfFunctions+2] = 0x10
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no export directory", missing.bytes()},
+		{"unmapped directory", unmapped.bytes()},
+		{"functions out of bounds", tooMany},
+		{"truncated directory", exportTestImage(3).bytes()[:testFileAlignment+20]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(tt.data)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			if exports, err := p.ExportTable(); err == nil {
+				t.Errorf("ExportTable = %+v, want an error", exports)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package pefile
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// Layout of the images testImage builds: the NT headers at testLfanew,
+// headers padded to one file alignment unit, and section i mapped at
+// testSectionRVA(i).
+const (
+	testLfanew           = 0x80
+	testFileAlignment    = 0x200
+	testSectionAlignment = 0x1000
+	testImageBase        = 0x140000000
+)
+
+type testSection struct {
+	name            string
+	data            []byte
+	characteristics uint32
+	virtualSize     uint32 // defaults to len(data)
+}
+
+// testImage describes a small PE image built in memory for the tests.
+type testImage struct {
+	machine            uint16 // defaults to AMD64
+	pe32               bool   // PE32 rather than PE32+
+	timeDateStamp      uint32
+	characteristics    uint16
+	dllCharacteristics uint16
+	checkSum           uint32
+	entryPoint         uint32
+	sections           []testSection
+	dirs               map[int]DataDirectory
+	overlay            []byte
+}
+
+// testSectionRVA is the RVA the builder maps the i-th section at.
+func testSectionRVA(i int) uint32 {
+	return uint32(i+1) * testSectionAlignment
+}
+
+func alignUp(n uint32, alignment uint32) uint32 {
+	return (n + alignment - 1) &^ (alignment - 1)
+}
+
+func (img testImage) bytes() []byte {
+	machine := img.machine
+	if machine == 0 {
+		machine = pe.IMAGE_FILE_MACHINE_AMD64
+	}
+
+	var dirs [16]pe.DataDirectory
+	for i, dir := range img.dirs {
+		dirs[i] = dir
+	}
+
+	sizeOfImage := testSectionRVA(len(img.sections))
+	var optHeader any
+	if img.pe32 {
+		optHeader = &pe.OptionalHeader32{
+			Magic:               IMAGE_NT_OPTIONAL_HDR32_MAGIC,
+			AddressOfEntryPoint: img.entryPoint,
+			ImageBase:           0x400000,
+			SectionAlignment:    testSectionAlignment,
+			FileAlignment:       testFileAlignment,
+			SizeOfImage:         sizeOfImage,
+			SizeOfHeaders:       testFileAlignment,
+			CheckSum:            img.checkSum,
+			Subsystem:           pe.IMAGE_SUBSYSTEM_WINDOWS_CUI,
+			DllCharacteristics:  img.dllCharacteristics,
+			NumberOfRvaAndSizes: 16,
+			DataDirectory:       dirs,
+		}
+	} else {
+		optHeader = &pe.OptionalHeader64{
+			Magic:               IMAGE_NT_OPTIONAL_HDR64_MAGIC,
+			AddressOfEntryPoint: img.entryPoint,
+			ImageBase:           testImageBase,
+			SectionAlignment:    testSectionAlignment,
+			FileAlignment:       testFileAlignment,
+			SizeOfImage:         sizeOfImage,
+			SizeOfHeaders:       testFileAlignment,
+			CheckSum:            img.checkSum,
+			Subsystem:           pe.IMAGE_SUBSYSTEM_WINDOWS_CUI,
+			DllCharacteristics:  img.dllCharacteristics,
+			NumberOfRvaAndSizes: 16,
+			DataDirectory:       dirs,
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, DOSHeader{E_magic: 0x5A4D, E_ifanew: testLfanew})
+	buf.Write(make([]byte, testLfanew-buf.Len()))
+	binary.Write(buf, binary.LittleEndian, NtHeaders{Signature: 0x00004550})
+	binary.Write(buf, binary.LittleEndian, pe.FileHeader{
+		Machine:              machine,
+		NumberOfSections:     uint16(len(img.sections)),
+		TimeDateStamp:        img.timeDateStamp,
+		SizeOfOptionalHeader: uint16(binary.Size(optHeader)),
+		Characteristics:      img.characteristics,
+	})
+	binary.Write(buf, binary.LittleEndian, optHeader)
+
+	rawOffset := uint32(testFileAlignment)
+	for i, section := range img.sections {
+		var name [8]uint8
+		copy(name[:], section.name)
+		virtualSize := section.virtualSize
+		if virtualSize == 0 {
+			virtualSize = uint32(len(section.data))
+		}
+		rawSize := alignUp(uint32(len(section.data)), testFileAlignment)
+		header := pe.SectionHeader32{
+			Name:            name,
+			VirtualSize:     virtualSize,
+			VirtualAddress:  testSectionRVA(i),
+			SizeOfRawData:   rawSize,
+			Characteristics: section.characteristics,
+		}
+		if rawSize != 0 {
+			header.PointerToRawData = rawOffset
+		}
+		binary.Write(buf, binary.LittleEndian, header)
+		rawOffset += rawSize
+	}
+	buf.Write(make([]byte, testFileAlignment-buf.Len()))
+
+	for _, section := range img.sections {
+		buf.Write(section.data)
+		buf.Write(make([]byte, alignUp(uint32(len(section.data)), testFileAlignment)-uint32(len(section.data))))
+	}
+	buf.Write(img.overlay)
+	return buf.Bytes()
+}
+
+// parse builds the image and decodes it, failing the test on error.
+func (img testImage) parse(t *testing.T) *PeFull {
+	t.Helper()
+	p, err := ParseBytes(img.bytes())
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	return p
+}
+
+// testData lays out the contents of a section that starts at rva.
+type testData struct {
+	rva uint32
+	buf bytes.Buffer
+}
+
+func newTestData(rva uint32) *testData {
+	return &testData{rva: rva}
+}
+
+// next is the RVA the next item will be placed at.
+func (d *testData) next() uint32 {
+	return d.rva + uint32(d.buf.Len())
+}
+
+// put appends raw bytes and returns their RVA.
+func (d *testData) put(data []byte) uint32 {
+	rva := d.next()
+	d.buf.Write(data)
+	return rva
+}
+
+// putStruct appends the little endian encoding of v and returns its RVA.
+func (d *testData) putStruct(v any) uint32 {
+	rva := d.next()
+	binary.Write(&d.buf, binary.LittleEndian, v)
+	return rva
+}
+
+// putString appends a NUL terminated string, padded to an even length.
+func (d *testData) putString(s string) uint32 {
+	rva := d.put(append([]byte(s), 0))
+	if d.buf.Len()%2 != 0 {
+		d.buf.WriteByte(0)
+	}
+	return rva
+}
+
+// align pads the data to a multiple of n bytes.
+func (d *testData) align(n int) {
+	for d.buf.Len()%n != 0 {
+		d.buf.WriteByte(0)
+	}
+}
+
+func (d *testData) bytes() []byte {
+	return d.buf.Bytes()
+}
+
+// testResource is one leaf of the tree resourceSection builds.
+type testResource struct {
+	typ  uint16
+	id   uint16
+	name string // used instead of id when set
+	lang uint16
+	data []byte
+	rva  uint32 // points the data entry here instead of at data when set
+}
+
+// resourceSection lays out a type, name and language directory tree for
+// resources, grouped by type in the order the types first appear, followed
+// by the data entries, the names and the data. rva is the RVA of the section
+// the tree is placed at.
+func resourceSection(rva uint32, resources []testResource) []byte {
+	var types []uint16
+	byType := map[uint16][]testResource{}
+	for _, r := range resources {
+		if _, ok := byType[r.typ]; !ok {
+			types = append(types, r.typ)
+		}
+		byType[r.typ] = append(byType[r.typ], r)
+	}
+	var leaves []testResource
+	for _, typ := range types {
+		leaves = append(leaves, byType[typ]...)
+	}
+
+	dirSize := func(entries int) uint32 { return 16 + 8*uint32(entries) }
+	offset := dirSize(len(types))
+	typeDirs := map[uint16]uint32{}
+	for _, typ := range types {
+		typeDirs[typ] = offset
+		offset += dirSize(len(byType[typ]))
+	}
+	langDirs := make([]uint32, len(leaves))
+	for i := range leaves {
+		langDirs[i] = offset
+		offset += dirSize(1)
+	}
+	dataEntries := make([]uint32, len(leaves))
+	for i := range leaves {
+		dataEntries[i] = offset
+		offset += 16
+	}
+	names := make([]uint32, len(leaves))
+	for i, leaf := range leaves {
+		if leaf.name != "" {
+			names[i] = offset
+			offset += 2 + 2*uint32(len(utf16.Encode([]rune(leaf.name))))
+		}
+	}
+
+	d := newTestData(rva)
+	putDir := func(named, ids int) {
+		d.putStruct(IMAGE_RESOURCE_DIRECTORY{NumberOfNamedEntries: uint16(named), NumberOfIdEntries: uint16(ids)})
+	}
+	putDir(0, len(types))
+	for _, typ := range types {
+		d.putStruct(IMAGE_RESOURCE_DIRECTORY_ENTRY{uint32(typ), resourceDataIsDir | typeDirs[typ]})
+	}
+	i := 0
+	for _, typ := range types {
+		named := 0
+		for _, leaf := range byType[typ] {
+			if leaf.name != "" {
+				named++
+			}
+		}
+		putDir(named, len(byType[typ])-named)
+		for range byType[typ] {
+			name := uint32(leaves[i].id)
+			if leaves[i].name != "" {
+				name = resourceNameIsString | names[i]
+			}
+			d.putStruct(IMAGE_RESOURCE_DIRECTORY_ENTRY{name, resourceDataIsDir | langDirs[i]})
+			i++
+		}
+	}
+	for i, leaf := range leaves {
+		putDir(0, 1)
+		d.putStruct(IMAGE_RESOURCE_DIRECTORY_ENTRY{uint32(leaf.lang), dataEntries[i]})
+	}
+
+	// The data goes after the names, so the entries are filled in last
+	entries := d.next()
+	d.put(make([]byte, 16*len(leaves)))
+	for _, leaf := range leaves {
+		if leaf.name != "" {
+			units := utf16.Encode([]rune(leaf.name))
+			d.putStruct(uint16(len(units)))
+			d.putStruct(units)
+		}
+	}
+	data := make([]IMAGE_RESOURCE_DATA_ENTRY, len(leaves))
+	for i, leaf := range leaves {
+		d.align(4)
+		data[i] = IMAGE_RESOURCE_DATA_ENTRY{OffsetToData: d.put(leaf.data), Size: uint32(len(leaf.data))}
+		if leaf.rva != 0 {
+			data[i].OffsetToData = leaf.rva
+		}
+	}
+
+	section := d.bytes()
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, data)
+	copy(section[entries-rva:], buf.Bytes())
+	return section
+}
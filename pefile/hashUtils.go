@@ -0,0 +1,87 @@
+package pefile
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ImpHash computes the import hash the way pefile does: the MD5 of the
+// comma separated "dll.function" pairs of every import, lowercased, with
+// .dll/.ocx/.sys dropped from DLL names and ordinals resolved by
+// OrdinalName.
+func (p *PeFull) ImpHash() (string, error) {
+	imports, err := p.ImportTable()
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, descriptor := range imports {
+		dllName := strings.ToLower(descriptor.DllName)
+		if i := strings.LastIndex(dllName, "."); i >= 0 {
+			switch dllName[i+1:] {
+			case "dll", "ocx", "sys":
+				dllName = dllName[:i]
+			}
+		}
+		for _, function := range descriptor.Functions {
+			name := function.Name
+			if function.ByOrdinal {
+				name = OrdinalName(descriptor.DllName, function.Ordinal)
+			}
+			// Entries whose hint/name could not be read have no name to hash
+			if name == "" {
+				continue
+			}
+			names = append(names, dllName+"."+strings.ToLower(name))
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no imported functions")
+	}
+
+	sum := md5.Sum([]byte(strings.Join(names, ",")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ExportHash computes an export hash along the same lines as ImpHash: the
+// MD5 of the comma separated export names, lowercased, in ordinal order.
+// Exports without a name count as "ord<n>".
+func (p *PeFull) ExportHash() (string, error) {
+	exports, err := p.ExportTable()
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, function := range exports.Functions {
+		// Unused slots of the address table are not exports
+		if function.FunctionRVA == 0 {
+			continue
+		}
+		name := function.Name
+		if name == "" {
+			name = fmt.Sprintf("ord%d", function.Ordinal)
+		}
+		names = append(names, strings.ToLower(name))
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no exported functions")
+	}
+
+	sum := md5.Sum([]byte(strings.Join(names, ",")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RichHeaderHash is the MD5 of the decoded Rich header, from "DanS" up to
+// "Rich", as pefile computes it.
+func (p *PeFull) RichHeaderHash() (string, error) {
+	rich, err := p.RichHeader()
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(rich.ClearData)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -1,4 +1,4 @@
-package main
+package pefile
 
 import (
 	"bytes"
@@ -34,28 +34,8 @@ type NtHeaders struct {
 	Signature uint32
 }
 
-type PeFull struct {
-	dos      *DOSHeader // dos header
-	nt       *NtHeaders // nt headers
-	peFile   *pe.File   // rest of the pe fields
-	fileData []byte     // raw file
-}
-
-type IMAGE_EXPORT_DIRECTORY struct {
-	Characteristics       uint32
-	TimeDateStamp         uint32
-	MajorVersion          uint16
-	MinorVersion          uint16
-	Name                  uint32
-	Base                  uint32
-	NumberOfFunctions     uint32
-	NumberOfNames         uint32
-	AddressOfFunctions    uint32
-	AddressOfNames        uint32
-	AddressOfNameOrdinals uint32
-}
-
-var directoryNames = []string{
+// DirectoryNames holds the names of the data directories, by index.
+var DirectoryNames = []string{
 	"Export Table",
 	"Import Table",
 	"Resource Table",
@@ -74,16 +54,7 @@ var directoryNames = []string{
 	"Reserved",
 }
 
-func NewPeFull(_dos *DOSHeader, _nt *NtHeaders, _peFile *pe.File, _fileData []byte) *PeFull {
-	return &PeFull{
-		dos:      _dos,
-		nt:       _nt,
-		peFile:   _peFile,
-		fileData: _fileData,
-	}
-}
-
-func parseDOSHeader(fileData []byte) (*DOSHeader, error) {
+func ParseDOSHeader(fileData []byte) (*DOSHeader, error) {
 
 	// The DOS Header is at the beginning of the file
 	header := DOSHeader{}
@@ -100,11 +71,14 @@ func parseDOSHeader(fileData []byte) (*DOSHeader, error) {
 	return &header, nil
 }
 
-func parseNtHeaders(fileData []byte, dos *DOSHeader) (*NtHeaders, error) {
+func ParseNtHeaders(fileData []byte, dos *DOSHeader) (*NtHeaders, error) {
 
 	// The NT Headers are a signature followed by the rest of the headers
 	headers := NtHeaders{}
-	reader := bytes.NewReader(fileData[int64(dos.E_ifanew):])
+	if uint64(dos.E_ifanew)+uint64(binary.Size(headers)) > uint64(len(fileData)) {
+		return nil, fmt.Errorf("NT Headers at 0x%X out of bounds", dos.E_ifanew)
+	}
+	reader := bytes.NewReader(fileData[dos.E_ifanew:])
 	err := binary.Read(reader, binary.LittleEndian, &headers)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read NT Headers: %v", err)
@@ -118,7 +92,7 @@ func parseNtHeaders(fileData []byte, dos *DOSHeader) (*NtHeaders, error) {
 	return &headers, nil
 }
 
-func rvaToOffset(pe *pe.File, rva uint32) (uint32, error) {
+func RvaToOffset(pe *pe.File, rva uint32) (uint32, error) {
 	for _, sh := range pe.Sections {
 		size := sh.VirtualSize
 		// Some tools pad VirtualSize to multiple of FileAlignment; make sure to handle that.
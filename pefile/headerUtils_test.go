@@ -0,0 +1,70 @@
+package pefile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseBytes(t *testing.T) {
+	image := testImage{
+		timeDateStamp: 0x5F000000,
+		sections:      []testSection{{name: ".text", data: []byte{0xC3}, characteristics: 0x60000020}},
+	}.bytes()
+
+	p, err := ParseBytes(image)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	if p.NtHeadersOffset() != testLfanew {
+		t.Errorf("NtHeadersOffset = 0x%X, want 0x%X", p.NtHeadersOffset(), testLfanew)
+	}
+	if got := p.PeFile.FileHeader.TimeDateStamp; got != 0x5F000000 {
+		t.Errorf("TimeDateStamp = 0x%X", got)
+	}
+	if len(p.Directories) != 16 || p.Directories[1].Name != "Import Table" {
+		t.Errorf("unexpected directories %+v", p.Directories)
+	}
+	if want := p.DataDirectoriesOffset() + 8; p.Directories[1].Offset != want {
+		t.Errorf("Import Table entry at 0x%X, want 0x%X", p.Directories[1].Offset, want)
+	}
+	if p.ImageBase() != testImageBase {
+		t.Errorf("ImageBase = 0x%X", p.ImageBase())
+	}
+}
+
+// withLfanew returns a copy of image with e_lfanew replaced.
+func withLfanew(image []byte, lfanew uint32) []byte {
+	image = append([]byte(nil), image...)
+	binary.LittleEndian.PutUint32(image[0x3C:], lfanew)
+	return image
+}
+
+func TestParseBytesCorrupt(t *testing.T) {
+	valid := testImage{sections: []testSection{{name: ".text", data: []byte{0xC3}}}}.bytes()
+
+	badSignature := append([]byte(nil), valid...)
+	badSignature[testLfanew] = 'X'
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated DOS header", valid[:0x20]},
+		{"bad MZ", append([]byte{'X', 'Z'}, valid[2:]...)},
+		{"e_lfanew past the end", withLfanew(valid, 0xFFFFFFF0)},
+		{"e_lfanew at the end", withLfanew(valid, uint32(len(valid)))},
+		{"e_lfanew wraps", withLfanew(valid, 0xFFFFFFFF)},
+		{"truncated NT headers", valid[:testLfanew+2]},
+		{"truncated file header", valid[:testLfanew+10]},
+		{"truncated optional header", valid[:testLfanew+4+20+16]},
+		{"bad PE signature", badSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseBytes(tt.data); err == nil {
+				t.Error("ParseBytes succeeded")
+			}
+		})
+	}
+}
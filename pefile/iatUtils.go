@@ -0,0 +1,78 @@
+package pefile
+
+import "fmt"
+
+// IatSlot is one pointer sized slot of the IAT directory.
+type IatSlot struct {
+	RVA        uint32
+	Offset     uint32 // file offset, valid when FileBacked is set
+	FileBacked bool
+	Value      uint64
+	// DllName is the DLL of the descriptor that claims the slot, or empty
+	// when no import descriptor points into it.
+	DllName string
+	// Function is the import stored in the slot, or nil when the slot is the
+	// null entry terminating the DLL's thunks.
+	Function *ImportFunction
+	Delayed  bool // claimed by a delay-load descriptor
+}
+
+// Claimed reports whether an import descriptor accounts for the slot.
+func (s *IatSlot) Claimed() bool {
+	return s.DllName != ""
+}
+
+// IatSlots lists the slots of the IAT directory and resolves each one back
+// to the import descriptor and function it belongs to.
+func (p *PeFull) IatSlots() ([]IatSlot, error) {
+	iatDir, ok := p.Directory("IAT")
+	if !ok || !iatDir.Present() {
+		return nil, fmt.Errorf("no IAT directory")
+	}
+
+	type claim struct {
+		dllName  string
+		function *ImportFunction
+		delayed  bool
+	}
+	thunkSize := p.thunkSize()
+	claims := map[uint32]claim{}
+	addClaims := func(dllName string, iatRVA uint32, functions []ImportFunction, delayed bool) {
+		if iatRVA == 0 {
+			return
+		}
+		for i := range functions {
+			claims[functions[i].IatRVA] = claim{dllName, &functions[i], delayed}
+		}
+		claims[iatRVA+uint32(len(functions))*thunkSize] = claim{dllName, nil, delayed}
+	}
+
+	var err error
+	if importDir, ok := p.Directory("Import Table"); ok && importDir.Present() {
+		var imports []ImportDescriptor
+		imports, err = p.ImportTable()
+		for _, descriptor := range imports {
+			addClaims(descriptor.DllName, descriptor.Descriptor.FirstThunk, descriptor.Functions, false)
+		}
+	}
+	if delayImports, _ := p.DelayImportTable(); len(delayImports) > 0 {
+		imageBase := p.ImageBase()
+		for _, descriptor := range delayImports {
+			addClaims(descriptor.DllName, descriptor.ToRVA(descriptor.Descriptor.ImportAddressTableRVA, imageBase), descriptor.Functions, true)
+		}
+	}
+
+	var slots []IatSlot
+	for rva := iatDir.VirtualAddress; uint64(rva)+uint64(thunkSize) <= uint64(iatDir.VirtualAddress)+uint64(iatDir.Size); rva += thunkSize {
+		slot := IatSlot{RVA: rva}
+		if slot.Offset, slot.FileBacked = p.FileBackedOffset(rva, thunkSize); slot.FileBacked {
+			slot.Value, _ = p.readThunk(slot.Offset)
+		}
+		if c, ok := claims[rva]; ok {
+			slot.DllName, slot.Function, slot.Delayed = c.dllName, c.function, c.delayed
+		}
+		slots = append(slots, slot)
+	}
+
+	return slots, err
+}
@@ -0,0 +1,312 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// GRPICONDIR is the header of an RT_GROUP_ICON resource.
+type GRPICONDIR struct {
+	Reserved uint16
+	Type     uint16 // 1 for icons
+	Count    uint16
+}
+
+// GRPICONDIRENTRY describes one image of an icon group. Unlike the entries
+// of a .ico file it refers to the image by RT_ICON resource id.
+type GRPICONDIRENTRY struct {
+	Width      uint8 // 0 means 256
+	Height     uint8 // 0 means 256
+	ColorCount uint8
+	Reserved   uint8
+	Planes     uint16
+	BitCount   uint16
+	BytesInRes uint32
+	ID         uint16
+}
+
+// Icon is a single image of an icon group together with its RT_ICON data.
+type Icon struct {
+	Entry  GRPICONDIRENTRY
+	Offset uint32 // file offset of the RT_ICON data, 0 if it is missing
+	Data   []byte
+}
+
+// IconGroup is a decoded RT_GROUP_ICON resource.
+type IconGroup struct {
+	Offset uint32 // file offset of the RT_GROUP_ICON data
+	Header GRPICONDIR
+	Icons  []Icon
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// IconGroups decodes every RT_GROUP_ICON resource, in resource order. A
+// group that can't be decoded is left out and the rest are still returned,
+// together with the first error.
+func (p *PeFull) IconGroups() ([]*IconGroup, error) {
+	resources, err := p.ResourceTable()
+	if resources == nil {
+		return nil, err
+	}
+
+	var groups []*IconGroup
+	var firstErr error
+	for _, typeEntry := range resources.Entries {
+		if typeEntry.IsNamed || typeEntry.ID != RT_GROUP_ICON || typeEntry.Directory == nil {
+			continue
+		}
+		for _, nameEntry := range typeEntry.Directory.Entries {
+			data := nameEntry.Data
+			if nameEntry.Directory != nil {
+				data = nameEntry.Directory.FirstData()
+			}
+			if data == nil {
+				continue
+			}
+			group, err := p.IconGroup(data)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			// A group cut short still holds the icons read before the error
+			if group != nil && (err == nil || len(group.Icons) > 0) {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	if len(groups) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, fmt.Errorf("no icons")
+	}
+	return groups, firstErr
+}
+
+// IconGroup decodes the RT_GROUP_ICON resource data and pairs each entry with
+// the RT_ICON resource it names.
+func (p *PeFull) IconGroup(data *ResourceData) (*IconGroup, error) {
+	raw, err := p.ResourceBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &IconGroup{Offset: data.DataOffset}
+	reader := bytes.NewReader(raw)
+	if err := binary.Read(reader, binary.LittleEndian, &group.Header); err != nil {
+		return nil, fmt.Errorf("failed to read icon group: %v", err)
+	}
+
+	resources, _ := p.ResourceTable()
+	for i := 0; i < int(group.Header.Count); i++ {
+		var icon Icon
+		if err := binary.Read(reader, binary.LittleEndian, &icon.Entry); err != nil {
+			return group, fmt.Errorf("failed to read icon group entry %d: %v", i, err)
+		}
+		if iconData := resources.Find(RT_ICON, icon.Entry.ID); iconData != nil {
+			if iconBytes, err := p.ResourceBytes(iconData); err == nil {
+				icon.Offset = iconData.DataOffset
+				icon.Data = iconBytes
+			}
+		}
+		group.Icons = append(group.Icons, icon)
+	}
+
+	return group, nil
+}
+
+// Width returns the width of the icon in pixels.
+func (i *Icon) Width() int {
+	if i.Entry.Width == 0 {
+		return 256
+	}
+	return int(i.Entry.Width)
+}
+
+// Height returns the height of the icon in pixels.
+func (i *Icon) Height() int {
+	if i.Entry.Height == 0 {
+		return 256
+	}
+	return int(i.Entry.Height)
+}
+
+// IsPNG reports whether the icon image is stored as PNG rather than as a DIB.
+func (i *Icon) IsPNG() bool {
+	return bytes.HasPrefix(i.Data, pngSignature)
+}
+
+// Decode decodes the icon image.
+func (i *Icon) Decode() (image.Image, error) {
+	if i.Data == nil {
+		return nil, fmt.Errorf("missing RT_ICON resource %d", i.Entry.ID)
+	}
+	if i.IsPNG() {
+		return png.Decode(bytes.NewReader(i.Data))
+	}
+	return decodeIconDIB(i.Data)
+}
+
+// Best returns the icon whose size is closest to size, preferring the one
+// with the most colours on a tie.
+func (g *IconGroup) Best(size int) *Icon {
+	var best *Icon
+	bestDistance := 0
+	for i := range g.Icons {
+		icon := &g.Icons[i]
+		if icon.Data == nil {
+			continue
+		}
+		distance := icon.Width() - size
+		if distance < 0 {
+			distance = -distance
+		}
+		if best == nil || distance < bestDistance ||
+			(distance == bestDistance && icon.Entry.BitCount > best.Entry.BitCount) {
+			best = icon
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// WriteICO writes the group as a .ico file.
+func (g *IconGroup) WriteICO(w io.Writer) error {
+	var icons []Icon
+	for _, icon := range g.Icons {
+		if icon.Data != nil {
+			icons = append(icons, icon)
+		}
+	}
+
+	// ICONDIR followed by a 16-byte ICONDIRENTRY per image
+	buf := new(bytes.Buffer)
+	header := GRPICONDIR{Type: 1, Count: uint16(len(icons))}
+	if err := binary.Write(buf, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	imageOffset := uint32(6 + 16*len(icons))
+	for _, icon := range icons {
+		entry := struct {
+			Width       uint8
+			Height      uint8
+			ColorCount  uint8
+			Reserved    uint8
+			Planes      uint16
+			BitCount    uint16
+			BytesInRes  uint32
+			ImageOffset uint32
+		}{
+			icon.Entry.Width, icon.Entry.Height, icon.Entry.ColorCount, icon.Entry.Reserved,
+			icon.Entry.Planes, icon.Entry.BitCount, uint32(len(icon.Data)), imageOffset,
+		}
+		if err := binary.Write(buf, binary.LittleEndian, entry); err != nil {
+			return err
+		}
+		imageOffset += uint32(len(icon.Data))
+	}
+	for _, icon := range icons {
+		buf.Write(icon.Data)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// decodeIconDIB decodes a BITMAPINFOHEADER based icon image: the colour
+// (XOR) bitmap followed by a 1-bit transparency (AND) mask, both bottom-up
+// and together twice as high as the icon.
+func decodeIconDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, fmt.Errorf("icon bitmap too short")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:]))) / 2
+	bitCount := int(binary.LittleEndian.Uint16(data[14:]))
+	compression := binary.LittleEndian.Uint32(data[16:])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:]))
+
+	if headerSize < 40 || headerSize > len(data) || width <= 0 || height <= 0 || width > 1024 || height > 1024 {
+		return nil, fmt.Errorf("bad icon bitmap header")
+	}
+	if compression != 0 { // BI_RGB
+		return nil, fmt.Errorf("unsupported icon bitmap compression %d", compression)
+	}
+
+	var palette []color.NRGBA
+	if bitCount <= 8 {
+		if colorsUsed == 0 {
+			colorsUsed = 1 << bitCount
+		}
+		for i := 0; i < colorsUsed; i++ {
+			pos := headerSize + i*4
+			if pos+4 > len(data) {
+				return nil, fmt.Errorf("icon palette out of bounds")
+			}
+			palette = append(palette, color.NRGBA{R: data[pos+2], G: data[pos+1], B: data[pos], A: 0xFF})
+		}
+	}
+
+	xorOffset := headerSize + len(palette)*4
+	xorStride := (width*bitCount + 31) / 32 * 4
+	andOffset := xorOffset + xorStride*height
+	andStride := (width + 31) / 32 * 4
+	if andOffset > len(data) {
+		return nil, fmt.Errorf("icon bitmap out of bounds")
+	}
+	hasMask := andOffset+andStride*height <= len(data)
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := data[xorOffset+(height-1-y)*xorStride:]
+		for x := 0; x < width; x++ {
+			var c color.NRGBA
+			switch bitCount {
+			case 1, 2, 4, 8:
+				bitPos := x * bitCount
+				index := int(row[bitPos/8]>>(8-bitCount-bitPos%8)) & (1<<bitCount - 1)
+				if index < len(palette) {
+					c = palette[index]
+				}
+			case 24:
+				c = color.NRGBA{R: row[x*3+2], G: row[x*3+1], B: row[x*3], A: 0xFF}
+			case 32:
+				c = color.NRGBA{R: row[x*4+2], G: row[x*4+1], B: row[x*4], A: row[x*4+3]}
+				hasAlpha = hasAlpha || c.A != 0
+			default:
+				return nil, fmt.Errorf("unsupported icon bit count %d", bitCount)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	// 32-bit images carry their own alpha; the mask only matters when the
+	// alpha channel is unused
+	if bitCount == 32 && hasAlpha {
+		return img, nil
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			transparent := false
+			if hasMask {
+				maskRow := data[andOffset+(height-1-y)*andStride:]
+				transparent = maskRow[x/8]&(0x80>>(x%8)) != 0
+			}
+			c := img.NRGBAAt(x, y)
+			c.A = 0xFF
+			if transparent {
+				c.A = 0
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img, nil
+}
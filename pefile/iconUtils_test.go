@@ -0,0 +1,158 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// testIconDIB is a 2x2 24-bit icon: red and a transparent pixel on top,
+// green and blue below.
+func testIconDIB() []byte {
+	d := newTestData(0)
+	d.putStruct([]uint32{40, 2, 4})
+	d.putStruct([]uint16{1, 24})
+	d.put(make([]byte, 24))
+	// Bottom-up colour rows, BGR, each padded to 4 bytes
+	d.put([]byte{0x00, 0xFF, 0x00, 0xFF, 0x00, 0x00, 0, 0})
+	d.put([]byte{0x00, 0x00, 0xFF, 0x00, 0x00, 0x00, 0, 0})
+	// Bottom-up mask rows
+	d.put([]byte{0x00, 0, 0, 0})
+	d.put([]byte{0x40, 0, 0, 0})
+	return d.bytes()
+}
+
+func testIconPNG(t *testing.T) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, image.NewNRGBA(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testIconGroupData builds an RT_GROUP_ICON resource with an entry for each
+// of the given icons, claiming count entries in its header.
+func testIconGroupData(count uint16, entries ...GRPICONDIRENTRY) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, GRPICONDIR{Type: 1, Count: count})
+	binary.Write(buf, binary.LittleEndian, entries)
+	return buf.Bytes()
+}
+
+// iconTestImage has two icons and three icon groups:
+//
+//	1: data at an unmapped RVA
+//	2: both icons and a third that has no RT_ICON resource
+//	3: claims two entries but holds only one
+func iconTestImage(t *testing.T) testImage {
+	dib := GRPICONDIRENTRY{Width: 2, Height: 2, Planes: 1, BitCount: 24, ID: 1}
+	pngIcon := GRPICONDIRENTRY{Width: 32, Height: 32, Planes: 1, BitCount: 32, ID: 2}
+	missing := GRPICONDIRENTRY{Width: 48, Height: 48, Planes: 1, BitCount: 32, ID: 9}
+
+	rsrc := resourceSection(testSectionRVA(0), []testResource{
+		{typ: RT_ICON, id: 1, data: testIconDIB()},
+		{typ: RT_ICON, id: 2, data: testIconPNG(t)},
+		{typ: RT_GROUP_ICON, id: 1, data: testIconGroupData(1, dib), rva: unmappedRVA},
+		{typ: RT_GROUP_ICON, id: 2, data: testIconGroupData(3, dib, pngIcon, missing)},
+		{typ: RT_GROUP_ICON, id: 3, data: testIconGroupData(2, pngIcon)},
+	})
+	return testImage{
+		sections: []testSection{{name: ".rsrc", data: rsrc, characteristics: 0x40000040}},
+		dirs:     map[int]DataDirectory{2: {VirtualAddress: testSectionRVA(0), Size: uint32(len(rsrc))}},
+	}
+}
+
+// A group that can't be read must not hide the others.
+func TestIconGroups(t *testing.T) {
+	p := iconTestImage(t).parse(t)
+
+	groups, err := p.IconGroups()
+	if err == nil {
+		t.Error("IconGroups did not report the unmapped group")
+	}
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+
+	icons := groups[0].Icons
+	if len(icons) != 3 {
+		t.Fatalf("group 2 has %d icons, want 3", len(icons))
+	}
+	if icons[0].Data == nil || icons[0].IsPNG() || !icons[1].IsPNG() || icons[2].Data != nil || icons[2].Offset != 0 {
+		t.Errorf("group 2 icons = %+v", icons)
+	}
+	if best := groups[0].Best(32); best != &icons[1] {
+		t.Errorf("Best(32) = %+v, want the 32x32 icon", best)
+	}
+	if best := groups[0].Best(1); best != &icons[0] {
+		t.Errorf("Best(1) = %+v, want the 2x2 icon", best)
+	}
+
+	// The short group keeps the entry read before running out of data
+	if len(groups[1].Icons) != 1 || groups[1].Icons[0].Entry.ID != 2 {
+		t.Errorf("group 3 icons = %+v", groups[1].Icons)
+	}
+}
+
+func TestIconGroupsNone(t *testing.T) {
+	rsrc := resourceSection(testSectionRVA(0), []testResource{
+		{typ: RT_GROUP_ICON, id: 1, rva: unmappedRVA},
+	})
+	p := testImage{
+		sections: []testSection{{name: ".rsrc", data: rsrc, characteristics: 0x40000040}},
+		dirs:     map[int]DataDirectory{2: {VirtualAddress: testSectionRVA(0), Size: uint32(len(rsrc))}},
+	}.parse(t)
+
+	if groups, err := p.IconGroups(); err == nil || groups != nil {
+		t.Errorf("IconGroups = %v, %v, want an error", groups, err)
+	}
+}
+
+func TestIconDecode(t *testing.T) {
+	icon := Icon{Data: testIconDIB()}
+	img, err := icon.Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	tests := []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{0, 0, color.NRGBA{R: 0xFF, A: 0xFF}},
+		{1, 0, color.NRGBA{}},
+		{0, 1, color.NRGBA{G: 0xFF, A: 0xFF}},
+		{1, 1, color.NRGBA{B: 0xFF, A: 0xFF}},
+	}
+	for _, tt := range tests {
+		if got := color.NRGBAModel.Convert(img.At(tt.x, tt.y)); got != tt.want {
+			t.Errorf("pixel (%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	for _, data := range [][]byte{nil, testIconDIB()[:40], testIconDIB()[:20]} {
+		if _, err := (&Icon{Data: data}).Decode(); err == nil {
+			t.Errorf("Decode of %d bytes succeeded", len(data))
+		}
+	}
+}
+
+// The .ico file written for a group leaves out the icons that are missing.
+func TestIconWriteICO(t *testing.T) {
+	groups, _ := iconTestImage(t).parse(t).IconGroups()
+	buf := new(bytes.Buffer)
+	if err := groups[0].WriteICO(buf); err != nil {
+		t.Fatalf("WriteICO: %v", err)
+	}
+	ico := buf.Bytes()
+	if count := binary.LittleEndian.Uint16(ico[4:]); count != 2 {
+		t.Fatalf("ico has %d images, want 2", count)
+	}
+	dibOffset := binary.LittleEndian.Uint32(ico[6+12:])
+	if !bytes.Equal(ico[dibOffset:dibOffset+40], testIconDIB()[:40]) {
+		t.Error("first image is not the DIB")
+	}
+}
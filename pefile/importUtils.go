@@ -0,0 +1,183 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+type IMAGE_IMPORT_DESCRIPTOR struct {
+	OriginalFirstThunk uint32
+	TimeDateStamp      uint32
+	ForwarderChain     uint32
+	Name               uint32
+	FirstThunk         uint32
+}
+
+// ImportFunction is one entry of an import lookup table (ILT) together with
+// the matching import address table (IAT) slot.
+type ImportFunction struct {
+	IltRVA     uint32
+	IltOffset  uint32
+	IatRVA     uint32
+	IatOffset  uint32
+	ThunkValue uint64 // raw ILT entry
+	ByOrdinal  bool
+	Ordinal    uint16 // valid when ByOrdinal is set
+	Hint       uint16 // valid when ByOrdinal is not set
+	Name       string // valid when ByOrdinal is not set
+}
+
+type ImportDescriptor struct {
+	Offset     uint32 // file offset of the descriptor
+	Descriptor IMAGE_IMPORT_DESCRIPTOR
+	DllName    string
+	Functions  []ImportFunction
+	Err        error // set when the DLL name or its functions could not be read
+}
+
+// readThunk reads a pointer sized value at the given file offset.
+func (p *PeFull) readThunk(offset uint32) (uint64, error) {
+	if p.Is64Bit() {
+		if uint64(offset)+8 > uint64(len(p.FileData)) {
+			return 0, fmt.Errorf("thunk at 0x%X out of bounds", offset)
+		}
+		return binary.LittleEndian.Uint64(p.FileData[offset:]), nil
+	}
+	if uint64(offset)+4 > uint64(len(p.FileData)) {
+		return 0, fmt.Errorf("thunk at 0x%X out of bounds", offset)
+	}
+	return uint64(binary.LittleEndian.Uint32(p.FileData[offset:])), nil
+}
+
+// thunkSize is the size in bytes of an ILT/IAT entry.
+func (p *PeFull) thunkSize() uint32 {
+	if p.Is64Bit() {
+		return 8
+	}
+	return 4
+}
+
+// ordinalFlag is IMAGE_ORDINAL_FLAG32 or IMAGE_ORDINAL_FLAG64.
+func (p *PeFull) ordinalFlag() uint64 {
+	if p.Is64Bit() {
+		return 0x8000000000000000
+	}
+	return 0x80000000
+}
+
+// ImportTable decodes every IMAGE_IMPORT_DESCRIPTOR up to the terminating
+// null descriptor, together with the functions each DLL provides. A
+// descriptor that can't be fully read keeps its error in Err and doesn't
+// stop the walk.
+func (p *PeFull) ImportTable() ([]ImportDescriptor, error) {
+	importDir, ok := p.Directory("Import Table")
+	if !ok || !importDir.Present() {
+		return nil, fmt.Errorf("no import table")
+	}
+
+	offset, err := RvaToOffset(p.PeFile, importDir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []ImportDescriptor
+	descriptorSize := uint32(binary.Size(IMAGE_IMPORT_DESCRIPTOR{}))
+	for ; uint64(offset)+uint64(descriptorSize) <= uint64(len(p.FileData)); offset += descriptorSize {
+		var descriptor IMAGE_IMPORT_DESCRIPTOR
+		reader := bytes.NewReader(p.FileData[offset:])
+		if err := binary.Read(reader, binary.LittleEndian, &descriptor); err != nil {
+			return imports, err
+		}
+		if descriptor == (IMAGE_IMPORT_DESCRIPTOR{}) {
+			break
+		}
+
+		entry := ImportDescriptor{Offset: offset, Descriptor: descriptor}
+		entry.DllName, entry.Err = ReadStringFromRVA(p.PeFile, p.FileData, descriptor.Name)
+		if entry.Err != nil {
+			entry.Err = fmt.Errorf("import descriptor at 0x%X: %v", offset, entry.Err)
+			imports = append(imports, entry)
+			continue
+		}
+
+		entry.Functions, err = p.readImportThunks(descriptor.OriginalFirstThunk, descriptor.FirstThunk, 0)
+		if err != nil {
+			entry.Err = fmt.Errorf("imports of %s: %v", entry.DllName, err)
+		}
+		imports = append(imports, entry)
+	}
+
+	return imports, nil
+}
+
+// readImportThunks walks an import lookup table and the import address table
+// next to it. Old linkers leave the ILT out, in which case the IAT is read
+// instead. nameBase is subtracted from hint/name addresses that are VAs.
+// An entry whose hint/name can't be read is kept without a name, and the
+// first such error is returned once the walk is done.
+func (p *PeFull) readImportThunks(iltRVA uint32, iatRVA uint32, nameBase uint64) ([]ImportFunction, error) {
+	if iltRVA == 0 {
+		iltRVA = iatRVA
+	}
+	if iltRVA == 0 {
+		return nil, nil
+	}
+
+	iltOffset, err := RvaToOffset(p.PeFile, iltRVA)
+	if err != nil {
+		return nil, err
+	}
+	var iatOffset uint32
+	if iatRVA != 0 {
+		iatOffset, err = RvaToOffset(p.PeFile, iatRVA)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var functions []ImportFunction
+	var nameErr error
+	thunkSize := p.thunkSize()
+	for {
+		thunk, err := p.readThunk(iltOffset)
+		if err != nil {
+			return functions, err
+		}
+		if thunk == 0 {
+			break
+		}
+
+		function := ImportFunction{
+			IltRVA:     iltRVA,
+			IltOffset:  iltOffset,
+			IatRVA:     iatRVA,
+			IatOffset:  iatOffset,
+			ThunkValue: thunk,
+		}
+		if thunk&p.ordinalFlag() != 0 {
+			function.ByOrdinal = true
+			function.Ordinal = uint16(thunk)
+		} else {
+			// Bits 0-30 hold the RVA of an IMAGE_IMPORT_BY_NAME
+			hintNameRVA := uint32((thunk - nameBase) & 0x7FFFFFFF)
+			hintNameOffset, err := RvaToOffset(p.PeFile, hintNameRVA)
+			if err == nil && uint64(hintNameOffset)+2 <= uint64(len(p.FileData)) {
+				function.Hint = binary.LittleEndian.Uint16(p.FileData[hintNameOffset:])
+				function.Name, _ = ReadStringFromRVA(p.PeFile, p.FileData, hintNameRVA+2)
+			} else if nameErr == nil {
+				nameErr = fmt.Errorf("bad hint/name RVA 0x%X", hintNameRVA)
+			}
+		}
+		functions = append(functions, function)
+
+		iltRVA += thunkSize
+		iltOffset += thunkSize
+		if iatRVA != 0 {
+			iatRVA += thunkSize
+			iatOffset += thunkSize
+		}
+	}
+
+	return functions, nameErr
+}
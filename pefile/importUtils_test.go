@@ -0,0 +1,216 @@
+package pefile
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// unmappedRVA is an RVA no test image maps.
+const unmappedRVA = 0x7FFF0000
+
+// putHintName appends an IMAGE_IMPORT_BY_NAME entry.
+func (d *testData) putHintName(hint uint16, name string) uint32 {
+	rva := d.putStruct(hint)
+	d.putString(name)
+	return rva
+}
+
+// putThunks64 appends a null terminated 64-bit thunk array.
+func (d *testData) putThunks64(thunks ...uint64) uint32 {
+	d.align(8)
+	return d.putStruct(append(thunks, 0))
+}
+
+// importTestImage has three import descriptors: KERNEL32.dll, which decodes
+// fine, one whose DLL name is unmapped, and USER32.dll whose first function
+// has an unmapped hint/name entry. The IAT directory covers all their IATs.
+func importTestImage() testImage {
+	d := newTestData(testSectionRVA(0))
+	kernel32 := d.putString("KERNEL32.dll")
+	user32 := d.putString("USER32.dll")
+	getProcAddress := d.putHintName(0x10, "GetProcAddress")
+	loadLibrary := d.putHintName(0x20, "LoadLibraryA")
+	messageBox := d.putHintName(0x30, "MessageBoxA")
+
+	kernel32Thunks := []uint64{uint64(getProcAddress), uint64(loadLibrary), 0x8000000000000005}
+	user32Thunks := []uint64{unmappedRVA, uint64(messageBox)}
+	kernel32Ilt := d.putThunks64(kernel32Thunks...)
+	user32Ilt := d.putThunks64(user32Thunks...)
+	iatStart := d.next()
+	kernel32Iat := d.putThunks64(kernel32Thunks...)
+	badIat := d.putThunks64(1)
+	user32Iat := d.putThunks64(user32Thunks...)
+	iatSize := d.next() - iatStart
+
+	descriptors := d.putStruct([]IMAGE_IMPORT_DESCRIPTOR{
+		{OriginalFirstThunk: kernel32Ilt, Name: kernel32, FirstThunk: kernel32Iat},
+		{Name: unmappedRVA, FirstThunk: badIat},
+		{OriginalFirstThunk: user32Ilt, Name: user32, FirstThunk: user32Iat},
+		{},
+	})
+
+	return testImage{
+		sections: []testSection{{name: ".idata", data: d.bytes(), characteristics: 0xC0000040}},
+		dirs: map[int]DataDirectory{
+			1:  {VirtualAddress: descriptors, Size: 4 * 20},
+			12: {VirtualAddress: iatStart, Size: iatSize},
+		},
+	}
+}
+
+func TestImportTable(t *testing.T) {
+	p := importTestImage().parse(t)
+
+	imports, err := p.ImportTable()
+	if err != nil {
+		t.Fatalf("ImportTable: %v", err)
+	}
+	if len(imports) != 3 {
+		t.Fatalf("got %d descriptors, want 3", len(imports))
+	}
+
+	kernel32 := imports[0]
+	if kernel32.DllName != "KERNEL32.dll" || kernel32.Err != nil {
+		t.Errorf("descriptor 0 = %q, %v", kernel32.DllName, kernel32.Err)
+	}
+	wantFunctions := []struct {
+		name    string
+		hint    uint16
+		ordinal uint16
+	}{
+		{"GetProcAddress", 0x10, 0},
+		{"LoadLibraryA", 0x20, 0},
+		{"", 0, 5},
+	}
+	if len(kernel32.Functions) != len(wantFunctions) {
+		t.Fatalf("KERNEL32.dll has %d functions, want %d", len(kernel32.Functions), len(wantFunctions))
+	}
+	for i, want := range wantFunctions {
+		got := kernel32.Functions[i]
+		if got.Name != want.name || got.Hint != want.hint || got.Ordinal != want.ordinal || got.ByOrdinal != (want.name == "") {
+			t.Errorf("function %d = %+v, want %+v", i, got, want)
+		}
+		if got.IatRVA != kernel32.Descriptor.FirstThunk+uint32(i)*8 {
+			t.Errorf("function %d IAT RVA 0x%X", i, got.IatRVA)
+		}
+	}
+
+	if bad := imports[1]; bad.Err == nil || bad.DllName != "" {
+		t.Errorf("descriptor 1 = %q, %v; want an error", bad.DllName, bad.Err)
+	}
+
+	user32 := imports[2]
+	if user32.DllName != "USER32.dll" || user32.Err == nil {
+		t.Errorf("descriptor 2 = %q, %v; want a hint/name error", user32.DllName, user32.Err)
+	}
+	if len(user32.Functions) != 2 || user32.Functions[0].Name != "" || user32.Functions[1].Name != "MessageBoxA" {
+		t.Errorf("USER32.dll functions = %+v", user32.Functions)
+	}
+}
+
+func TestImportTableTree(t *testing.T) {
+	p := importTestImage().parse(t)
+	tree := GetPeTreeMap(p, "test.exe")
+
+	nodes := tree["Import Table"]
+	if len(nodes) != 3 {
+		t.Fatalf("Import Table nodes = %q, want 3", nodes)
+	}
+	if TreeNodeLabel(nodes[0]) != "KERNEL32.dll" || TreeNodeLabel(nodes[2]) != "USER32.dll" {
+		t.Errorf("Import Table nodes = %q", nodes)
+	}
+}
+
+func TestImpHash(t *testing.T) {
+	p := importTestImage().parse(t)
+
+	// md5("kernel32.getprocaddress,kernel32.loadlibrarya,kernel32.ord5,user32.messageboxa")
+	const want = "90d10fe7247b8833d4e82193d500e0cf"
+	got, err := p.ImpHash()
+	if err != nil {
+		t.Fatalf("ImpHash: %v", err)
+	}
+	if got != want {
+		t.Errorf("ImpHash = %s, want %s", got, want)
+	}
+}
+
+func TestIatSlots(t *testing.T) {
+	p := importTestImage().parse(t)
+
+	slots, err := p.IatSlots()
+	if err != nil {
+		t.Fatalf("IatSlots: %v", err)
+	}
+	// KERNEL32.dll: 3 functions and a terminator, the unnamed DLL: 1 thunk
+	// and a terminator, USER32.dll: 2 functions and a terminator
+	if len(slots) != 9 {
+		t.Fatalf("got %d slots, want 9", len(slots))
+	}
+	for i, slot := range slots {
+		if !slot.FileBacked {
+			t.Errorf("slot %d is not file backed", i)
+		}
+		// Only the unnamed DLL's slots are not claimed
+		if claimed := i < 4 || i >= 6; slot.Claimed() != claimed {
+			t.Errorf("slot %d claimed = %v, want %v", i, slot.Claimed(), claimed)
+		}
+	}
+	if slots[6].DllName != "USER32.dll" || slots[6].Function == nil || slots[7].Function.Name != "MessageBoxA" || slots[8].Function != nil {
+		t.Errorf("USER32.dll slots = %+v", slots[6:])
+	}
+	if slots[2].Value != 0x8000000000000005 {
+		t.Errorf("slot 2 value 0x%X", slots[2].Value)
+	}
+}
+
+func TestImportTableCorrupt(t *testing.T) {
+	valid := importTestImage()
+
+	unmapped := importTestImage()
+	unmapped.dirs[1] = DataDirectory{VirtualAddress: unmappedRVA, Size: 20}
+
+	// Descriptors that run off the end of the file stop at the last whole one
+	truncated := importTestImage().bytes()
+	descriptors := valid.dirs[1].VirtualAddress - testSectionRVA(0) + testFileAlignment
+	truncated = truncated[:descriptors+20+10]
+
+	tests := []struct {
+		name  string
+		data  []byte
+		count int
+	}{
+		{"unmapped directory", unmapped.bytes(), -1},
+		{"truncated descriptors", truncated, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(tt.data)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			imports, err := p.ImportTable()
+			if tt.count < 0 {
+				if err == nil {
+					t.Error("ImportTable succeeded")
+				}
+				return
+			}
+			if len(imports) != tt.count {
+				t.Errorf("got %d descriptors, want %d", len(imports), tt.count)
+			}
+		})
+	}
+}
+
+func TestReadThunk(t *testing.T) {
+	p := testImage{sections: []testSection{{name: ".text", data: []byte{0xC3}}}}.parse(t)
+	binary.LittleEndian.PutUint64(p.FileData[0x100:], 0x1122334455667788)
+
+	if got, err := p.readThunk(0x100); err != nil || got != 0x1122334455667788 {
+		t.Errorf("readThunk = 0x%X, %v", got, err)
+	}
+	if _, err := p.readThunk(uint32(len(p.FileData)) - 4); err == nil {
+		t.Error("readThunk read past the end of the file")
+	}
+}
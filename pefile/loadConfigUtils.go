@@ -0,0 +1,337 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+)
+
+// IMAGE_LOAD_CONFIG_DIRECTORY32 is the full 32-bit layout. Images carry a
+// prefix of it whose length is given by Size; missing fields read as zero.
+type IMAGE_LOAD_CONFIG_DIRECTORY32 struct {
+	Size                                     uint32
+	TimeDateStamp                            uint32
+	MajorVersion                             uint16
+	MinorVersion                             uint16
+	GlobalFlagsClear                         uint32
+	GlobalFlagsSet                           uint32
+	CriticalSectionDefaultTimeout            uint32
+	DeCommitFreeBlockThreshold               uint32
+	DeCommitTotalFreeThreshold               uint32
+	LockPrefixTable                          uint32
+	MaximumAllocationSize                    uint32
+	VirtualMemoryThreshold                   uint32
+	ProcessHeapFlags                         uint32
+	ProcessAffinityMask                      uint32
+	CSDVersion                               uint16
+	DependentLoadFlags                       uint16
+	EditList                                 uint32
+	SecurityCookie                           uint32
+	SEHandlerTable                           uint32
+	SEHandlerCount                           uint32
+	GuardCFCheckFunctionPointer              uint32
+	GuardCFDispatchFunctionPointer           uint32
+	GuardCFFunctionTable                     uint32
+	GuardCFFunctionCount                     uint32
+	GuardFlags                               uint32
+	CodeIntegrityFlags                       uint16
+	CodeIntegrityCatalog                     uint16
+	CodeIntegrityCatalogOffset               uint32
+	CodeIntegrityReserved                    uint32
+	GuardAddressTakenIatEntryTable           uint32
+	GuardAddressTakenIatEntryCount           uint32
+	GuardLongJumpTargetTable                 uint32
+	GuardLongJumpTargetCount                 uint32
+	DynamicValueRelocTable                   uint32
+	CHPEMetadataPointer                      uint32
+	GuardRFFailureRoutine                    uint32
+	GuardRFFailureRoutineFunctionPointer     uint32
+	DynamicValueRelocTableOffset             uint32
+	DynamicValueRelocTableSection            uint16
+	Reserved2                                uint16
+	GuardRFVerifyStackPointerFunctionPointer uint32
+	HotPatchTableOffset                      uint32
+	Reserved3                                uint32
+	EnclaveConfigurationPointer              uint32
+	VolatileMetadataPointer                  uint32
+	GuardEHContinuationTable                 uint32
+	GuardEHContinuationCount                 uint32
+	GuardXFGCheckFunctionPointer             uint32
+	GuardXFGDispatchFunctionPointer          uint32
+	GuardXFGTableDispatchFunctionPointer     uint32
+	CastGuardOsDeterminedFailureMode         uint32
+	GuardMemcpyFunctionPointer               uint32
+	UmaFunctionPointers                      uint32
+}
+
+// IMAGE_LOAD_CONFIG_DIRECTORY64 is the full 64-bit layout.
+type IMAGE_LOAD_CONFIG_DIRECTORY64 struct {
+	Size                                     uint32
+	TimeDateStamp                            uint32
+	MajorVersion                             uint16
+	MinorVersion                             uint16
+	GlobalFlagsClear                         uint32
+	GlobalFlagsSet                           uint32
+	CriticalSectionDefaultTimeout            uint32
+	DeCommitFreeBlockThreshold               uint64
+	DeCommitTotalFreeThreshold               uint64
+	LockPrefixTable                          uint64
+	MaximumAllocationSize                    uint64
+	VirtualMemoryThreshold                   uint64
+	ProcessAffinityMask                      uint64
+	ProcessHeapFlags                         uint32
+	CSDVersion                               uint16
+	DependentLoadFlags                       uint16
+	EditList                                 uint64
+	SecurityCookie                           uint64
+	SEHandlerTable                           uint64
+	SEHandlerCount                           uint64
+	GuardCFCheckFunctionPointer              uint64
+	GuardCFDispatchFunctionPointer           uint64
+	GuardCFFunctionTable                     uint64
+	GuardCFFunctionCount                     uint64
+	GuardFlags                               uint32
+	CodeIntegrityFlags                       uint16
+	CodeIntegrityCatalog                     uint16
+	CodeIntegrityCatalogOffset               uint32
+	CodeIntegrityReserved                    uint32
+	GuardAddressTakenIatEntryTable           uint64
+	GuardAddressTakenIatEntryCount           uint64
+	GuardLongJumpTargetTable                 uint64
+	GuardLongJumpTargetCount                 uint64
+	DynamicValueRelocTable                   uint64
+	CHPEMetadataPointer                      uint64
+	GuardRFFailureRoutine                    uint64
+	GuardRFFailureRoutineFunctionPointer     uint64
+	DynamicValueRelocTableOffset             uint32
+	DynamicValueRelocTableSection            uint16
+	Reserved2                                uint16
+	GuardRFVerifyStackPointerFunctionPointer uint64
+	HotPatchTableOffset                      uint32
+	Reserved3                                uint32
+	EnclaveConfigurationPointer              uint64
+	VolatileMetadataPointer                  uint64
+	GuardEHContinuationTable                 uint64
+	GuardEHContinuationCount                 uint64
+	GuardXFGCheckFunctionPointer             uint64
+	GuardXFGDispatchFunctionPointer          uint64
+	GuardXFGTableDispatchFunctionPointer     uint64
+	CastGuardOsDeterminedFailureMode         uint64
+	GuardMemcpyFunctionPointer               uint64
+	UmaFunctionPointers                      uint64
+}
+
+const (
+	IMAGE_GUARD_CF_FUNCTION_TABLE_SIZE_MASK  = 0xF0000000
+	IMAGE_GUARD_CF_FUNCTION_TABLE_SIZE_SHIFT = 28
+)
+
+var guardFlagNames = []struct {
+	Flag uint32
+	Name string
+}{
+	{0x00000100, "CF_INSTRUMENTED"},
+	{0x00000200, "CFW_INSTRUMENTED"},
+	{0x00000400, "CF_FUNCTION_TABLE_PRESENT"},
+	{0x00000800, "SECURITY_COOKIE_UNUSED"},
+	{0x00001000, "PROTECT_DELAYLOAD_IAT"},
+	{0x00002000, "DELAYLOAD_IAT_IN_ITS_OWN_SECTION"},
+	{0x00004000, "CF_EXPORT_SUPPRESSION_INFO_PRESENT"},
+	{0x00008000, "CF_ENABLE_EXPORT_SUPPRESSION"},
+	{0x00010000, "CF_LONGJUMP_TABLE_PRESENT"},
+	{0x00020000, "RF_INSTRUMENTED"},
+	{0x00040000, "RF_ENABLE"},
+	{0x00080000, "RF_STRICT"},
+	{0x00100000, "RETPOLINE_PRESENT"},
+	{0x00400000, "EH_CONTINUATION_TABLE_PRESENT"},
+	{0x00800000, "XFG_ENABLED"},
+	{0x01000000, "CASTGUARD_PRESENT"},
+	{0x02000000, "MEMCPY_PRESENT"},
+}
+
+// GuardFlagNames decodes the IMAGE_GUARD_* bits of GuardFlags. The function
+// table stride in the top four bits is left out; unknown bits are listed in
+// hex.
+func GuardFlagNames(flags uint32) []string {
+	var names []string
+	flags &^= IMAGE_GUARD_CF_FUNCTION_TABLE_SIZE_MASK
+	for _, f := range guardFlagNames {
+		if flags&f.Flag != 0 {
+			names = append(names, f.Name)
+			flags &^= f.Flag
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("0x%X", flags))
+	}
+	return names
+}
+
+var guardTableFlagNames = []struct {
+	Flag uint8
+	Name string
+}{
+	{0x01, "FID_SUPPRESSED"},
+	{0x02, "EXPORT_SUPPRESSED"},
+	{0x04, "FID_LANGEXCPTHANDLER"},
+	{0x08, "FID_XFG"},
+}
+
+// GuardTableEntry is one function of a CFG, SafeSEH, longjmp, EH
+// continuation or address-taken IAT table.
+type GuardTableEntry struct {
+	Offset  uint32 // file offset of the entry
+	RVA     uint32
+	Flags   uint8 // IMAGE_GUARD_FLAG_* metadata byte, when the table has one
+	Section string
+}
+
+// FlagNames decodes the metadata byte of a guard table entry.
+func (e GuardTableEntry) FlagNames() []string {
+	var names []string
+	flags := e.Flags
+	for _, f := range guardTableFlagNames {
+		if flags&f.Flag != 0 {
+			names = append(names, f.Name)
+			flags &^= f.Flag
+		}
+	}
+	if flags != 0 {
+		names = append(names, fmt.Sprintf("0x%X", flags))
+	}
+	return names
+}
+
+// GuardTable is one of the RVA tables the load config points at, named
+// after its load config field.
+type GuardTable struct {
+	Name    string
+	Offset  uint32 // file offset of the table
+	Stride  uint32 // bytes per entry
+	Entries []GuardTableEntry
+	Err     error // set when the table could not be read completely
+}
+
+// LoadConfig is the decoded load config directory. Header is either an
+// *IMAGE_LOAD_CONFIG_DIRECTORY32 or an *IMAGE_LOAD_CONFIG_DIRECTORY64,
+// filled up to Size bytes.
+type LoadConfig struct {
+	Offset uint32
+	Size   uint32 // bytes of Header present in the image
+	Header any
+	Tables []GuardTable
+}
+
+// Field returns a header field by name, widened to 64 bits.
+func (l *LoadConfig) Field(name string) uint64 {
+	field := reflect.ValueOf(l.Header).Elem().FieldByName(name)
+	if !field.IsValid() {
+		return 0
+	}
+	return field.Uint()
+}
+
+// LoadConfig decodes the load config directory and the guard tables it
+// points at.
+func (p *PeFull) LoadConfig() (*LoadConfig, error) {
+	configDir, ok := p.Directory("Load Config Table")
+	if !ok || !configDir.Present() {
+		return nil, fmt.Errorf("no load config directory")
+	}
+
+	offset, err := RvaToOffset(p.PeFile, configDir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(offset)+4 > uint64(len(p.FileData)) {
+		return nil, fmt.Errorf("load config directory at 0x%X out of bounds", offset)
+	}
+
+	config := &LoadConfig{Offset: offset}
+	if p.Is64Bit() {
+		config.Header = &IMAGE_LOAD_CONFIG_DIRECTORY64{}
+	} else {
+		config.Header = &IMAGE_LOAD_CONFIG_DIRECTORY32{}
+	}
+
+	// The structure grows with every release; Size says how much of it the
+	// linker wrote
+	fullSize := binary.Size(config.Header)
+	config.Size = uint32(min(int(binary.LittleEndian.Uint32(p.FileData[offset:])), fullSize, len(p.FileData)-int(offset)))
+	raw := make([]byte, fullSize)
+	copy(raw, p.FileData[offset:offset+config.Size])
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, config.Header); err != nil {
+		return nil, fmt.Errorf("failed to read load config directory: %v", err)
+	}
+
+	// The CFG style tables have GuardFlags-defined extra bytes per entry
+	stride := 4 + (uint32(config.Field("GuardFlags"))&IMAGE_GUARD_CF_FUNCTION_TABLE_SIZE_MASK)>>IMAGE_GUARD_CF_FUNCTION_TABLE_SIZE_SHIFT
+	tables := []struct {
+		name   string
+		count  string
+		stride uint32
+	}{
+		{"GuardCFFunctionTable", "GuardCFFunctionCount", stride},
+		{"SEHandlerTable", "SEHandlerCount", 4},
+		{"GuardLongJumpTargetTable", "GuardLongJumpTargetCount", stride},
+		{"GuardEHContinuationTable", "GuardEHContinuationCount", stride},
+		{"GuardAddressTakenIatEntryTable", "GuardAddressTakenIatEntryCount", stride},
+	}
+	for _, t := range tables {
+		va, count := config.Field(t.name), config.Field(t.count)
+		if va == 0 || count == 0 {
+			continue
+		}
+		config.Tables = append(config.Tables, p.readGuardTable(t.name, va, count, t.stride))
+	}
+
+	return config, nil
+}
+
+// readGuardTable reads count entries of stride bytes at the given VA. Each
+// entry starts with an RVA, optionally followed by a metadata byte.
+func (p *PeFull) readGuardTable(name string, va uint64, count uint64, stride uint32) GuardTable {
+	table := GuardTable{Name: name, Stride: stride}
+	imageBase := p.ImageBase()
+	if va < imageBase {
+		table.Err = fmt.Errorf("%s VA 0x%X is below ImageBase", name, va)
+		return table
+	}
+	offset, ok := p.FileBackedOffset(uint32(va-imageBase), 0)
+	if !ok {
+		table.Err = fmt.Errorf("%s VA 0x%X is not in the file", name, va)
+		return table
+	}
+	table.Offset = offset
+
+	for i := uint64(0); i < count; i++ {
+		entryOffset := uint64(offset) + i*uint64(stride)
+		if entryOffset+uint64(stride) > uint64(len(p.FileData)) {
+			table.Err = fmt.Errorf("%s entry %d out of bounds", name, i)
+			break
+		}
+		entry := GuardTableEntry{
+			Offset: uint32(entryOffset),
+			RVA:    binary.LittleEndian.Uint32(p.FileData[entryOffset:]),
+		}
+		if stride > 4 {
+			entry.Flags = p.FileData[entryOffset+4]
+		}
+		if section := p.SectionForRVA(entry.RVA); section != nil {
+			entry.Section = section.Name
+		}
+		table.Entries = append(table.Entries, entry)
+	}
+	return table
+}
+
+// GuardTable returns the table loaded from the named load config field.
+func (l *LoadConfig) GuardTable(name string) *GuardTable {
+	for i := range l.Tables {
+		if l.Tables[i].Name == name {
+			return &l.Tables[i]
+		}
+	}
+	return nil
+}
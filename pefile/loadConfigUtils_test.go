@@ -0,0 +1,178 @@
+package pefile
+
+import (
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// loadConfig64TestImage has a .text section and a .rdata section holding a
+// full 64-bit load config, a CFG function table with a metadata byte per
+// entry and a longjmp table that runs past the end of the file.
+func loadConfig64TestImage() testImage {
+	size := uint32(binary.Size(IMAGE_LOAD_CONFIG_DIRECTORY64{}))
+	rdata := newTestData(testSectionRVA(1))
+	dir := rdata.put(make([]byte, size))
+	cfg := rdata.put([]byte{
+		0x00, 0x10, 0, 0, 0x01,
+		0x10, 0x10, 0, 0, 0x08,
+		0x00, 0x30, 0, 0, 0x10,
+	})
+	longjmp := rdata.putStruct([]uint32{0x1020})
+
+	section := rdata.bytes()
+	header := newTestData(dir)
+	header.putStruct(IMAGE_LOAD_CONFIG_DIRECTORY64{
+		Size:                     size,
+		SecurityCookie:           testImageBase + 0x2F00,
+		GuardCFFunctionTable:     testImageBase + uint64(cfg),
+		GuardCFFunctionCount:     3,
+		GuardFlags:               0x00010500 | 1<<IMAGE_GUARD_CF_FUNCTION_TABLE_SIZE_SHIFT,
+		GuardLongJumpTargetTable: testImageBase + uint64(longjmp),
+		GuardLongJumpTargetCount: 0x1000,
+		GuardEHContinuationTable: 0x1000,
+		GuardEHContinuationCount: 1,
+	})
+	copy(section[dir-testSectionRVA(1):], header.bytes())
+
+	return testImage{
+		sections: []testSection{
+			{name: ".text", data: make([]byte, 0x20), characteristics: 0x60000020},
+			{name: ".rdata", data: section, characteristics: 0x40000040},
+		},
+		dirs: map[int]DataDirectory{10: {VirtualAddress: dir, Size: size}},
+	}
+}
+
+func TestLoadConfig64(t *testing.T) {
+	config, err := loadConfig64TestImage().parse(t).LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if _, ok := config.Header.(*IMAGE_LOAD_CONFIG_DIRECTORY64); !ok || config.Size != uint32(binary.Size(config.Header)) {
+		t.Fatalf("header %T of 0x%X bytes", config.Header, config.Size)
+	}
+	if cookie := config.Field("SecurityCookie"); cookie != testImageBase+0x2F00 {
+		t.Errorf("SecurityCookie = 0x%X", cookie)
+	}
+	if config.Field("NoSuchField") != 0 {
+		t.Error("Field of a missing field is not 0")
+	}
+	if want := []string{"CF_INSTRUMENTED", "CF_FUNCTION_TABLE_PRESENT", "CF_LONGJUMP_TABLE_PRESENT"}; !slices.Equal(GuardFlagNames(uint32(config.Field("GuardFlags"))), want) {
+		t.Errorf("GuardFlagNames = %q, want %q", GuardFlagNames(uint32(config.Field("GuardFlags"))), want)
+	}
+
+	if len(config.Tables) != 3 {
+		t.Fatalf("got %d tables, want 3", len(config.Tables))
+	}
+	cfg := config.GuardTable("GuardCFFunctionTable")
+	if cfg == nil || cfg.Err != nil || len(cfg.Entries) != 3 {
+		t.Fatalf("CFG table = %+v", cfg)
+	}
+	wantCFG := []struct {
+		rva     uint32
+		flags   string
+		section string
+	}{
+		{0x1000, "FID_SUPPRESSED", ".text"},
+		{0x1010, "FID_XFG", ".text"},
+		{0x3000, "0x10", ""},
+	}
+	for i, want := range wantCFG {
+		got := cfg.Entries[i]
+		if got.RVA != want.rva || strings.Join(got.FlagNames(), "|") != want.flags || got.Section != want.section ||
+			got.Offset != cfg.Offset+uint32(i)*5 {
+			t.Errorf("CFG entry %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	// The longjmp table is cut off by the end of the file
+	longjmp := config.GuardTable("GuardLongJumpTargetTable")
+	if longjmp == nil || longjmp.Err == nil || len(longjmp.Entries) == 0 || longjmp.Entries[0].RVA != 0x1020 {
+		t.Errorf("longjmp table = %+v", longjmp)
+	}
+	if eh := config.GuardTable("GuardEHContinuationTable"); eh == nil || eh.Err == nil || len(eh.Entries) != 0 {
+		t.Errorf("EH continuation table below ImageBase = %+v", eh)
+	}
+	if config.GuardTable("SEHandlerTable") != nil {
+		t.Error("empty SEHandlerTable was loaded")
+	}
+}
+
+// Fields past Size are left zero even if the bytes after the directory are
+// not.
+func TestLoadConfig32(t *testing.T) {
+	rdata := newTestData(testSectionRVA(0))
+	dir := rdata.put(make([]byte, 0x48))
+	handlers := rdata.putStruct([]uint32{0x1100, 0x1200})
+	rdata.put([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+
+	section := rdata.bytes()
+	header := newTestData(dir)
+	// Size 0x48 is the Windows XP layout, up to and including SEHandlerCount
+	header.putStruct(IMAGE_LOAD_CONFIG_DIRECTORY32{
+		Size:           0x48,
+		SEHandlerTable: 0x400000 + handlers,
+		SEHandlerCount: 2,
+	})
+	copy(section, header.bytes()[:0x48])
+
+	p := testImage{
+		pe32:     true,
+		sections: []testSection{{name: ".rdata", data: section, characteristics: 0x40000040}},
+		dirs:     map[int]DataDirectory{10: {VirtualAddress: dir, Size: 0x40}},
+	}.parse(t)
+
+	config, err := p.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if _, ok := config.Header.(*IMAGE_LOAD_CONFIG_DIRECTORY32); !ok || config.Size != 0x48 {
+		t.Fatalf("header %T of 0x%X bytes", config.Header, config.Size)
+	}
+	if config.Field("GuardCFCheckFunctionPointer") != 0 {
+		t.Errorf("field past Size read as 0x%X", config.Field("GuardCFCheckFunctionPointer"))
+	}
+	handlerTable := config.GuardTable("SEHandlerTable")
+	if handlerTable == nil || handlerTable.Err != nil || len(handlerTable.Entries) != 2 ||
+		handlerTable.Entries[1].RVA != 0x1200 || handlerTable.Entries[1].Flags != 0 {
+		t.Errorf("SEHandlerTable = %+v", handlerTable)
+	}
+}
+
+func TestLoadConfigCorrupt(t *testing.T) {
+	missing := loadConfig64TestImage()
+	delete(missing.dirs, 10)
+
+	unmapped := loadConfig64TestImage()
+	unmapped.dirs[10] = DataDirectory{VirtualAddress: unmappedRVA, Size: 0x140}
+
+	// A Size larger than the structure or the file is capped
+	image := loadConfig64TestImage()
+	oversized := image.bytes()
+	dir := testFileAlignment + alignUp(0x20, testFileAlignment)
+	oversized[dir+2] = 0x10
+	oversized = oversized[:dir+0x100]
+
+	p, err := ParseBytes(oversized)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	if config, err := p.LoadConfig(); err != nil || config.Size != 0x100 {
+		t.Errorf("truncated LoadConfig = %+v, %v", config, err)
+	}
+
+	for name, image := range map[string]testImage{"no directory": missing, "unmapped directory": unmapped} {
+		if _, err := image.parse(t).LoadConfig(); err == nil {
+			t.Errorf("%s: LoadConfig succeeded", name)
+		}
+	}
+}
+
+func TestGuardFlagNames(t *testing.T) {
+	got := GuardFlagNames(0x30000100 | 0x00400000 | 0x00200000)
+	if want := []string{"CF_INSTRUMENTED", "EH_CONTINUATION_TABLE_PRESENT", "0x200000"}; !slices.Equal(got, want) {
+		t.Errorf("GuardFlagNames = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package pefile
+
+import (
+	"debug/pe"
+	"fmt"
+	"slices"
+)
+
+const (
+	IMAGE_NT_OPTIONAL_HDR32_MAGIC = 0x10b
+	IMAGE_NT_OPTIONAL_HDR64_MAGIC = 0x20b
+	IMAGE_ROM_OPTIONAL_HDR_MAGIC  = 0x107
+)
+
+// IMAGE_FILE_MACHINE_* values that debug/pe does not define.
+const (
+	IMAGE_FILE_MACHINE_TARGET_HOST = 0x0001
+	IMAGE_FILE_MACHINE_R3000       = 0x0162
+	IMAGE_FILE_MACHINE_R10000      = 0x0168
+	IMAGE_FILE_MACHINE_ALPHA       = 0x0184
+	IMAGE_FILE_MACHINE_SH3E        = 0x01A4
+	IMAGE_FILE_MACHINE_POWERPCBE   = 0x01F2
+	IMAGE_FILE_MACHINE_ALPHA64     = 0x0284
+	IMAGE_FILE_MACHINE_TRICORE     = 0x0520
+	IMAGE_FILE_MACHINE_CEF         = 0x0CEF
+	IMAGE_FILE_MACHINE_CHPE_X86    = 0x3A64
+	IMAGE_FILE_MACHINE_ARM64EC     = 0xA641
+	IMAGE_FILE_MACHINE_ARM64X      = 0xA64E
+	IMAGE_FILE_MACHINE_CEE         = 0xC0EE
+)
+
+// IMAGE_FILE_MACHINE_ARM64 is re-exported so that callers don't need
+// debug/pe to recognise ARM64 images.
+const IMAGE_FILE_MACHINE_ARM64 = pe.IMAGE_FILE_MACHINE_ARM64
+
+var machineNames = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_UNKNOWN:     "UNKNOWN",
+	IMAGE_FILE_MACHINE_TARGET_HOST:    "TARGET_HOST",
+	pe.IMAGE_FILE_MACHINE_I386:        "I386",
+	IMAGE_FILE_MACHINE_R3000:          "R3000",
+	pe.IMAGE_FILE_MACHINE_R4000:       "R4000",
+	IMAGE_FILE_MACHINE_R10000:         "R10000",
+	pe.IMAGE_FILE_MACHINE_WCEMIPSV2:   "WCEMIPSV2",
+	IMAGE_FILE_MACHINE_ALPHA:          "ALPHA",
+	pe.IMAGE_FILE_MACHINE_SH3:         "SH3",
+	pe.IMAGE_FILE_MACHINE_SH3DSP:      "SH3DSP",
+	IMAGE_FILE_MACHINE_SH3E:           "SH3E",
+	pe.IMAGE_FILE_MACHINE_SH4:         "SH4",
+	pe.IMAGE_FILE_MACHINE_SH5:         "SH5",
+	pe.IMAGE_FILE_MACHINE_ARM:         "ARM",
+	pe.IMAGE_FILE_MACHINE_THUMB:       "THUMB",
+	pe.IMAGE_FILE_MACHINE_ARMNT:       "ARMNT",
+	pe.IMAGE_FILE_MACHINE_AM33:        "AM33",
+	pe.IMAGE_FILE_MACHINE_POWERPC:     "POWERPC",
+	pe.IMAGE_FILE_MACHINE_POWERPCFP:   "POWERPCFP",
+	IMAGE_FILE_MACHINE_POWERPCBE:      "POWERPCBE",
+	pe.IMAGE_FILE_MACHINE_IA64:        "IA64",
+	pe.IMAGE_FILE_MACHINE_MIPS16:      "MIPS16",
+	IMAGE_FILE_MACHINE_ALPHA64:        "ALPHA64",
+	pe.IMAGE_FILE_MACHINE_MIPSFPU:     "MIPSFPU",
+	pe.IMAGE_FILE_MACHINE_MIPSFPU16:   "MIPSFPU16",
+	IMAGE_FILE_MACHINE_TRICORE:        "TRICORE",
+	IMAGE_FILE_MACHINE_CEF:            "CEF",
+	pe.IMAGE_FILE_MACHINE_EBC:         "EBC",
+	IMAGE_FILE_MACHINE_CHPE_X86:       "CHPE_X86",
+	pe.IMAGE_FILE_MACHINE_RISCV32:     "RISCV32",
+	pe.IMAGE_FILE_MACHINE_RISCV64:     "RISCV64",
+	pe.IMAGE_FILE_MACHINE_RISCV128:    "RISCV128",
+	pe.IMAGE_FILE_MACHINE_LOONGARCH32: "LOONGARCH32",
+	pe.IMAGE_FILE_MACHINE_LOONGARCH64: "LOONGARCH64",
+	pe.IMAGE_FILE_MACHINE_AMD64:       "AMD64",
+	pe.IMAGE_FILE_MACHINE_M32R:        "M32R",
+	IMAGE_FILE_MACHINE_ARM64EC:        "ARM64EC",
+	IMAGE_FILE_MACHINE_ARM64X:         "ARM64X",
+	pe.IMAGE_FILE_MACHINE_ARM64:       "ARM64",
+	IMAGE_FILE_MACHINE_CEE:            "CEE",
+}
+
+// ReadyToRun images built by .NET for other operating systems XOR the
+// machine type with a per-OS value.
+var readyToRunOSNames = []struct {
+	Value uint16
+	Name  string
+}{
+	{0x4644, "Apple"},
+	{0xADC4, "FreeBSD"},
+	{0x7B79, "Linux"},
+	{0x1993, "NetBSD"},
+	{0x1992, "SunOS"},
+}
+
+// readyToRunMachines are the machine types .NET emits ReadyToRun code for.
+// Limited to these, every OS value above decodes a different set of
+// machine values, so a match is never ambiguous.
+var readyToRunMachines = []uint16{
+	pe.IMAGE_FILE_MACHINE_I386,
+	pe.IMAGE_FILE_MACHINE_AMD64,
+	pe.IMAGE_FILE_MACHINE_ARMNT,
+	pe.IMAGE_FILE_MACHINE_ARM64,
+}
+
+// MachineName decodes an IMAGE_FILE_MACHINE_* value, e.g. "ARM64" or
+// "AMD64 (Linux ReadyToRun)". Unknown values are returned in hex.
+func MachineName(machine uint16) string {
+	if name, ok := machineNames[machine]; ok {
+		return name
+	}
+	for _, os := range readyToRunOSNames {
+		if slices.Contains(readyToRunMachines, machine^os.Value) {
+			return fmt.Sprintf("%s (%s ReadyToRun)", machineNames[machine^os.Value], os.Name)
+		}
+	}
+	return fmt.Sprintf("0x%04X", machine)
+}
+
+// OptionalHeaderMagicName decodes the Magic of the optional header.
+func OptionalHeaderMagicName(magic uint16) string {
+	switch magic {
+	case IMAGE_NT_OPTIONAL_HDR32_MAGIC:
+		return "PE32"
+	case IMAGE_NT_OPTIONAL_HDR64_MAGIC:
+		return "PE32+"
+	case IMAGE_ROM_OPTIONAL_HDR_MAGIC:
+		return "ROM"
+	}
+	return fmt.Sprintf("0x%X", magic)
+}
+
+// IMAGE_SUBSYSTEM_* value that debug/pe does not define.
+const IMAGE_SUBSYSTEM_XBOX_CODE_CATALOG = 17
+
+var subsystemNames = map[uint16]string{
+	pe.IMAGE_SUBSYSTEM_UNKNOWN:                  "UNKNOWN",
+	pe.IMAGE_SUBSYSTEM_NATIVE:                   "NATIVE",
+	pe.IMAGE_SUBSYSTEM_WINDOWS_GUI:              "WINDOWS_GUI",
+	pe.IMAGE_SUBSYSTEM_WINDOWS_CUI:              "WINDOWS_CUI",
+	pe.IMAGE_SUBSYSTEM_OS2_CUI:                  "OS2_CUI",
+	pe.IMAGE_SUBSYSTEM_POSIX_CUI:                "POSIX_CUI",
+	pe.IMAGE_SUBSYSTEM_NATIVE_WINDOWS:           "NATIVE_WINDOWS",
+	pe.IMAGE_SUBSYSTEM_WINDOWS_CE_GUI:           "WINDOWS_CE_GUI",
+	pe.IMAGE_SUBSYSTEM_EFI_APPLICATION:          "EFI_APPLICATION",
+	pe.IMAGE_SUBSYSTEM_EFI_BOOT_SERVICE_DRIVER:  "EFI_BOOT_SERVICE_DRIVER",
+	pe.IMAGE_SUBSYSTEM_EFI_RUNTIME_DRIVER:       "EFI_RUNTIME_DRIVER",
+	pe.IMAGE_SUBSYSTEM_EFI_ROM:                  "EFI_ROM",
+	pe.IMAGE_SUBSYSTEM_XBOX:                     "XBOX",
+	pe.IMAGE_SUBSYSTEM_WINDOWS_BOOT_APPLICATION: "WINDOWS_BOOT_APPLICATION",
+	IMAGE_SUBSYSTEM_XBOX_CODE_CATALOG:           "XBOX_CODE_CATALOG",
+}
+
+// SubsystemName decodes an IMAGE_SUBSYSTEM_* value. Unknown values are
+// returned in decimal.
+func SubsystemName(subsystem uint16) string {
+	if name, ok := subsystemNames[subsystem]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", subsystem)
+}
@@ -0,0 +1,49 @@
+package pefile
+
+import (
+	"debug/pe"
+	"testing"
+)
+
+func TestMachineName(t *testing.T) {
+	tests := []struct {
+		machine uint16
+		want    string
+	}{
+		{pe.IMAGE_FILE_MACHINE_AMD64, "AMD64"},
+		{IMAGE_FILE_MACHINE_ARM64EC, "ARM64EC"},
+		{pe.IMAGE_FILE_MACHINE_AMD64 ^ 0x7B79, "AMD64 (Linux ReadyToRun)"},
+		{pe.IMAGE_FILE_MACHINE_ARM64 ^ 0x4644, "ARM64 (Apple ReadyToRun)"},
+		{pe.IMAGE_FILE_MACHINE_I386 ^ 0xADC4, "I386 (FreeBSD ReadyToRun)"},
+		{pe.IMAGE_FILE_MACHINE_ARMNT ^ 0x1992, "ARMNT (SunOS ReadyToRun)"},
+		// SH3DSP ^ NetBSD and SH3 ^ SunOS: not a ReadyToRun machine
+		{0x1830, "0x1830"},
+		{0xFFFF, "0xFFFF"},
+	}
+	for _, tt := range tests {
+		if got := MachineName(tt.machine); got != tt.want {
+			t.Errorf("MachineName(0x%04X) = %q, want %q", tt.machine, got, tt.want)
+		}
+	}
+}
+
+// Every value must decode to at most one ReadyToRun OS, otherwise the
+// result would depend on the order the OS values are tried in.
+func TestMachineNameUnambiguous(t *testing.T) {
+	for machine := 0; machine <= 0xFFFF; machine++ {
+		if _, ok := machineNames[uint16(machine)]; ok {
+			continue
+		}
+		matches := 0
+		for _, os := range readyToRunOSNames {
+			for _, target := range readyToRunMachines {
+				if uint16(machine)^os.Value == target {
+					matches++
+				}
+			}
+		}
+		if matches > 1 {
+			t.Errorf("0x%04X decodes as %d ReadyToRun machines", machine, matches)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// MetadataTablesHeader is the header of the #~ stream, ahead of the row
+// counts of the tables present.
+type MetadataTablesHeader struct {
+	Reserved     uint32
+	MajorVersion uint8
+	MinorVersion uint8
+	HeapSizes    uint8
+	Reserved2    uint8
+	Valid        uint64
+	Sorted       uint64
+}
+
+// HeapSizes bits widening heap indexes to four bytes, and the flag that
+// adds four bytes of extra data after the row counts.
+const (
+	heapSizesStrings   = 0x01
+	heapSizesGuid      = 0x02
+	heapSizesBlob      = 0x04
+	heapSizesExtraData = 0x40
+)
+
+// Metadata table numbers, as used in the top byte of tokens.
+const (
+	MetadataModule                 = 0x00
+	MetadataTypeRef                = 0x01
+	MetadataTypeDef                = 0x02
+	MetadataField                  = 0x04
+	MetadataMethodDef              = 0x06
+	MetadataParam                  = 0x08
+	MetadataInterfaceImpl          = 0x09
+	MetadataMemberRef              = 0x0A
+	MetadataDeclSecurity           = 0x0E
+	MetadataStandAloneSig          = 0x11
+	MetadataEvent                  = 0x14
+	MetadataProperty               = 0x17
+	MetadataModuleRef              = 0x1A
+	MetadataTypeSpec               = 0x1B
+	MetadataAssembly               = 0x20
+	MetadataAssemblyRef            = 0x23
+	MetadataFile                   = 0x26
+	MetadataExportedType           = 0x27
+	MetadataManifestResource       = 0x28
+	MetadataGenericParam           = 0x2A
+	MetadataMethodSpec             = 0x2B
+	MetadataGenericParamConstraint = 0x2C
+	metadataTableCount             = 0x2D
+)
+
+type metadataColumnKind int
+
+const (
+	columnUint16 metadataColumnKind = iota
+	columnUint32
+	columnString
+	columnGuid
+	columnBlob
+	columnTable // index into the table in ref
+	columnCoded // coded index of the kind in ref
+)
+
+type metadataColumnSpec struct {
+	name string
+	kind metadataColumnKind
+	ref  int
+}
+
+// Coded index kinds, indexing codedIndexes.
+const (
+	codedTypeDefOrRef = iota
+	codedHasConstant
+	codedHasCustomAttribute
+	codedHasFieldMarshal
+	codedHasDeclSecurity
+	codedMemberRefParent
+	codedHasSemantics
+	codedMethodDefOrRef
+	codedMemberForwarded
+	codedImplementation
+	codedCustomAttributeType
+	codedResolutionScope
+	codedTypeOrMethodDef
+)
+
+// codedIndexes lists the tables each coded index kind can refer to, by tag.
+// -1 marks tags that are not used.
+var codedIndexes = [][]int{
+	codedTypeDefOrRef:        {MetadataTypeDef, MetadataTypeRef, MetadataTypeSpec},
+	codedHasConstant:         {MetadataField, MetadataParam, MetadataProperty},
+	codedHasCustomAttribute:  {MetadataMethodDef, MetadataField, MetadataTypeRef, MetadataTypeDef, MetadataParam, MetadataInterfaceImpl, MetadataMemberRef, MetadataModule, MetadataDeclSecurity, MetadataProperty, MetadataEvent, MetadataStandAloneSig, MetadataModuleRef, MetadataTypeSpec, MetadataAssembly, MetadataAssemblyRef, MetadataFile, MetadataExportedType, MetadataManifestResource, MetadataGenericParam, MetadataGenericParamConstraint, MetadataMethodSpec},
+	codedHasFieldMarshal:     {MetadataField, MetadataParam},
+	codedHasDeclSecurity:     {MetadataTypeDef, MetadataMethodDef, MetadataAssembly},
+	codedMemberRefParent:     {MetadataTypeDef, MetadataTypeRef, MetadataModuleRef, MetadataMethodDef, MetadataTypeSpec},
+	codedHasSemantics:        {MetadataEvent, MetadataProperty},
+	codedMethodDefOrRef:      {MetadataMethodDef, MetadataMemberRef},
+	codedMemberForwarded:     {MetadataField, MetadataMethodDef},
+	codedImplementation:      {MetadataFile, MetadataAssemblyRef, MetadataExportedType},
+	codedCustomAttributeType: {-1, -1, MetadataMethodDef, MetadataMemberRef, -1},
+	codedResolutionScope:     {MetadataModule, MetadataModuleRef, MetadataAssemblyRef, MetadataTypeRef},
+	codedTypeOrMethodDef:     {MetadataTypeDef, MetadataMethodDef},
+}
+
+// codedIndexTagBits is how many low bits of a coded index hold the tag.
+func codedIndexTagBits(kind int) uint {
+	return uint(bits.Len(uint(len(codedIndexes[kind]) - 1)))
+}
+
+var metadataSchemas = [metadataTableCount]struct {
+	name    string
+	columns []metadataColumnSpec
+}{
+	0x00: {"Module", []metadataColumnSpec{{"Generation", columnUint16, 0}, {"Name", columnString, 0}, {"Mvid", columnGuid, 0}, {"EncId", columnGuid, 0}, {"EncBaseId", columnGuid, 0}}},
+	0x01: {"TypeRef", []metadataColumnSpec{{"ResolutionScope", columnCoded, codedResolutionScope}, {"TypeName", columnString, 0}, {"TypeNamespace", columnString, 0}}},
+	0x02: {"TypeDef", []metadataColumnSpec{{"Flags", columnUint32, 0}, {"TypeName", columnString, 0}, {"TypeNamespace", columnString, 0}, {"Extends", columnCoded, codedTypeDefOrRef}, {"FieldList", columnTable, MetadataField}, {"MethodList", columnTable, MetadataMethodDef}}},
+	0x03: {"FieldPtr", []metadataColumnSpec{{"Field", columnTable, MetadataField}}},
+	0x04: {"Field", []metadataColumnSpec{{"Flags", columnUint16, 0}, {"Name", columnString, 0}, {"Signature", columnBlob, 0}}},
+	0x05: {"MethodPtr", []metadataColumnSpec{{"Method", columnTable, MetadataMethodDef}}},
+	0x06: {"MethodDef", []metadataColumnSpec{{"RVA", columnUint32, 0}, {"ImplFlags", columnUint16, 0}, {"Flags", columnUint16, 0}, {"Name", columnString, 0}, {"Signature", columnBlob, 0}, {"ParamList", columnTable, MetadataParam}}},
+	0x07: {"ParamPtr", []metadataColumnSpec{{"Param", columnTable, MetadataParam}}},
+	0x08: {"Param", []metadataColumnSpec{{"Flags", columnUint16, 0}, {"Sequence", columnUint16, 0}, {"Name", columnString, 0}}},
+	0x09: {"InterfaceImpl", []metadataColumnSpec{{"Class", columnTable, MetadataTypeDef}, {"Interface", columnCoded, codedTypeDefOrRef}}},
+	0x0A: {"MemberRef", []metadataColumnSpec{{"Class", columnCoded, codedMemberRefParent}, {"Name", columnString, 0}, {"Signature", columnBlob, 0}}},
+	0x0B: {"Constant", []metadataColumnSpec{{"Type", columnUint16, 0}, {"Parent", columnCoded, codedHasConstant}, {"Value", columnBlob, 0}}},
+	0x0C: {"CustomAttribute", []metadataColumnSpec{{"Parent", columnCoded, codedHasCustomAttribute}, {"Type", columnCoded, codedCustomAttributeType}, {"Value", columnBlob, 0}}},
+	0x0D: {"FieldMarshal", []metadataColumnSpec{{"Parent", columnCoded, codedHasFieldMarshal}, {"NativeType", columnBlob, 0}}},
+	0x0E: {"DeclSecurity", []metadataColumnSpec{{"Action", columnUint16, 0}, {"Parent", columnCoded, codedHasDeclSecurity}, {"PermissionSet", columnBlob, 0}}},
+	0x0F: {"ClassLayout", []metadataColumnSpec{{"PackingSize", columnUint16, 0}, {"ClassSize", columnUint32, 0}, {"Parent", columnTable, MetadataTypeDef}}},
+	0x10: {"FieldLayout", []metadataColumnSpec{{"Offset", columnUint32, 0}, {"Field", columnTable, MetadataField}}},
+	0x11: {"StandAloneSig", []metadataColumnSpec{{"Signature", columnBlob, 0}}},
+	0x12: {"EventMap", []metadataColumnSpec{{"Parent", columnTable, MetadataTypeDef}, {"EventList", columnTable, MetadataEvent}}},
+	0x13: {"EventPtr", []metadataColumnSpec{{"Event", columnTable, MetadataEvent}}},
+	0x14: {"Event", []metadataColumnSpec{{"EventFlags", columnUint16, 0}, {"Name", columnString, 0}, {"EventType", columnCoded, codedTypeDefOrRef}}},
+	0x15: {"PropertyMap", []metadataColumnSpec{{"Parent", columnTable, MetadataTypeDef}, {"PropertyList", columnTable, MetadataProperty}}},
+	0x16: {"PropertyPtr", []metadataColumnSpec{{"Property", columnTable, MetadataProperty}}},
+	0x17: {"Property", []metadataColumnSpec{{"Flags", columnUint16, 0}, {"Name", columnString, 0}, {"Type", columnBlob, 0}}},
+	0x18: {"MethodSemantics", []metadataColumnSpec{{"Semantics", columnUint16, 0}, {"Method", columnTable, MetadataMethodDef}, {"Association", columnCoded, codedHasSemantics}}},
+	0x19: {"MethodImpl", []metadataColumnSpec{{"Class", columnTable, MetadataTypeDef}, {"MethodBody", columnCoded, codedMethodDefOrRef}, {"MethodDeclaration", columnCoded, codedMethodDefOrRef}}},
+	0x1A: {"ModuleRef", []metadataColumnSpec{{"Name", columnString, 0}}},
+	0x1B: {"TypeSpec", []metadataColumnSpec{{"Signature", columnBlob, 0}}},
+	0x1C: {"ImplMap", []metadataColumnSpec{{"MappingFlags", columnUint16, 0}, {"MemberForwarded", columnCoded, codedMemberForwarded}, {"ImportName", columnString, 0}, {"ImportScope", columnTable, MetadataModuleRef}}},
+	0x1D: {"FieldRVA", []metadataColumnSpec{{"RVA", columnUint32, 0}, {"Field", columnTable, MetadataField}}},
+	0x1E: {"EncLog", []metadataColumnSpec{{"Token", columnUint32, 0}, {"FuncCode", columnUint32, 0}}},
+	0x1F: {"EncMap", []metadataColumnSpec{{"Token", columnUint32, 0}}},
+	0x20: {"Assembly", []metadataColumnSpec{{"HashAlgId", columnUint32, 0}, {"MajorVersion", columnUint16, 0}, {"MinorVersion", columnUint16, 0}, {"BuildNumber", columnUint16, 0}, {"RevisionNumber", columnUint16, 0}, {"Flags", columnUint32, 0}, {"PublicKey", columnBlob, 0}, {"Name", columnString, 0}, {"Culture", columnString, 0}}},
+	0x21: {"AssemblyProcessor", []metadataColumnSpec{{"Processor", columnUint32, 0}}},
+	0x22: {"AssemblyOS", []metadataColumnSpec{{"OSPlatformID", columnUint32, 0}, {"OSMajorVersion", columnUint32, 0}, {"OSMinorVersion", columnUint32, 0}}},
+	0x23: {"AssemblyRef", []metadataColumnSpec{{"MajorVersion", columnUint16, 0}, {"MinorVersion", columnUint16, 0}, {"BuildNumber", columnUint16, 0}, {"RevisionNumber", columnUint16, 0}, {"Flags", columnUint32, 0}, {"PublicKeyOrToken", columnBlob, 0}, {"Name", columnString, 0}, {"Culture", columnString, 0}, {"HashValue", columnBlob, 0}}},
+	0x24: {"AssemblyRefProcessor", []metadataColumnSpec{{"Processor", columnUint32, 0}, {"AssemblyRef", columnTable, MetadataAssemblyRef}}},
+	0x25: {"AssemblyRefOS", []metadataColumnSpec{{"OSPlatformID", columnUint32, 0}, {"OSMajorVersion", columnUint32, 0}, {"OSMinorVersion", columnUint32, 0}, {"AssemblyRef", columnTable, MetadataAssemblyRef}}},
+	0x26: {"File", []metadataColumnSpec{{"Flags", columnUint32, 0}, {"Name", columnString, 0}, {"HashValue", columnBlob, 0}}},
+	0x27: {"ExportedType", []metadataColumnSpec{{"Flags", columnUint32, 0}, {"TypeDefId", columnUint32, 0}, {"TypeName", columnString, 0}, {"TypeNamespace", columnString, 0}, {"Implementation", columnCoded, codedImplementation}}},
+	0x28: {"ManifestResource", []metadataColumnSpec{{"Offset", columnUint32, 0}, {"Flags", columnUint32, 0}, {"Name", columnString, 0}, {"Implementation", columnCoded, codedImplementation}}},
+	0x29: {"NestedClass", []metadataColumnSpec{{"NestedClass", columnTable, MetadataTypeDef}, {"EnclosingClass", columnTable, MetadataTypeDef}}},
+	0x2A: {"GenericParam", []metadataColumnSpec{{"Number", columnUint16, 0}, {"Flags", columnUint16, 0}, {"Owner", columnCoded, codedTypeOrMethodDef}, {"Name", columnString, 0}}},
+	0x2B: {"MethodSpec", []metadataColumnSpec{{"Method", columnCoded, codedMethodDefOrRef}, {"Instantiation", columnBlob, 0}}},
+	0x2C: {"GenericParamConstraint", []metadataColumnSpec{{"Owner", columnTable, MetadataGenericParam}, {"Constraint", columnCoded, codedTypeDefOrRef}}},
+}
+
+// MetadataTableName returns the name of a metadata table number.
+func MetadataTableName(id int) string {
+	if id >= 0 && id < metadataTableCount {
+		return metadataSchemas[id].name
+	}
+	return fmt.Sprintf("Table 0x%X", id)
+}
+
+type MetadataColumn struct {
+	Name string
+	Size uint32
+	kind metadataColumnKind
+	ref  int
+}
+
+// Numeric reports whether the column holds a plain number rather than a
+// heap index or a reference to another table.
+func (c MetadataColumn) Numeric() bool {
+	return c.kind == columnUint16 || c.kind == columnUint32
+}
+
+type MetadataTable struct {
+	ID       int
+	Name     string
+	Offset   uint32 // file offset of the first row
+	RowCount uint32
+	RowSize  uint32
+	Columns  []MetadataColumn
+	Rows     [][]uint32
+}
+
+type MetadataTables struct {
+	Offset uint32 // file offset of the #~ stream
+	Header MetadataTablesHeader
+	Tables []MetadataTable // the tables present, by number
+}
+
+// Table returns the table with the given number, or nil when it is absent.
+func (t *MetadataTables) Table(id int) *MetadataTable {
+	for i := range t.Tables {
+		if t.Tables[i].ID == id {
+			return &t.Tables[i]
+		}
+	}
+	return nil
+}
+
+// parseMetadataTables decodes the #~ stream. Column widths depend on the
+// heap sizes and on the row counts of the tables they index, so every table
+// up to the last present one must be known to find where the rows are.
+func parseMetadataTables(stream MetadataStream) (*MetadataTables, error) {
+	tables := &MetadataTables{Offset: stream.FileOffset}
+	reader := bytes.NewReader(stream.Data)
+	if err := binary.Read(reader, binary.LittleEndian, &tables.Header); err != nil {
+		return nil, err
+	}
+
+	var rowCounts [64]uint32
+	for id := 0; id < 64; id++ {
+		if tables.Header.Valid&(1<<id) == 0 {
+			continue
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &rowCounts[id]); err != nil {
+			return nil, fmt.Errorf("row counts: %v", err)
+		}
+		if id >= metadataTableCount {
+			return nil, fmt.Errorf("unknown metadata table 0x%X", id)
+		}
+	}
+	if tables.Header.HeapSizes&heapSizesExtraData != 0 {
+		reader.Seek(4, 1)
+	}
+
+	heapIndexSize := func(flag uint8) uint32 {
+		if tables.Header.HeapSizes&flag != 0 {
+			return 4
+		}
+		return 2
+	}
+	columnSize := func(spec metadataColumnSpec) uint32 {
+		switch spec.kind {
+		case columnUint16:
+			return 2
+		case columnUint32:
+			return 4
+		case columnString:
+			return heapIndexSize(heapSizesStrings)
+		case columnGuid:
+			return heapIndexSize(heapSizesGuid)
+		case columnBlob:
+			return heapIndexSize(heapSizesBlob)
+		case columnTable:
+			if rowCounts[spec.ref] > 0xFFFF {
+				return 4
+			}
+			return 2
+		}
+		// A coded index widens once a target table outgrows the bits the
+		// tag leaves
+		limit := uint32(1) << (16 - codedIndexTagBits(spec.ref))
+		for _, id := range codedIndexes[spec.ref] {
+			if id >= 0 && rowCounts[id] >= limit {
+				return 4
+			}
+		}
+		return 2
+	}
+
+	pos := uint32(len(stream.Data) - reader.Len())
+	for id := 0; id < metadataTableCount; id++ {
+		if tables.Header.Valid&(1<<id) == 0 {
+			continue
+		}
+		table := MetadataTable{
+			ID:       id,
+			Name:     metadataSchemas[id].name,
+			Offset:   stream.FileOffset + pos,
+			RowCount: rowCounts[id],
+		}
+		for _, spec := range metadataSchemas[id].columns {
+			size := columnSize(spec)
+			table.Columns = append(table.Columns, MetadataColumn{spec.name, size, spec.kind, spec.ref})
+			table.RowSize += size
+		}
+		if uint64(pos)+uint64(table.RowSize)*uint64(table.RowCount) > uint64(len(stream.Data)) {
+			tables.Tables = append(tables.Tables, table)
+			return tables, fmt.Errorf("%s table out of bounds", table.Name)
+		}
+
+		for row := uint32(0); row < table.RowCount; row++ {
+			values := make([]uint32, len(table.Columns))
+			for i, column := range table.Columns {
+				if column.Size == 2 {
+					values[i] = uint32(binary.LittleEndian.Uint16(stream.Data[pos:]))
+				} else {
+					values[i] = binary.LittleEndian.Uint32(stream.Data[pos:])
+				}
+				pos += column.Size
+			}
+			table.Rows = append(table.Rows, values)
+		}
+		tables.Tables = append(tables.Tables, table)
+	}
+
+	return tables, nil
+}
+
+// Token returns the metadata token of a 1-based row of a table.
+func Token(table int, row uint32) uint32 {
+	return uint32(table)<<24 | row
+}
+
+// RowName returns the name of a 1-based row of a table: the namespace
+// qualified name of types, the Name column of other named rows, or "".
+func (m *Metadata) RowName(id int, row uint32) string {
+	if m.Tables == nil {
+		return ""
+	}
+	table := m.Tables.Table(id)
+	if table == nil || row == 0 || row > uint32(len(table.Rows)) {
+		return ""
+	}
+
+	var name, namespace string
+	for i, column := range table.Columns {
+		switch column.Name {
+		case "Name", "TypeName":
+			name = m.String(table.Rows[row-1][i])
+		case "TypeNamespace":
+			namespace = m.String(table.Rows[row-1][i])
+		}
+	}
+	if namespace != "" {
+		return namespace + "." + name
+	}
+	return name
+}
+
+// TokenName describes a token as its table, row and name.
+func (m *Metadata) TokenName(token uint32) string {
+	id, row := int(token>>24), token&0xFFFFFF
+	text := fmt.Sprintf("%s[%d]", MetadataTableName(id), row)
+	if name := m.RowName(id, row); name != "" {
+		text += " " + name
+	}
+	return text
+}
+
+// FormatValue resolves a column value against the heaps and the tables it
+// refers to.
+func (m *Metadata) FormatValue(column MetadataColumn, value uint32) string {
+	switch column.kind {
+	case columnString:
+		return m.String(value)
+	case columnGuid:
+		return m.Guid(value)
+	case columnBlob:
+		if value == 0 {
+			return ""
+		}
+		return formatBlob(m.Blob(value))
+	case columnTable:
+		return m.TokenName(Token(column.ref, value))
+	case columnCoded:
+		tagBits := codedIndexTagBits(column.ref)
+		tag, row := int(value&(1<<tagBits-1)), value>>tagBits
+		targets := codedIndexes[column.ref]
+		if row == 0 {
+			return "null"
+		}
+		if tag >= len(targets) || targets[tag] < 0 {
+			return fmt.Sprintf("bad tag %d (0x%X)", tag, value)
+		}
+		return m.TokenName(Token(targets[tag], row))
+	}
+	return fmt.Sprintf("0x%X", value)
+}
@@ -0,0 +1,240 @@
+// Package pefile parses Portable Executable images into a typed model.
+//
+// It has no GUI or operating system dependencies, so it can be used by the
+// PEGo viewer as well as by command line tools and scripts.
+package pefile
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Aliases for the debug/pe types that make up part of the model, so that
+// callers only need to import this package.
+type (
+	FileHeader       = pe.FileHeader
+	OptionalHeader32 = pe.OptionalHeader32
+	OptionalHeader64 = pe.OptionalHeader64
+	DataDirectory    = pe.DataDirectory
+	Section          = pe.Section
+	SectionHeader    = pe.SectionHeader
+)
+
+type PeFull struct {
+	Dos         *DOSHeader  // dos header
+	Nt          *NtHeaders  // nt headers
+	PeFile      *pe.File    // rest of the pe fields
+	FileData    []byte      // raw file
+	Directories []Directory // data directories, in header order
+}
+
+// Directory is a single data directory entry together with its name and the
+// file offset of the entry inside the optional header.
+type Directory struct {
+	Index          int
+	Name           string
+	Offset         uint32
+	VirtualAddress uint32
+	Size           uint32
+}
+
+// Present reports whether the directory points at any data.
+func (d Directory) Present() bool {
+	return d.VirtualAddress != 0 && d.Size != 0
+}
+
+func NewPeFull(_dos *DOSHeader, _nt *NtHeaders, _peFile *pe.File, _fileData []byte) *PeFull {
+	return &PeFull{
+		Dos:      _dos,
+		Nt:       _nt,
+		PeFile:   _peFile,
+		FileData: _fileData,
+	}
+}
+
+// Parse reads a whole PE image from r and decodes its headers, sections and
+// data directory entries.
+func Parse(r io.ReaderAt) (*PeFull, error) {
+	fileData, err := io.ReadAll(io.NewSectionReader(r, 0, math.MaxInt64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	return ParseBytes(fileData)
+}
+
+// ParseBytes decodes a PE image that is already in memory.
+func ParseBytes(fileData []byte) (*PeFull, error) {
+	dos, err := ParseDOSHeader(fileData)
+	if err != nil {
+		return nil, err
+	}
+
+	nt, err := ParseNtHeaders(fileData, dos)
+	if err != nil {
+		return nil, err
+	}
+
+	// debug/pe rejects machine types it does not know, although nothing
+	// else it parses depends on them. Hide the field from it and put the
+	// real value back afterwards.
+	machineOffset := int64(dos.E_ifanew) + int64(binary.Size(nt))
+	if machineOffset+2 > int64(len(fileData)) {
+		return nil, fmt.Errorf("file header out of bounds")
+	}
+	machine := binary.LittleEndian.Uint16(fileData[machineOffset:])
+	peFile, err := pe.NewFile(machineMaskReader{fileData, machineOffset})
+	if err != nil {
+		return nil, fmt.Errorf("unsupported file format: %v", err)
+	}
+	peFile.FileHeader.Machine = machine
+
+	peFull := NewPeFull(dos, nt, peFile, fileData)
+
+	optHeader, err := GetOptionalHeader(peFile)
+	if err != nil {
+		return nil, err
+	}
+	dataDirs, err := GetDataDirectories(optHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := peFull.DataDirectoriesOffset()
+	for i, dir := range dataDirs {
+		name := "Unknown"
+		if i < len(DirectoryNames) {
+			name = DirectoryNames[i]
+		}
+		peFull.Directories = append(peFull.Directories, Directory{
+			Index:          i,
+			Name:           name,
+			Offset:         offset,
+			VirtualAddress: dir.VirtualAddress,
+			Size:           dir.Size,
+		})
+		offset += uint32(binary.Size(dir))
+	}
+
+	return peFull, nil
+}
+
+// machineMaskReader reads a file image with the Machine field of the file
+// header zeroed.
+type machineMaskReader struct {
+	data          []byte
+	machineOffset int64
+}
+
+func (r machineMaskReader) ReadAt(b []byte, off int64) (int, error) {
+	n, err := bytes.NewReader(r.data).ReadAt(b, off)
+	for i := r.machineOffset; i < r.machineOffset+2; i++ {
+		if i >= off && i < off+int64(n) {
+			b[i-off] = 0
+		}
+	}
+	return n, err
+}
+
+// Is64Bit reports whether the image has a PE32+ optional header, which is
+// what decides the width of thunks and other pointer sized fields.
+func (p *PeFull) Is64Bit() bool {
+	optHeader, err := GetOptionalHeader(p.PeFile)
+	if err != nil {
+		return false
+	}
+	_, is64 := optHeader.(*OptionalHeader64)
+	return is64
+}
+
+// Directory returns the data directory entry with the given name.
+func (p *PeFull) Directory(name string) (Directory, bool) {
+	for _, dir := range p.Directories {
+		if dir.Name == name {
+			return dir, true
+		}
+	}
+	return Directory{}, false
+}
+
+// ImageBase returns the preferred load address from the optional header.
+func (p *PeFull) ImageBase() uint64 {
+	switch hdr := p.PeFile.OptionalHeader.(type) {
+	case *OptionalHeader32:
+		return uint64(hdr.ImageBase)
+	case *OptionalHeader64:
+		return hdr.ImageBase
+	}
+	return 0
+}
+
+// SizeOfHeaders returns the size of the headers from the optional header.
+func (p *PeFull) SizeOfHeaders() uint32 {
+	switch hdr := p.PeFile.OptionalHeader.(type) {
+	case *OptionalHeader32:
+		return hdr.SizeOfHeaders
+	case *OptionalHeader64:
+		return hdr.SizeOfHeaders
+	}
+	return 0
+}
+
+// SectionForRVA returns the section that contains rva, or nil.
+func (p *PeFull) SectionForRVA(rva uint32) *Section {
+	for _, sh := range p.PeFile.Sections {
+		if rva >= sh.VirtualAddress && rva < sh.VirtualAddress+max(sh.VirtualSize, sh.Size) {
+			return sh
+		}
+	}
+	return nil
+}
+
+// FileBackedOffset maps size bytes at rva to a file offset, and reports
+// false when they fall outside the raw data of their section, such as in
+// zero-filled .bss space.
+func (p *PeFull) FileBackedOffset(rva uint32, size uint32) (uint32, bool) {
+	for _, sh := range p.PeFile.Sections {
+		if rva < sh.VirtualAddress || rva >= sh.VirtualAddress+max(sh.VirtualSize, sh.Size) {
+			continue
+		}
+		delta := rva - sh.VirtualAddress
+		if uint64(delta)+uint64(size) > uint64(sh.Size) {
+			return 0, false
+		}
+		offset := uint64(sh.Offset) + uint64(delta)
+		if offset+uint64(size) > uint64(len(p.FileData)) {
+			return 0, false
+		}
+		return uint32(offset), true
+	}
+	return 0, false
+}
+
+// NtHeadersOffset is the file offset of the PE signature.
+func (p *PeFull) NtHeadersOffset() uint32 {
+	return p.Dos.E_ifanew
+}
+
+// FileHeaderOffset is the file offset of the COFF file header.
+func (p *PeFull) FileHeaderOffset() uint32 {
+	return p.NtHeadersOffset() + uint32(binary.Size(p.Nt))
+}
+
+// OptionalHeaderOffset is the file offset of the optional header.
+func (p *PeFull) OptionalHeaderOffset() uint32 {
+	return p.FileHeaderOffset() + uint32(binary.Size(p.PeFile.FileHeader))
+}
+
+// DataDirectoriesOffset is the file offset of the first data directory entry.
+func (p *PeFull) DataDirectoriesOffset() uint32 {
+	var dataDirs [16]pe.DataDirectory
+	return p.OptionalHeaderOffset() + uint32(binary.Size(p.PeFile.OptionalHeader)) - uint32(binary.Size(dataDirs))
+}
+
+// SectionHeadersOffset is the file offset of the first section header.
+func (p *PeFull) SectionHeadersOffset() uint32 {
+	return p.OptionalHeaderOffset() + uint32(p.PeFile.FileHeader.SizeOfOptionalHeader)
+}
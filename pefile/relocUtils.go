@@ -0,0 +1,235 @@
+package pefile
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+)
+
+type IMAGE_BASE_RELOCATION struct {
+	VirtualAddress uint32
+	SizeOfBlock    uint32
+}
+
+const (
+	IMAGE_REL_BASED_ABSOLUTE       = 0
+	IMAGE_REL_BASED_HIGH           = 1
+	IMAGE_REL_BASED_LOW            = 2
+	IMAGE_REL_BASED_HIGHLOW        = 3
+	IMAGE_REL_BASED_HIGHADJ        = 4
+	IMAGE_REL_BASED_MACHINE_SPEC_5 = 5
+	IMAGE_REL_BASED_RESERVED       = 6
+	IMAGE_REL_BASED_MACHINE_SPEC_7 = 7
+	IMAGE_REL_BASED_MACHINE_SPEC_8 = 8
+	IMAGE_REL_BASED_MACHINE_SPEC_9 = 9
+	IMAGE_REL_BASED_DIR64          = 10
+)
+
+// RelocationEntry is one fixup of a base relocation block.
+type RelocationEntry struct {
+	Offset       uint32 // file offset of the entry
+	Raw          uint16
+	Type         uint8
+	TypeName     string
+	TargetRVA    uint32
+	TargetOffset uint32 // file offset of the fixup, 0 if it is not backed by the file
+	Size         uint32 // number of bytes the fixup patches
+	Value        uint64 // value currently stored at the target, valid when HasValue is set
+	HasValue     bool
+	Param        uint16 // low half of the adjusted value for HIGHADJ
+}
+
+// RelocationBlock is an IMAGE_BASE_RELOCATION block covering one 4 KiB page.
+type RelocationBlock struct {
+	Offset  uint32 // file offset of the block header
+	Header  IMAGE_BASE_RELOCATION
+	Entries []RelocationEntry
+}
+
+// RelocationTypeName decodes the type of a base relocation entry. Types 5 to
+// 9 mean different things depending on the machine.
+func RelocationTypeName(machine uint16, relocType uint8) string {
+	switch relocType {
+	case IMAGE_REL_BASED_ABSOLUTE:
+		return "ABSOLUTE"
+	case IMAGE_REL_BASED_HIGH:
+		return "HIGH"
+	case IMAGE_REL_BASED_LOW:
+		return "LOW"
+	case IMAGE_REL_BASED_HIGHLOW:
+		return "HIGHLOW"
+	case IMAGE_REL_BASED_HIGHADJ:
+		return "HIGHADJ"
+	case IMAGE_REL_BASED_DIR64:
+		return "DIR64"
+	case IMAGE_REL_BASED_RESERVED:
+		return "RESERVED"
+	}
+
+	isRiscV := machine == pe.IMAGE_FILE_MACHINE_RISCV32 || machine == pe.IMAGE_FILE_MACHINE_RISCV64 || machine == pe.IMAGE_FILE_MACHINE_RISCV128
+	isLoongArch := machine == pe.IMAGE_FILE_MACHINE_LOONGARCH32 || machine == pe.IMAGE_FILE_MACHINE_LOONGARCH64
+	isMips := machine == pe.IMAGE_FILE_MACHINE_R4000 || machine == IMAGE_FILE_MACHINE_R3000 || machine == IMAGE_FILE_MACHINE_R10000 ||
+		machine == pe.IMAGE_FILE_MACHINE_WCEMIPSV2 || machine == pe.IMAGE_FILE_MACHINE_MIPS16 ||
+		machine == pe.IMAGE_FILE_MACHINE_MIPSFPU || machine == pe.IMAGE_FILE_MACHINE_MIPSFPU16
+	isArm := machine == pe.IMAGE_FILE_MACHINE_ARM || machine == pe.IMAGE_FILE_MACHINE_ARMNT || machine == pe.IMAGE_FILE_MACHINE_THUMB
+
+	switch relocType {
+	case IMAGE_REL_BASED_MACHINE_SPEC_5:
+		switch {
+		case isMips:
+			return "MIPS_JMPADDR"
+		case isArm:
+			return "ARM_MOV32"
+		case isRiscV:
+			return "RISCV_HIGH20"
+		}
+	case IMAGE_REL_BASED_MACHINE_SPEC_7:
+		switch {
+		case isArm:
+			return "THUMB_MOV32"
+		case isRiscV:
+			return "RISCV_LOW12I"
+		}
+	case IMAGE_REL_BASED_MACHINE_SPEC_8:
+		switch {
+		case isRiscV:
+			return "RISCV_LOW12S"
+		case machine == pe.IMAGE_FILE_MACHINE_LOONGARCH32:
+			return "LOONGARCH32_MARK_LA"
+		case isLoongArch:
+			return "LOONGARCH64_MARK_LA"
+		}
+	case IMAGE_REL_BASED_MACHINE_SPEC_9:
+		switch {
+		case isMips:
+			return "MIPS_JMPADDR16"
+		case machine == pe.IMAGE_FILE_MACHINE_IA64:
+			return "IA64_IMM64"
+		}
+	}
+	return fmt.Sprintf("MACHINE_SPECIFIC_%d", relocType)
+}
+
+// relocationSize returns how many bytes at the target a relocation type
+// patches, or 0 when it does not touch memory or is not understood.
+func relocationSize(typeName string) uint32 {
+	switch typeName {
+	case "HIGH", "LOW", "HIGHADJ":
+		return 2
+	case "HIGHLOW", "MIPS_JMPADDR", "MIPS_JMPADDR16", "RISCV_HIGH20", "RISCV_LOW12I", "RISCV_LOW12S":
+		return 4
+	case "DIR64", "ARM_MOV32", "THUMB_MOV32":
+		return 8
+	}
+	return 0
+}
+
+// BaseRelocations decodes every IMAGE_BASE_RELOCATION block of the base
+// relocation directory together with its entries.
+func (p *PeFull) BaseRelocations() ([]RelocationBlock, error) {
+	relocDir, ok := p.Directory("Base Relocation Table")
+	if !ok || !relocDir.Present() {
+		return nil, fmt.Errorf("no base relocation table")
+	}
+
+	offset, err := RvaToOffset(p.PeFile, relocDir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []RelocationBlock
+	machine := p.PeFile.FileHeader.Machine
+	headerSize := uint32(binary.Size(IMAGE_BASE_RELOCATION{}))
+	end := uint64(offset) + uint64(relocDir.Size)
+	for uint64(offset)+uint64(headerSize) <= end {
+		if uint64(offset)+uint64(headerSize) > uint64(len(p.FileData)) {
+			return blocks, fmt.Errorf("relocation block at 0x%X out of bounds", offset)
+		}
+
+		block := RelocationBlock{Offset: offset}
+		if err := binary.Read(bytes.NewReader(p.FileData[offset:]), binary.LittleEndian, &block.Header); err != nil {
+			return blocks, err
+		}
+		// Some linkers pad the directory with a zero block
+		if block.Header.SizeOfBlock == 0 {
+			break
+		}
+		blockEnd := uint64(offset) + uint64(block.Header.SizeOfBlock)
+		if block.Header.SizeOfBlock < headerSize || blockEnd > end || blockEnd > uint64(len(p.FileData)) {
+			return blocks, fmt.Errorf("bad relocation block size %d at 0x%X", block.Header.SizeOfBlock, offset)
+		}
+
+		for entryOffset := offset + headerSize; uint64(entryOffset)+2 <= blockEnd; entryOffset += 2 {
+			raw := binary.LittleEndian.Uint16(p.FileData[entryOffset:])
+			entry := RelocationEntry{
+				Offset:    entryOffset,
+				Raw:       raw,
+				Type:      uint8(raw >> 12),
+				TargetRVA: block.Header.VirtualAddress + uint32(raw&0x0FFF),
+			}
+			entry.TypeName = RelocationTypeName(machine, entry.Type)
+
+			// HIGHADJ takes up the following slot for the low half
+			if entry.Type == IMAGE_REL_BASED_HIGHADJ && uint64(entryOffset)+4 <= blockEnd {
+				entryOffset += 2
+				entry.Param = binary.LittleEndian.Uint16(p.FileData[entryOffset:])
+			}
+
+			if entry.Type != IMAGE_REL_BASED_ABSOLUTE {
+				entry.Size = relocationSize(entry.TypeName)
+				if targetOffset, ok := p.FileBackedOffset(entry.TargetRVA, entry.Size); ok {
+					entry.TargetOffset = targetOffset
+					entry.Value, entry.HasValue = p.readRelocationTarget(entry)
+				}
+			}
+			block.Entries = append(block.Entries, entry)
+		}
+
+		blocks = append(blocks, block)
+		offset = uint32(blockEnd)
+	}
+
+	return blocks, nil
+}
+
+// readRelocationTarget reads the value a relocation entry patches. For the
+// ARM MOVW/MOVT pairs the 32-bit immediate they load is returned.
+func (p *PeFull) readRelocationTarget(entry RelocationEntry) (uint64, bool) {
+	if entry.Size == 0 {
+		return 0, false
+	}
+	data := p.FileData[entry.TargetOffset:]
+
+	switch entry.TypeName {
+	case "ARM_MOV32":
+		low := armMovImmediate(binary.LittleEndian.Uint32(data))
+		high := armMovImmediate(binary.LittleEndian.Uint32(data[4:]))
+		return uint64(high)<<16 | uint64(low), true
+	case "THUMB_MOV32":
+		low := thumbMovImmediate(binary.LittleEndian.Uint16(data), binary.LittleEndian.Uint16(data[2:]))
+		high := thumbMovImmediate(binary.LittleEndian.Uint16(data[4:]), binary.LittleEndian.Uint16(data[6:]))
+		return uint64(high)<<16 | uint64(low), true
+	}
+
+	switch entry.Size {
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(data)), true
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(data)), true
+	case 8:
+		return binary.LittleEndian.Uint64(data), true
+	}
+	return 0, false
+}
+
+// armMovImmediate extracts imm4:imm12 from an ARM MOVW/MOVT instruction.
+func armMovImmediate(instruction uint32) uint16 {
+	return uint16((instruction>>16)&0xF)<<12 | uint16(instruction&0xFFF)
+}
+
+// thumbMovImmediate extracts imm4:i:imm3:imm8 from a Thumb-2 MOVW/MOVT
+// instruction given as its two halfwords.
+func thumbMovImmediate(hw1 uint16, hw2 uint16) uint16 {
+	return (hw1&0xF)<<12 | (hw1>>10&1)<<11 | (hw2>>12&7)<<8 | hw2&0xFF
+}
@@ -0,0 +1,192 @@
+package pefile
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"testing"
+)
+
+// relocEntry packs a base relocation entry.
+func relocEntry(relocType uint8, pageOffset uint16) uint16 {
+	return uint16(relocType)<<12 | pageOffset
+}
+
+// relocTestImage has a .data section, a .bss section that is not backed by
+// the file, and a .reloc section with a block for each and a zero block
+// padding the directory.
+func relocTestImage() testImage {
+	data := make([]byte, 0x100)
+	binary.LittleEndian.PutUint64(data[0x10:], testImageBase+0x1234)
+	binary.LittleEndian.PutUint32(data[0x20:], 0x00401234)
+	binary.LittleEndian.PutUint16(data[0x30:], 0x0040)
+	binary.LittleEndian.PutUint16(data[0x40:], 0x1234)
+
+	reloc := newTestData(testSectionRVA(2))
+	relocs := reloc.putStruct(IMAGE_BASE_RELOCATION{testSectionRVA(0), 8 + 6*2})
+	reloc.putStruct([]uint16{
+		relocEntry(IMAGE_REL_BASED_DIR64, 0x10),
+		relocEntry(IMAGE_REL_BASED_HIGHLOW, 0x20),
+		relocEntry(IMAGE_REL_BASED_HIGHADJ, 0x30), 0x8000,
+		relocEntry(IMAGE_REL_BASED_LOW, 0x40),
+		relocEntry(IMAGE_REL_BASED_ABSOLUTE, 0),
+	})
+	reloc.putStruct(IMAGE_BASE_RELOCATION{testSectionRVA(1), 8 + 2*2})
+	reloc.putStruct([]uint16{relocEntry(IMAGE_REL_BASED_DIR64, 0x8), 0})
+	reloc.putStruct(IMAGE_BASE_RELOCATION{})
+
+	return testImage{
+		sections: []testSection{
+			{name: ".data", data: data, characteristics: 0xC0000040},
+			{name: ".bss", characteristics: 0xC0000080, virtualSize: 0x100},
+			{name: ".reloc", data: reloc.bytes(), characteristics: 0x42000040},
+		},
+		dirs: map[int]DataDirectory{5: {VirtualAddress: relocs, Size: uint32(len(reloc.bytes()))}},
+	}
+}
+
+func TestBaseRelocations(t *testing.T) {
+	p := relocTestImage().parse(t)
+
+	blocks, err := p.BaseRelocations()
+	if err != nil {
+		t.Fatalf("BaseRelocations: %v", err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+
+	dataOffset := uint32(testFileAlignment)
+	tests := []struct {
+		typeName     string
+		targetRVA    uint32
+		targetOffset uint32
+		value        uint64
+		hasValue     bool
+	}{
+		{"DIR64", 0x1010, dataOffset + 0x10, testImageBase + 0x1234, true},
+		{"HIGHLOW", 0x1020, dataOffset + 0x20, 0x00401234, true},
+		{"HIGHADJ", 0x1030, dataOffset + 0x30, 0x0040, true},
+		{"LOW", 0x1040, dataOffset + 0x40, 0x1234, true},
+		{"ABSOLUTE", 0x1000, 0, 0, false},
+	}
+	entries := blocks[0].Entries
+	if len(entries) != len(tests) {
+		t.Fatalf("block 0 has %d entries, want %d", len(entries), len(tests))
+	}
+	for i, want := range tests {
+		got := entries[i]
+		if got.TypeName != want.typeName || got.TargetRVA != want.targetRVA || got.TargetOffset != want.targetOffset ||
+			got.Value != want.value || got.HasValue != want.hasValue {
+			t.Errorf("entry %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if entries[2].Param != 0x8000 {
+		t.Errorf("HIGHADJ low half 0x%X, want 0x8000", entries[2].Param)
+	}
+
+	// .bss is not in the file
+	if got := blocks[1].Entries[0]; got.TargetRVA != 0x2008 || got.TargetOffset != 0 || got.HasValue {
+		t.Errorf("block 1 entry 0 = %+v", got)
+	}
+}
+
+func TestBaseRelocationsThumbMov32(t *testing.T) {
+	// movw r0, #0x5678; movt r0, #0x1234
+	text := []byte{0x45, 0xF2, 0x78, 0x60, 0xC1, 0xF2, 0x34, 0x20}
+	reloc := newTestData(testSectionRVA(1))
+	relocs := reloc.putStruct(IMAGE_BASE_RELOCATION{testSectionRVA(0), 8 + 2*2})
+	reloc.putStruct([]uint16{relocEntry(IMAGE_REL_BASED_MACHINE_SPEC_7, 0), 0})
+
+	p := testImage{
+		machine: pe.IMAGE_FILE_MACHINE_ARMNT,
+		pe32:    true,
+		sections: []testSection{
+			{name: ".text", data: text, characteristics: 0x60000020},
+			{name: ".reloc", data: reloc.bytes(), characteristics: 0x42000040},
+		},
+		dirs: map[int]DataDirectory{5: {VirtualAddress: relocs, Size: 12}},
+	}.parse(t)
+
+	blocks, err := p.BaseRelocations()
+	if err != nil {
+		t.Fatalf("BaseRelocations: %v", err)
+	}
+	if got := blocks[0].Entries[0]; got.TypeName != "THUMB_MOV32" || got.Value != 0x12345678 {
+		t.Errorf("entry = %s 0x%X, want THUMB_MOV32 0x12345678", got.TypeName, got.Value)
+	}
+}
+
+func TestBaseRelocationsCorrupt(t *testing.T) {
+	relocOffset := uint32(testFileAlignment) + alignUp(0x100, testFileAlignment)
+	secondBlock := relocOffset + 8 + 6*2
+
+	unmapped := relocTestImage()
+	unmapped.dirs[5] = DataDirectory{VirtualAddress: unmappedRVA, Size: 8}
+
+	// A SizeOfBlock smaller than the block header
+	tooSmall := relocTestImage().bytes()
+	binary.LittleEndian.PutUint32(tooSmall[secondBlock+4:], 4)
+
+	// A block running past the end of the directory
+	tooLarge := relocTestImage().bytes()
+	binary.LittleEndian.PutUint32(tooLarge[secondBlock+4:], 0x1000)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		count int
+	}{
+		{"unmapped directory", unmapped.bytes(), 0},
+		{"block smaller than its header", tooSmall, 1},
+		{"block past the directory", tooLarge, 1},
+		{"truncated file", relocTestImage().bytes()[:secondBlock+4], 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(tt.data)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			blocks, err := p.BaseRelocations()
+			if err == nil {
+				t.Error("BaseRelocations succeeded")
+			}
+			if len(blocks) != tt.count {
+				t.Errorf("got %d blocks, want %d", len(blocks), tt.count)
+			}
+		})
+	}
+}
+
+func TestRelocationTypeName(t *testing.T) {
+	tests := []struct {
+		machine   uint16
+		relocType uint8
+		want      string
+	}{
+		{pe.IMAGE_FILE_MACHINE_AMD64, IMAGE_REL_BASED_DIR64, "DIR64"},
+		{pe.IMAGE_FILE_MACHINE_ARMNT, IMAGE_REL_BASED_MACHINE_SPEC_5, "ARM_MOV32"},
+		{pe.IMAGE_FILE_MACHINE_ARMNT, IMAGE_REL_BASED_MACHINE_SPEC_7, "THUMB_MOV32"},
+		{pe.IMAGE_FILE_MACHINE_R4000, IMAGE_REL_BASED_MACHINE_SPEC_9, "MIPS_JMPADDR16"},
+		{pe.IMAGE_FILE_MACHINE_RISCV64, IMAGE_REL_BASED_MACHINE_SPEC_8, "RISCV_LOW12S"},
+		{pe.IMAGE_FILE_MACHINE_LOONGARCH64, IMAGE_REL_BASED_MACHINE_SPEC_8, "LOONGARCH64_MARK_LA"},
+		{pe.IMAGE_FILE_MACHINE_AMD64, IMAGE_REL_BASED_MACHINE_SPEC_5, "MACHINE_SPECIFIC_5"},
+		{pe.IMAGE_FILE_MACHINE_AMD64, 15, "MACHINE_SPECIFIC_15"},
+	}
+	for _, tt := range tests {
+		if got := RelocationTypeName(tt.machine, tt.relocType); got != tt.want {
+			t.Errorf("RelocationTypeName(%s, %d) = %s, want %s", MachineName(tt.machine), tt.relocType, got, tt.want)
+		}
+	}
+}
+
+// The tree has a single node for the relocation directory; the blocks are
+// listed in its table rather than one node each.
+func TestBaseRelocationsTree(t *testing.T) {
+	p := relocTestImage().parse(t)
+	tree := GetPeTreeMap(p, "test.exe")
+
+	if children := tree["Base Relocation Table"]; len(children) != 0 {
+		t.Errorf("Base Relocation Table has %d child nodes", len(children))
+	}
+}
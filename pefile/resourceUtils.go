@@ -0,0 +1,363 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+type IMAGE_RESOURCE_DIRECTORY struct {
+	Characteristics      uint32
+	TimeDateStamp        uint32
+	MajorVersion         uint16
+	MinorVersion         uint16
+	NumberOfNamedEntries uint16
+	NumberOfIdEntries    uint16
+}
+
+type IMAGE_RESOURCE_DIRECTORY_ENTRY struct {
+	Name         uint32
+	OffsetToData uint32
+}
+
+type IMAGE_RESOURCE_DATA_ENTRY struct {
+	OffsetToData uint32
+	Size         uint32
+	CodePage     uint32
+	Reserved     uint32
+}
+
+// Predefined resource types (RT_*).
+const (
+	RT_CURSOR       = 1
+	RT_BITMAP       = 2
+	RT_ICON         = 3
+	RT_MENU         = 4
+	RT_DIALOG       = 5
+	RT_STRING       = 6
+	RT_FONTDIR      = 7
+	RT_FONT         = 8
+	RT_ACCELERATOR  = 9
+	RT_RCDATA       = 10
+	RT_MESSAGETABLE = 11
+	RT_GROUP_CURSOR = 12
+	RT_GROUP_ICON   = 14
+	RT_VERSION      = 16
+	RT_DLGINCLUDE   = 17
+	RT_PLUGPLAY     = 19
+	RT_VXD          = 20
+	RT_ANICURSOR    = 21
+	RT_ANIICON      = 22
+	RT_HTML         = 23
+	RT_MANIFEST     = 24
+)
+
+var resourceTypeNames = map[uint16]string{
+	RT_CURSOR:       "CURSOR",
+	RT_BITMAP:       "BITMAP",
+	RT_ICON:         "ICON",
+	RT_MENU:         "MENU",
+	RT_DIALOG:       "DIALOG",
+	RT_STRING:       "STRING",
+	RT_FONTDIR:      "FONTDIR",
+	RT_FONT:         "FONT",
+	RT_ACCELERATOR:  "ACCELERATOR",
+	RT_RCDATA:       "RCDATA",
+	RT_MESSAGETABLE: "MESSAGETABLE",
+	RT_GROUP_CURSOR: "GROUP_CURSOR",
+	RT_GROUP_ICON:   "GROUP_ICON",
+	RT_VERSION:      "VERSION",
+	RT_DLGINCLUDE:   "DLGINCLUDE",
+	RT_PLUGPLAY:     "PLUGPLAY",
+	RT_VXD:          "VXD",
+	RT_ANICURSOR:    "ANICURSOR",
+	RT_ANIICON:      "ANIICON",
+	RT_HTML:         "HTML",
+	RT_MANIFEST:     "MANIFEST",
+}
+
+// The high bit of a directory entry's Name and OffsetToData fields tells
+// whether it holds a name string offset and a subdirectory offset.
+const (
+	resourceNameIsString = 0x80000000
+	resourceDataIsDir    = 0x80000000
+)
+
+// maxResourceDepth bounds the recursion on malformed files. Normal images
+// use three levels: type, name and language.
+const maxResourceDepth = 8
+
+// ResourceDirectory is an IMAGE_RESOURCE_DIRECTORY and its entries.
+type ResourceDirectory struct {
+	Offset  uint32 // file offset of the directory header
+	Header  IMAGE_RESOURCE_DIRECTORY
+	Entries []ResourceEntry
+}
+
+// ResourceEntry is a directory entry, which points either at a subdirectory
+// or at a data entry.
+type ResourceEntry struct {
+	Offset    uint32 // file offset of the directory entry
+	Entry     IMAGE_RESOURCE_DIRECTORY_ENTRY
+	Level     int // 0 for types, 1 for names, 2 for languages
+	IsNamed   bool
+	Name      string // Unicode name for named entries
+	ID        uint16 // valid when IsNamed is not set
+	Directory *ResourceDirectory
+	Data      *ResourceData
+}
+
+// ResourceData is a leaf IMAGE_RESOURCE_DATA_ENTRY.
+type ResourceData struct {
+	Offset     uint32 // file offset of the data entry
+	Entry      IMAGE_RESOURCE_DATA_ENTRY
+	DataOffset uint32 // file offset of the resource bytes
+}
+
+// ResourceTypeName returns the RT_* name of a predefined resource type.
+func ResourceTypeName(id uint16) string {
+	if name, ok := resourceTypeNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", id)
+}
+
+// Label is the text used for the entry in the resource tree.
+func (e *ResourceEntry) Label() string {
+	if e.IsNamed {
+		// Tree node ids use "/" as separator
+		return strings.ReplaceAll(e.Name, treeNodeSeparator, "∕")
+	}
+	if e.Level == 0 {
+		return ResourceTypeName(e.ID)
+	}
+	return fmt.Sprintf("%d", e.ID)
+}
+
+// ResourceTable walks the whole resource tree starting at the root
+// directory.
+func (p *PeFull) ResourceTable() (*ResourceDirectory, error) {
+	resourceDir, ok := p.Directory("Resource Table")
+	if !ok || !resourceDir.Present() {
+		return nil, fmt.Errorf("no resource table")
+	}
+
+	base, err := RvaToOffset(p.PeFile, resourceDir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	visited := map[uint32]bool{}
+	return p.readResourceDirectory(base, base, 0, visited)
+}
+
+// readResourceDirectory reads the directory at offset; all offsets inside the
+// resource section are relative to base.
+func (p *PeFull) readResourceDirectory(base uint32, offset uint32, level int, visited map[uint32]bool) (*ResourceDirectory, error) {
+	if level >= maxResourceDepth {
+		return nil, fmt.Errorf("resource tree too deep at 0x%X", offset)
+	}
+	if visited[offset] {
+		return nil, fmt.Errorf("resource directory loop at 0x%X", offset)
+	}
+	visited[offset] = true
+
+	headerSize := uint32(binary.Size(IMAGE_RESOURCE_DIRECTORY{}))
+	if uint64(offset)+uint64(headerSize) > uint64(len(p.FileData)) {
+		return nil, fmt.Errorf("resource directory at 0x%X out of bounds", offset)
+	}
+
+	dir := &ResourceDirectory{Offset: offset}
+	reader := bytes.NewReader(p.FileData[offset:])
+	if err := binary.Read(reader, binary.LittleEndian, &dir.Header); err != nil {
+		return nil, err
+	}
+
+	count := int(dir.Header.NumberOfNamedEntries) + int(dir.Header.NumberOfIdEntries)
+	entryOffset := offset + headerSize
+	entrySize := uint32(binary.Size(IMAGE_RESOURCE_DIRECTORY_ENTRY{}))
+	for i := 0; i < count; i++ {
+		if uint64(entryOffset)+uint64(entrySize) > uint64(len(p.FileData)) {
+			return dir, fmt.Errorf("resource entry at 0x%X out of bounds", entryOffset)
+		}
+
+		entry := ResourceEntry{Offset: entryOffset, Level: level}
+		entry.Entry.Name = binary.LittleEndian.Uint32(p.FileData[entryOffset:])
+		entry.Entry.OffsetToData = binary.LittleEndian.Uint32(p.FileData[entryOffset+4:])
+
+		if entry.Entry.Name&resourceNameIsString != 0 {
+			entry.IsNamed = true
+			name, err := p.readResourceString(base + entry.Entry.Name&^resourceNameIsString)
+			if err != nil {
+				return dir, err
+			}
+			entry.Name = name
+		} else {
+			entry.ID = uint16(entry.Entry.Name)
+		}
+
+		if entry.Entry.OffsetToData&resourceDataIsDir != 0 {
+			subdir, err := p.readResourceDirectory(base, base+entry.Entry.OffsetToData&^resourceDataIsDir, level+1, visited)
+			if err != nil {
+				return dir, err
+			}
+			entry.Directory = subdir
+		} else {
+			data, err := p.readResourceData(base + entry.Entry.OffsetToData)
+			if err != nil {
+				return dir, err
+			}
+			entry.Data = data
+		}
+
+		dir.Entries = append(dir.Entries, entry)
+		entryOffset += entrySize
+	}
+
+	return dir, nil
+}
+
+// readResourceString reads an IMAGE_RESOURCE_DIR_STRING_U: a 16-bit length
+// followed by that many UTF-16 code units.
+func (p *PeFull) readResourceString(offset uint32) (string, error) {
+	if uint64(offset)+2 > uint64(len(p.FileData)) {
+		return "", fmt.Errorf("resource name at 0x%X out of bounds", offset)
+	}
+	length := uint32(binary.LittleEndian.Uint16(p.FileData[offset:]))
+	if uint64(offset)+2+uint64(length)*2 > uint64(len(p.FileData)) {
+		return "", fmt.Errorf("resource name at 0x%X out of bounds", offset)
+	}
+
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(p.FileData[offset+2+uint32(i)*2:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+func (p *PeFull) readResourceData(offset uint32) (*ResourceData, error) {
+	entrySize := uint32(binary.Size(IMAGE_RESOURCE_DATA_ENTRY{}))
+	if uint64(offset)+uint64(entrySize) > uint64(len(p.FileData)) {
+		return nil, fmt.Errorf("resource data entry at 0x%X out of bounds", offset)
+	}
+
+	data := &ResourceData{Offset: offset}
+	reader := bytes.NewReader(p.FileData[offset:])
+	if err := binary.Read(reader, binary.LittleEndian, &data.Entry); err != nil {
+		return nil, err
+	}
+
+	// The data itself is addressed by RVA, unlike everything else in the
+	// tree. An RVA outside every section leaves DataOffset at 0.
+	if dataOffset, err := RvaToOffset(p.PeFile, data.Entry.OffsetToData); err == nil {
+		data.DataOffset = dataOffset
+	}
+	return data, nil
+}
+
+// ResourceBytes returns the raw bytes a resource data entry points at.
+func (p *PeFull) ResourceBytes(data *ResourceData) ([]byte, error) {
+	if data.DataOffset == 0 {
+		return nil, fmt.Errorf("resource data RVA 0x%X is not mapped", data.Entry.OffsetToData)
+	}
+	end := uint64(data.DataOffset) + uint64(data.Entry.Size)
+	if end > uint64(len(p.FileData)) {
+		return nil, fmt.Errorf("resource data at 0x%X out of bounds", data.DataOffset)
+	}
+	return p.FileData[data.DataOffset:end], nil
+}
+
+// FindFirst returns the first data entry of the given resource type.
+func (d *ResourceDirectory) FindFirst(typeID uint16) *ResourceData {
+	for _, entry := range d.Entries {
+		if !entry.IsNamed && entry.ID == typeID {
+			return entry.Directory.FirstData()
+		}
+	}
+	return nil
+}
+
+// Find returns the first language of the resource with the given type and
+// integer id.
+func (d *ResourceDirectory) Find(typeID uint16, id uint16) *ResourceData {
+	if d == nil {
+		return nil
+	}
+	for _, typeEntry := range d.Entries {
+		if typeEntry.IsNamed || typeEntry.ID != typeID || typeEntry.Directory == nil {
+			continue
+		}
+		for _, nameEntry := range typeEntry.Directory.Entries {
+			if nameEntry.IsNamed || nameEntry.ID != id {
+				continue
+			}
+			if nameEntry.Data != nil {
+				return nameEntry.Data
+			}
+			return nameEntry.Directory.FirstData()
+		}
+	}
+	return nil
+}
+
+// FirstData returns the first leaf found in a depth-first walk of d.
+func (d *ResourceDirectory) FirstData() *ResourceData {
+	if d == nil {
+		return nil
+	}
+	for _, entry := range d.Entries {
+		if entry.Data != nil {
+			return entry.Data
+		}
+		if data := entry.Directory.FirstData(); data != nil {
+			return data
+		}
+	}
+	return nil
+}
+
+// FindByTreeID returns the entry for a "Resource Table/..." tree node id.
+func (d *ResourceDirectory) FindByTreeID(uid string) (*ResourceEntry, error) {
+	labels := strings.Split(uid, treeNodeSeparator)
+	if len(labels) < 2 || labels[0] != "Resource Table" {
+		return nil, fmt.Errorf("not a resource node: %s", uid)
+	}
+
+	dir := d
+	var found *ResourceEntry
+	for _, label := range labels[1:] {
+		if dir == nil {
+			return nil, fmt.Errorf("resource node not found: %s", uid)
+		}
+		found = nil
+		for i := range dir.Entries {
+			if dir.Entries[i].Label() == label {
+				found = &dir.Entries[i]
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("resource node not found: %s", uid)
+		}
+		dir = found.Directory
+	}
+	return found, nil
+}
+
+// addResourceTreeNodes adds the entries of dir, recursively, as children of
+// the parent tree node.
+func addResourceTreeNodes(data map[string][]string, parent string, dir *ResourceDirectory) {
+	if dir == nil || len(dir.Entries) == 0 {
+		return
+	}
+	children := []string{}
+	for i := range dir.Entries {
+		entry := &dir.Entries[i]
+		uid := TreeNodeID(parent, entry.Label())
+		children = append(children, uid)
+		addResourceTreeNodes(data, uid, entry.Directory)
+	}
+	data[parent] = children
+}
@@ -0,0 +1,177 @@
+package pefile
+
+import (
+	"encoding/binary"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func resourceTestImage() testImage {
+	rsrc := resourceSection(testSectionRVA(0), []testResource{
+		{typ: RT_MANIFEST, id: 1, lang: 0x409, data: []byte("<assembly/>")},
+		{typ: RT_RCDATA, name: "CONFIG/A", lang: 0x407, data: []byte("abc")},
+		{typ: RT_RCDATA, id: 7, data: []byte("seven")},
+		{typ: 0x100, id: 2, data: []byte("custom")},
+	})
+	return testImage{
+		sections: []testSection{{name: ".rsrc", data: rsrc, characteristics: 0x40000040}},
+		dirs:     map[int]DataDirectory{2: {VirtualAddress: testSectionRVA(0), Size: uint32(len(rsrc))}},
+	}
+}
+
+func TestResourceTable(t *testing.T) {
+	p := resourceTestImage().parse(t)
+
+	root, err := p.ResourceTable()
+	if err != nil {
+		t.Fatalf("ResourceTable: %v", err)
+	}
+	var types []string
+	for _, entry := range root.Entries {
+		types = append(types, entry.Label())
+	}
+	if want := []string{"MANIFEST", "RCDATA", "256"}; !slices.Equal(types, want) {
+		t.Errorf("types = %q, want %q", types, want)
+	}
+
+	named := root.Entries[1].Directory.Entries[0]
+	if !named.IsNamed || named.Name != "CONFIG/A" || named.Label() != "CONFIG∕A" || named.Level != 1 {
+		t.Errorf("named entry = %+v", named)
+	}
+	lang := named.Directory.Entries[0]
+	if lang.ID != 0x407 || lang.Level != 2 || lang.Data == nil {
+		t.Fatalf("language entry = %+v", lang)
+	}
+	if data, err := p.ResourceBytes(lang.Data); err != nil || string(data) != "abc" {
+		t.Errorf("ResourceBytes = %q, %v", data, err)
+	}
+
+	if data := root.Find(RT_RCDATA, 7); data == nil {
+		t.Error("Find(RT_RCDATA, 7) found nothing")
+	} else if raw, _ := p.ResourceBytes(data); string(raw) != "seven" {
+		t.Errorf("Find(RT_RCDATA, 7) = %q", raw)
+	}
+	if root.Find(RT_RCDATA, 8) != nil || root.Find(RT_ICON, 1) != nil {
+		t.Error("Find returned a resource that doesn't exist")
+	}
+	if raw, _ := p.ResourceBytes(root.FindFirst(RT_MANIFEST)); string(raw) != "<assembly/>" {
+		t.Errorf("FindFirst(RT_MANIFEST) = %q", raw)
+	}
+
+	entry, err := root.FindByTreeID("Resource Table/RCDATA/CONFIG∕A/1031")
+	if err != nil || entry.Data != lang.Data {
+		t.Errorf("FindByTreeID = %+v, %v", entry, err)
+	}
+	for _, uid := range []string{"Resource Table/RCDATA/9", "Resource Table/MANIFEST/1/1033/x", "Import Table/RCDATA"} {
+		if _, err := root.FindByTreeID(uid); err == nil {
+			t.Errorf("FindByTreeID(%q) succeeded", uid)
+		}
+	}
+}
+
+func TestResourceTree(t *testing.T) {
+	tree := GetPeTreeMap(resourceTestImage().parse(t), "test.exe")
+	want := []string{"Resource Table/RCDATA/CONFIG∕A", "Resource Table/RCDATA/7"}
+	if got := tree["Resource Table/RCDATA"]; !slices.Equal(got, want) {
+		t.Errorf("RCDATA nodes = %q, want %q", got, want)
+	}
+	if got := tree["Resource Table/RCDATA/7"]; !slices.Equal(got, []string{"Resource Table/RCDATA/7/0"}) {
+		t.Errorf("RCDATA/7 nodes = %q", got)
+	}
+}
+
+// resourceChain lays out depth directories, each with a single entry
+// pointing at the next one, and a data entry at the end.
+func resourceChain(depth int) []byte {
+	d := newTestData(testSectionRVA(0))
+	for i := 0; i < depth; i++ {
+		d.putStruct(IMAGE_RESOURCE_DIRECTORY{NumberOfIdEntries: 1})
+		next := uint32(i+1) * 24
+		if i < depth-1 {
+			next |= resourceDataIsDir
+		}
+		d.putStruct(IMAGE_RESOURCE_DIRECTORY_ENTRY{1, next})
+	}
+	d.putStruct(IMAGE_RESOURCE_DATA_ENTRY{})
+	return d.bytes()
+}
+
+func TestResourceTableCorrupt(t *testing.T) {
+	rsrcOffset := uint32(testFileAlignment)
+
+	// The first type entry points back at the root directory
+	loop := resourceTestImage().bytes()
+	binary.LittleEndian.PutUint32(loop[rsrcOffset+16+4:], resourceDataIsDir)
+
+	// A root directory claiming more entries than the file holds
+	tooMany := resourceTestImage().bytes()
+	binary.LittleEndian.PutUint16(tooMany[rsrcOffset+14:], 0xFFFF)
+
+	// A name string far outside the file
+	badName := resourceTestImage().bytes()
+	rcdata := binary.LittleEndian.Uint32(badName[rsrcOffset+16+8+4:]) &^ resourceDataIsDir
+	binary.LittleEndian.PutUint32(badName[rsrcOffset+rcdata+16:], resourceNameIsString|0x7FFFFFF0)
+
+	deep := resourceTestImage()
+	deep.sections[0].data = resourceChain(maxResourceDepth + 1)
+	shallow := resourceTestImage()
+	shallow.sections[0].data = resourceChain(maxResourceDepth)
+
+	unmapped := resourceTestImage()
+	unmapped.dirs[2] = DataDirectory{VirtualAddress: unmappedRVA, Size: 16}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"directory loop", loop, "loop"},
+		{"entries out of bounds", tooMany, "out of bounds"},
+		{"name out of bounds", badName, "out of bounds"},
+		{"too deep", deep.bytes(), "too deep"},
+		{"as deep as allowed", shallow.bytes(), ""},
+		{"unmapped directory", unmapped.bytes(), "RVA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(tt.data)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			_, err = p.ResourceTable()
+			if tt.err == "" && err != nil {
+				t.Errorf("ResourceTable: %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("ResourceTable error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestResourceBytesCorrupt(t *testing.T) {
+	rsrc := resourceSection(testSectionRVA(0), []testResource{
+		{typ: RT_RCDATA, id: 1, rva: unmappedRVA},
+		{typ: RT_RCDATA, id: 2, data: []byte("ok")},
+	})
+	p := testImage{
+		sections: []testSection{{name: ".rsrc", data: rsrc, characteristics: 0x40000040}},
+		dirs:     map[int]DataDirectory{2: {VirtualAddress: testSectionRVA(0), Size: uint32(len(rsrc))}},
+	}.parse(t)
+
+	root, err := p.ResourceTable()
+	if err != nil {
+		t.Fatalf("ResourceTable: %v", err)
+	}
+	if _, err := p.ResourceBytes(root.Find(RT_RCDATA, 1)); err == nil {
+		t.Error("ResourceBytes of unmapped data succeeded")
+	}
+
+	// A size running past the end of the file
+	data := *root.Find(RT_RCDATA, 2)
+	data.Entry.Size = 0x10000
+	if _, err := p.ResourceBytes(&data); err == nil {
+		t.Error("ResourceBytes past the end of the file succeeded")
+	}
+}
@@ -0,0 +1,418 @@
+package pefile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+const (
+	richSignature = 0x68636952 // "Rich"
+	dansSignature = 0x536E6144 // "DanS"
+)
+
+// RichEntry is one @comp.id record of the Rich header: how many objects a
+// given tool and build contributed to the image.
+type RichEntry struct {
+	Offset    uint32 // file offset of the entry
+	ProductID uint16
+	Build     uint16
+	Count     uint32
+}
+
+// RichHeader is the XOR-encoded record of build tools MSVC linkers leave
+// between the DOS stub and the NT headers.
+type RichHeader struct {
+	Offset    uint32 // file offset of the "DanS" marker
+	Size      uint32 // bytes up to and including the key after "Rich"
+	Key       uint32 // XOR key, which doubles as the checksum
+	Checksum  uint32 // checksum computed from the DOS header and the entries
+	Entries   []RichEntry
+	ClearData []byte // decoded bytes from "DanS" up to "Rich"
+}
+
+// Valid reports whether the stored key matches the computed checksum.
+func (r *RichHeader) Valid() bool {
+	return r.Key == r.Checksum
+}
+
+// RichHeader finds and decodes the Rich header, if the image has one.
+func (p *PeFull) RichHeader() (*RichHeader, error) {
+	end := min(int(p.Dos.E_ifanew), len(p.FileData))
+
+	// "Rich" is stored in clear text and followed by the key
+	richOffset := -1
+	for pos := 0x40; pos+8 <= end; pos += 4 {
+		if binary.LittleEndian.Uint32(p.FileData[pos:]) == richSignature {
+			richOffset = pos
+			break
+		}
+	}
+	if richOffset < 0 {
+		return nil, fmt.Errorf("no Rich header")
+	}
+	key := binary.LittleEndian.Uint32(p.FileData[richOffset+4:])
+
+	// Walk back to the encoded "DanS" marker
+	start := -1
+	for pos := richOffset - 4; pos >= 0x40; pos -= 4 {
+		if binary.LittleEndian.Uint32(p.FileData[pos:])^key == dansSignature {
+			start = pos
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("Rich header at 0x%X has no DanS marker", richOffset)
+	}
+
+	rich := &RichHeader{
+		Offset: uint32(start),
+		Size:   uint32(richOffset + 8 - start),
+		Key:    key,
+	}
+	for pos := start; pos < richOffset; pos += 4 {
+		rich.ClearData = binary.LittleEndian.AppendUint32(rich.ClearData, binary.LittleEndian.Uint32(p.FileData[pos:])^key)
+	}
+
+	// "DanS" is followed by three zero padding DWORDs
+	for pos := 16; pos+8 <= len(rich.ClearData); pos += 8 {
+		compID := binary.LittleEndian.Uint32(rich.ClearData[pos:])
+		rich.Entries = append(rich.Entries, RichEntry{
+			Offset:    uint32(start + pos),
+			ProductID: uint16(compID >> 16),
+			Build:     uint16(compID),
+			Count:     binary.LittleEndian.Uint32(rich.ClearData[pos+4:]),
+		})
+	}
+
+	rich.Checksum = p.richChecksum(rich)
+	return rich, nil
+}
+
+// richChecksum recomputes the key the linker derives from the DOS header
+// and stub (skipping e_lfanew) and the @comp.id records.
+func (p *PeFull) richChecksum(rich *RichHeader) uint32 {
+	checksum := rich.Offset
+	for i := uint32(0); i < rich.Offset; i++ {
+		if i >= 0x3C && i < 0x40 {
+			continue
+		}
+		checksum += bits.RotateLeft32(uint32(p.FileData[i]), int(i))
+	}
+	for _, entry := range rich.Entries {
+		compID := uint32(entry.ProductID)<<16 | uint32(entry.Build)
+		checksum += bits.RotateLeft32(compID, int(entry.Count))
+	}
+	return checksum
+}
+
+// ProductName returns the name of the tool the product id stands for, e.g.
+// "Utc1900_CPP" for the VS2015+ C++ compiler.
+func (e RichEntry) ProductName() string {
+	if int(e.ProductID) < len(richProducts) {
+		return richProducts[e.ProductID].name
+	}
+	return fmt.Sprintf("0x%04X", e.ProductID)
+}
+
+// VisualStudioVersion names the Visual Studio release the tool shipped
+// with, or returns "" when it is not tied to one. Tools since VS2015 share
+// product ids, so the build number tells them apart.
+func (e RichEntry) VisualStudioVersion() string {
+	if int(e.ProductID) >= len(richProducts) || richProducts[e.ProductID].version == "" {
+		return ""
+	}
+	version := richProducts[e.ProductID].version
+	if version == "2015+" {
+		switch {
+		case e.Build < 25000:
+			version = "2015"
+		case e.Build < 27500:
+			version = "2017"
+		case e.Build < 30500:
+			version = "2019"
+		default:
+			version = "2022"
+		}
+	}
+	return "Visual Studio " + version
+}
+
+// richProducts maps @comp.id product ids to tool names and the Visual Studio
+// release they belong to, indexed by product id.
+var richProducts = []struct {
+	name    string
+	version string
+}{
+	{"Unknown", ""},                    // 0x0000
+	{"Import0", ""},                    // 0x0001
+	{"Linker510", "97"},                // 0x0002
+	{"Cvtomf510", "97"},                // 0x0003
+	{"Linker600", "6.0"},               // 0x0004
+	{"Cvtomf600", "6.0"},               // 0x0005
+	{"Cvtres500", "97"},                // 0x0006
+	{"Utc11_Basic", "97"},              // 0x0007
+	{"Utc11_C", "97"},                  // 0x0008
+	{"Utc12_Basic", "6.0"},             // 0x0009
+	{"Utc12_C", "6.0"},                 // 0x000A
+	{"Utc12_CPP", "6.0"},               // 0x000B
+	{"AliasObj60", "6.0"},              // 0x000C
+	{"VisualBasic60", "6.0"},           // 0x000D
+	{"Masm613", "6.0"},                 // 0x000E
+	{"Masm710", "2003"},                // 0x000F
+	{"Linker511", "97"},                // 0x0010
+	{"Cvtomf511", "97"},                // 0x0011
+	{"Masm614", "6.0"},                 // 0x0012
+	{"Linker512", "97"},                // 0x0013
+	{"Cvtomf512", "97"},                // 0x0014
+	{"Utc12_C_Std", "6.0"},             // 0x0015
+	{"Utc12_CPP_Std", "6.0"},           // 0x0016
+	{"Utc12_C_Book", "6.0"},            // 0x0017
+	{"Utc12_CPP_Book", "6.0"},          // 0x0018
+	{"Implib700", "2002"},              // 0x0019
+	{"Cvtomf700", "2002"},              // 0x001A
+	{"Utc13_Basic", "2002"},            // 0x001B
+	{"Utc13_C", "2002"},                // 0x001C
+	{"Utc13_CPP", "2002"},              // 0x001D
+	{"Linker610", "6.0"},               // 0x001E
+	{"Cvtomf610", "6.0"},               // 0x001F
+	{"Linker601", "6.0"},               // 0x0020
+	{"Cvtomf601", "6.0"},               // 0x0021
+	{"Utc12_1_Basic", "6.0"},           // 0x0022
+	{"Utc12_1_C", "6.0"},               // 0x0023
+	{"Utc12_1_CPP", "6.0"},             // 0x0024
+	{"Linker620", "6.0"},               // 0x0025
+	{"Cvtomf620", "6.0"},               // 0x0026
+	{"AliasObj70", "2002"},             // 0x0027
+	{"Linker621", "6.0"},               // 0x0028
+	{"Cvtomf621", "6.0"},               // 0x0029
+	{"Masm615", "6.0"},                 // 0x002A
+	{"Utc13_LTCG_C", "2002"},           // 0x002B
+	{"Utc13_LTCG_CPP", "2002"},         // 0x002C
+	{"Masm620", "6.0"},                 // 0x002D
+	{"ILAsm100", "2002"},               // 0x002E
+	{"Utc12_2_Basic", "6.0"},           // 0x002F
+	{"Utc12_2_C", "6.0"},               // 0x0030
+	{"Utc12_2_CPP", "6.0"},             // 0x0031
+	{"Utc12_2_C_Std", "6.0"},           // 0x0032
+	{"Utc12_2_CPP_Std", "6.0"},         // 0x0033
+	{"Utc12_2_C_Book", "6.0"},          // 0x0034
+	{"Utc12_2_CPP_Book", "6.0"},        // 0x0035
+	{"Implib622", "6.0"},               // 0x0036
+	{"Cvtomf622", "6.0"},               // 0x0037
+	{"Cvtres501", "97"},                // 0x0038
+	{"Utc13_C_Std", "2002"},            // 0x0039
+	{"Utc13_CPP_Std", "2002"},          // 0x003A
+	{"Cvtpgd1300", "2002"},             // 0x003B
+	{"Linker622", "6.0"},               // 0x003C
+	{"Linker700", "2002"},              // 0x003D
+	{"Export622", "6.0"},               // 0x003E
+	{"Export700", "2002"},              // 0x003F
+	{"Masm700", "2002"},                // 0x0040
+	{"Utc13_POGO_I_C", "2002"},         // 0x0041
+	{"Utc13_POGO_I_CPP", "2002"},       // 0x0042
+	{"Utc13_POGO_O_C", "2002"},         // 0x0043
+	{"Utc13_POGO_O_CPP", "2002"},       // 0x0044
+	{"Cvtres700", "2002"},              // 0x0045
+	{"Cvtres710p", "2003"},             // 0x0046
+	{"Linker710p", "2003"},             // 0x0047
+	{"Cvtomf710p", "2003"},             // 0x0048
+	{"Export710p", "2003"},             // 0x0049
+	{"Implib710p", "2003"},             // 0x004A
+	{"Masm710p", "2003"},               // 0x004B
+	{"Utc1310p_C", "2003"},             // 0x004C
+	{"Utc1310p_CPP", "2003"},           // 0x004D
+	{"Utc1310p_C_Std", "2003"},         // 0x004E
+	{"Utc1310p_CPP_Std", "2003"},       // 0x004F
+	{"Utc1310p_LTCG_C", "2003"},        // 0x0050
+	{"Utc1310p_LTCG_CPP", "2003"},      // 0x0051
+	{"Utc1310p_POGO_I_C", "2003"},      // 0x0052
+	{"Utc1310p_POGO_I_CPP", "2003"},    // 0x0053
+	{"Utc1310p_POGO_O_C", "2003"},      // 0x0054
+	{"Utc1310p_POGO_O_CPP", "2003"},    // 0x0055
+	{"Linker624", "6.0"},               // 0x0056
+	{"Cvtomf624", "6.0"},               // 0x0057
+	{"Export624", "6.0"},               // 0x0058
+	{"Implib624", "6.0"},               // 0x0059
+	{"Linker710", "2003"},              // 0x005A
+	{"Cvtomf710", "2003"},              // 0x005B
+	{"Export710", "2003"},              // 0x005C
+	{"Implib710", "2003"},              // 0x005D
+	{"Cvtres710", "2003"},              // 0x005E
+	{"Utc1310_C", "2003"},              // 0x005F
+	{"Utc1310_CPP", "2003"},            // 0x0060
+	{"Utc1310_C_Std", "2003"},          // 0x0061
+	{"Utc1310_CPP_Std", "2003"},        // 0x0062
+	{"Utc1310_LTCG_C", "2003"},         // 0x0063
+	{"Utc1310_LTCG_CPP", "2003"},       // 0x0064
+	{"Utc1310_POGO_I_C", "2003"},       // 0x0065
+	{"Utc1310_POGO_I_CPP", "2003"},     // 0x0066
+	{"Utc1310_POGO_O_C", "2003"},       // 0x0067
+	{"Utc1310_POGO_O_CPP", "2003"},     // 0x0068
+	{"AliasObj710", "2003"},            // 0x0069
+	{"AliasObj710p", "2003"},           // 0x006A
+	{"Cvtpgd1310", "2003"},             // 0x006B
+	{"Cvtpgd1310p", "2003"},            // 0x006C
+	{"Utc1400_C", "2005"},              // 0x006D
+	{"Utc1400_CPP", "2005"},            // 0x006E
+	{"Utc1400_C_Std", "2005"},          // 0x006F
+	{"Utc1400_CPP_Std", "2005"},        // 0x0070
+	{"Utc1400_LTCG_C", "2005"},         // 0x0071
+	{"Utc1400_LTCG_CPP", "2005"},       // 0x0072
+	{"Utc1400_POGO_I_C", "2005"},       // 0x0073
+	{"Utc1400_POGO_I_CPP", "2005"},     // 0x0074
+	{"Utc1400_POGO_O_C", "2005"},       // 0x0075
+	{"Utc1400_POGO_O_CPP", "2005"},     // 0x0076
+	{"Cvtpgd1400", "2005"},             // 0x0077
+	{"Linker800", "2005"},              // 0x0078
+	{"Cvtomf800", "2005"},              // 0x0079
+	{"Export800", "2005"},              // 0x007A
+	{"Implib800", "2005"},              // 0x007B
+	{"Cvtres800", "2005"},              // 0x007C
+	{"Masm800", "2005"},                // 0x007D
+	{"AliasObj800", "2005"},            // 0x007E
+	{"PhoenixPrerelease", ""},          // 0x007F
+	{"Utc1400_CVTCIL_C", "2005"},       // 0x0080
+	{"Utc1400_CVTCIL_CPP", "2005"},     // 0x0081
+	{"Utc1400_LTCG_MSIL", "2005"},      // 0x0082
+	{"Utc1500_C", "2008"},              // 0x0083
+	{"Utc1500_CPP", "2008"},            // 0x0084
+	{"Utc1500_C_Std", "2008"},          // 0x0085
+	{"Utc1500_CPP_Std", "2008"},        // 0x0086
+	{"Utc1500_CVTCIL_C", "2008"},       // 0x0087
+	{"Utc1500_CVTCIL_CPP", "2008"},     // 0x0088
+	{"Utc1500_LTCG_C", "2008"},         // 0x0089
+	{"Utc1500_LTCG_CPP", "2008"},       // 0x008A
+	{"Utc1500_LTCG_MSIL", "2008"},      // 0x008B
+	{"Utc1500_POGO_I_C", "2008"},       // 0x008C
+	{"Utc1500_POGO_I_CPP", "2008"},     // 0x008D
+	{"Utc1500_POGO_O_C", "2008"},       // 0x008E
+	{"Utc1500_POGO_O_CPP", "2008"},     // 0x008F
+	{"Cvtpgd1500", "2008"},             // 0x0090
+	{"Linker900", "2008"},              // 0x0091
+	{"Export900", "2008"},              // 0x0092
+	{"Implib900", "2008"},              // 0x0093
+	{"Cvtres900", "2008"},              // 0x0094
+	{"Masm900", "2008"},                // 0x0095
+	{"AliasObj900", "2008"},            // 0x0096
+	{"Resource", ""},                   // 0x0097
+	{"AliasObj1000", "2010"},           // 0x0098
+	{"Cvtpgd1600", "2010"},             // 0x0099
+	{"Cvtres1000", "2010"},             // 0x009A
+	{"Export1000", "2010"},             // 0x009B
+	{"Implib1000", "2010"},             // 0x009C
+	{"Linker1000", "2010"},             // 0x009D
+	{"Masm1000", "2010"},               // 0x009E
+	{"Phx1600_C", "2010"},              // 0x009F
+	{"Phx1600_CPP", "2010"},            // 0x00A0
+	{"Phx1600_CVTCIL_C", "2010"},       // 0x00A1
+	{"Phx1600_CVTCIL_CPP", "2010"},     // 0x00A2
+	{"Phx1600_LTCG_C", "2010"},         // 0x00A3
+	{"Phx1600_LTCG_CPP", "2010"},       // 0x00A4
+	{"Phx1600_LTCG_MSIL", "2010"},      // 0x00A5
+	{"Phx1600_POGO_I_C", "2010"},       // 0x00A6
+	{"Phx1600_POGO_I_CPP", "2010"},     // 0x00A7
+	{"Phx1600_POGO_O_C", "2010"},       // 0x00A8
+	{"Phx1600_POGO_O_CPP", "2010"},     // 0x00A9
+	{"Utc1600_C", "2010"},              // 0x00AA
+	{"Utc1600_CPP", "2010"},            // 0x00AB
+	{"Utc1600_CVTCIL_C", "2010"},       // 0x00AC
+	{"Utc1600_CVTCIL_CPP", "2010"},     // 0x00AD
+	{"Utc1600_LTCG_C", "2010"},         // 0x00AE
+	{"Utc1600_LTCG_CPP", "2010"},       // 0x00AF
+	{"Utc1600_LTCG_MSIL", "2010"},      // 0x00B0
+	{"Utc1600_POGO_I_C", "2010"},       // 0x00B1
+	{"Utc1600_POGO_I_CPP", "2010"},     // 0x00B2
+	{"Utc1600_POGO_O_C", "2010"},       // 0x00B3
+	{"Utc1600_POGO_O_CPP", "2010"},     // 0x00B4
+	{"AliasObj1010", "2010 SP1"},       // 0x00B5
+	{"Cvtpgd1610", "2010 SP1"},         // 0x00B6
+	{"Cvtres1010", "2010 SP1"},         // 0x00B7
+	{"Export1010", "2010 SP1"},         // 0x00B8
+	{"Implib1010", "2010 SP1"},         // 0x00B9
+	{"Linker1010", "2010 SP1"},         // 0x00BA
+	{"Masm1010", "2010 SP1"},           // 0x00BB
+	{"Utc1610_C", "2010 SP1"},          // 0x00BC
+	{"Utc1610_CPP", "2010 SP1"},        // 0x00BD
+	{"Utc1610_CVTCIL_C", "2010 SP1"},   // 0x00BE
+	{"Utc1610_CVTCIL_CPP", "2010 SP1"}, // 0x00BF
+	{"Utc1610_LTCG_C", "2010 SP1"},     // 0x00C0
+	{"Utc1610_LTCG_CPP", "2010 SP1"},   // 0x00C1
+	{"Utc1610_LTCG_MSIL", "2010 SP1"},  // 0x00C2
+	{"Utc1610_POGO_I_C", "2010 SP1"},   // 0x00C3
+	{"Utc1610_POGO_I_CPP", "2010 SP1"}, // 0x00C4
+	{"Utc1610_POGO_O_C", "2010 SP1"},   // 0x00C5
+	{"Utc1610_POGO_O_CPP", "2010 SP1"}, // 0x00C6
+	{"AliasObj1100", "2012"},           // 0x00C7
+	{"Cvtpgd1700", "2012"},             // 0x00C8
+	{"Cvtres1100", "2012"},             // 0x00C9
+	{"Export1100", "2012"},             // 0x00CA
+	{"Implib1100", "2012"},             // 0x00CB
+	{"Linker1100", "2012"},             // 0x00CC
+	{"Masm1100", "2012"},               // 0x00CD
+	{"Utc1700_C", "2012"},              // 0x00CE
+	{"Utc1700_CPP", "2012"},            // 0x00CF
+	{"Utc1700_CVTCIL_C", "2012"},       // 0x00D0
+	{"Utc1700_CVTCIL_CPP", "2012"},     // 0x00D1
+	{"Utc1700_LTCG_C", "2012"},         // 0x00D2
+	{"Utc1700_LTCG_CPP", "2012"},       // 0x00D3
+	{"Utc1700_LTCG_MSIL", "2012"},      // 0x00D4
+	{"Utc1700_POGO_I_C", "2012"},       // 0x00D5
+	{"Utc1700_POGO_I_CPP", "2012"},     // 0x00D6
+	{"Utc1700_POGO_O_C", "2012"},       // 0x00D7
+	{"Utc1700_POGO_O_CPP", "2012"},     // 0x00D8
+	{"AliasObj1200", "2013"},           // 0x00D9
+	{"Cvtpgd1800", "2013"},             // 0x00DA
+	{"Cvtres1200", "2013"},             // 0x00DB
+	{"Export1200", "2013"},             // 0x00DC
+	{"Implib1200", "2013"},             // 0x00DD
+	{"Linker1200", "2013"},             // 0x00DE
+	{"Masm1200", "2013"},               // 0x00DF
+	{"Utc1800_C", "2013"},              // 0x00E0
+	{"Utc1800_CPP", "2013"},            // 0x00E1
+	{"Utc1800_CVTCIL_C", "2013"},       // 0x00E2
+	{"Utc1800_CVTCIL_CPP", "2013"},     // 0x00E3
+	{"Utc1800_LTCG_C", "2013"},         // 0x00E4
+	{"Utc1800_LTCG_CPP", "2013"},       // 0x00E5
+	{"Utc1800_LTCG_MSIL", "2013"},      // 0x00E6
+	{"Utc1800_POGO_I_C", "2013"},       // 0x00E7
+	{"Utc1800_POGO_I_CPP", "2013"},     // 0x00E8
+	{"Utc1800_POGO_O_C", "2013"},       // 0x00E9
+	{"Utc1800_POGO_O_CPP", "2013"},     // 0x00EA
+	{"AliasObj1210", "2013"},           // 0x00EB
+	{"Cvtpgd1810", "2013"},             // 0x00EC
+	{"Cvtres1210", "2013"},             // 0x00ED
+	{"Export1210", "2013"},             // 0x00EE
+	{"Implib1210", "2013"},             // 0x00EF
+	{"Linker1210", "2013"},             // 0x00F0
+	{"Masm1210", "2013"},               // 0x00F1
+	{"Utc1810_C", "2013"},              // 0x00F2
+	{"Utc1810_CPP", "2013"},            // 0x00F3
+	{"Utc1810_CVTCIL_C", "2013"},       // 0x00F4
+	{"Utc1810_CVTCIL_CPP", "2013"},     // 0x00F5
+	{"Utc1810_LTCG_C", "2013"},         // 0x00F6
+	{"Utc1810_LTCG_CPP", "2013"},       // 0x00F7
+	{"Utc1810_LTCG_MSIL", "2013"},      // 0x00F8
+	{"Utc1810_POGO_I_C", "2013"},       // 0x00F9
+	{"Utc1810_POGO_I_CPP", "2013"},     // 0x00FA
+	{"Utc1810_POGO_O_C", "2013"},       // 0x00FB
+	{"Utc1810_POGO_O_CPP", "2013"},     // 0x00FC
+	{"AliasObj1400", "2015+"},          // 0x00FD
+	{"Cvtpgd1900", "2015+"},            // 0x00FE
+	{"Cvtres1400", "2015+"},            // 0x00FF
+	{"Export1400", "2015+"},            // 0x0100
+	{"Implib1400", "2015+"},            // 0x0101
+	{"Linker1400", "2015+"},            // 0x0102
+	{"Masm1400", "2015+"},              // 0x0103
+	{"Utc1900_C", "2015+"},             // 0x0104
+	{"Utc1900_CPP", "2015+"},           // 0x0105
+	{"Utc1900_CVTCIL_C", "2015+"},      // 0x0106
+	{"Utc1900_CVTCIL_CPP", "2015+"},    // 0x0107
+	{"Utc1900_LTCG_C", "2015+"},        // 0x0108
+	{"Utc1900_LTCG_CPP", "2015+"},      // 0x0109
+	{"Utc1900_LTCG_MSIL", "2015+"},     // 0x010A
+	{"Utc1900_POGO_I_C", "2015+"},      // 0x010B
+	{"Utc1900_POGO_I_CPP", "2015+"},    // 0x010C
+	{"Utc1900_POGO_O_C", "2015+"},      // 0x010D
+	{"Utc1900_POGO_O_CPP", "2015+"},    // 0x010E
+}
@@ -0,0 +1,136 @@
+package pefile
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// gui32Stub is the DOS header, stub and Rich header of an MSVC 2008 linked
+// executable, up to and including the key after "Rich". e_lfanew is 0xE8.
+const gui32Stub = "" +
+	"4d5a90000300000004000000ffff0000b8000000000000004000000000000000" +
+	"00000000000000000000000000000000000000000000000000000000e8000000" +
+	"0e1fba0e00b409cd21b8014ccd21546869732070726f6772616d2063616e6e6f" +
+	"742062652072756e20696e20444f53206d6f64652e0d0d0a2400000000000000" +
+	"e453c0d8a032ae8ba032ae8ba032ae8b87f4d58ba332ae8ba032af8bfb32ae8b" +
+	"1d7d388ba432ae8bbe602a8b8432ae8bbe603b8bb232ae8bbe602d8bd132ae8b" +
+	"be603f8ba132ae8b52696368a032ae8b"
+
+// gui32Key is the key the linker stored, which is the checksum of the stub
+// and the entries.
+const gui32Key = 0x8BAE32A0
+
+// richTestImage puts the gui32Stub in front of the NT headers of an image
+// without sections.
+func richTestImage(t *testing.T) []byte {
+	t.Helper()
+	stub, err := hex.DecodeString(gui32Stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(stub, make([]byte, 0xE8-len(stub))...)
+	return append(data, testImage{}.bytes()[testLfanew:]...)
+}
+
+func TestRichHeader(t *testing.T) {
+	p, err := ParseBytes(richTestImage(t))
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+
+	rich, err := p.RichHeader()
+	if err != nil {
+		t.Fatalf("RichHeader: %v", err)
+	}
+	if rich.Offset != 0x80 || rich.Size != 0x50 || rich.Key != gui32Key {
+		t.Errorf("Rich header at 0x%X, size 0x%X, key 0x%X", rich.Offset, rich.Size, rich.Key)
+	}
+	if rich.Checksum != gui32Key || !rich.Valid() {
+		t.Errorf("checksum 0x%X, want 0x%X", rich.Checksum, gui32Key)
+	}
+	if !strings.HasPrefix(string(rich.ClearData), "DanS\x00\x00\x00\x00") {
+		t.Errorf("clear data starts with % X", rich.ClearData[:8])
+	}
+
+	tests := []struct {
+		productID, build uint16
+		count            uint32
+		name, version    string
+	}{
+		{0x007B, 50727, 3, "Implib800", "Visual Studio 2005"},
+		{0x0001, 0, 91, "Import0", ""},
+		{0x0096, 20413, 4, "AliasObj900", "Visual Studio 2008"},
+		{0x0084, 21022, 36, "Utc1500_CPP", "Visual Studio 2008"},
+		{0x0095, 21022, 18, "Masm900", "Visual Studio 2008"},
+		{0x0083, 21022, 113, "Utc1500_C", "Visual Studio 2008"},
+		{0x0091, 21022, 1, "Linker900", "Visual Studio 2008"},
+	}
+	if len(rich.Entries) != len(tests) {
+		t.Fatalf("got %d entries, want %d", len(rich.Entries), len(tests))
+	}
+	for i, want := range tests {
+		got := rich.Entries[i]
+		if got.ProductID != want.productID || got.Build != want.build || got.Count != want.count ||
+			got.Offset != 0x90+uint32(i)*8 {
+			t.Errorf("entry %d = %+v", i, got)
+		}
+		if got.ProductName() != want.name || got.VisualStudioVersion() != want.version {
+			t.Errorf("entry %d is %s from %q, want %s from %q", i, got.ProductName(), got.VisualStudioVersion(), want.name, want.version)
+		}
+	}
+}
+
+func TestRichEntryNames(t *testing.T) {
+	tests := []struct {
+		entry         RichEntry
+		name, version string
+	}{
+		{RichEntry{ProductID: 0x0105, Build: 24215}, "Utc1900_CPP", "Visual Studio 2015"},
+		{RichEntry{ProductID: 0x0105, Build: 26706}, "Utc1900_CPP", "Visual Studio 2017"},
+		{RichEntry{ProductID: 0x0105, Build: 29913}, "Utc1900_CPP", "Visual Studio 2019"},
+		{RichEntry{ProductID: 0x0105, Build: 33145}, "Utc1900_CPP", "Visual Studio 2022"},
+		{RichEntry{ProductID: 0xFFFF}, "0xFFFF", ""},
+	}
+	for _, tt := range tests {
+		if name, version := tt.entry.ProductName(), tt.entry.VisualStudioVersion(); name != tt.name || version != tt.version {
+			t.Errorf("%+v is %s from %q, want %s from %q", tt.entry, name, version, tt.name, tt.version)
+		}
+	}
+}
+
+func TestRichHeaderCorrupt(t *testing.T) {
+	// A changed count no longer matches the checksum
+	tampered := richTestImage(t)
+	tampered[0x94] ^= 1
+	p, err := ParseBytes(tampered)
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	if rich, err := p.RichHeader(); err != nil || rich.Valid() {
+		t.Errorf("tampered Rich header: valid %v, %v", rich != nil && rich.Valid(), err)
+	}
+
+	noDanS := richTestImage(t)
+	binary.LittleEndian.PutUint32(noDanS[0x80:], 0)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no Rich header", testImage{}.bytes()},
+		{"no DanS marker", noDanS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(tt.data)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			if rich, err := p.RichHeader(); err == nil {
+				t.Errorf("RichHeader = %+v", rich)
+			}
+		})
+	}
+}
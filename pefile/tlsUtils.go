@@ -0,0 +1,119 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+type IMAGE_TLS_DIRECTORY32 struct {
+	StartAddressOfRawData uint32
+	EndAddressOfRawData   uint32
+	AddressOfIndex        uint32
+	AddressOfCallBacks    uint32
+	SizeOfZeroFill        uint32
+	Characteristics       uint32
+}
+
+type IMAGE_TLS_DIRECTORY64 struct {
+	StartAddressOfRawData uint64
+	EndAddressOfRawData   uint64
+	AddressOfIndex        uint64
+	AddressOfCallBacks    uint64
+	SizeOfZeroFill        uint32
+	Characteristics       uint32
+}
+
+// maxTlsCallbacks bounds the callback array walk on corrupt images.
+const maxTlsCallbacks = 1024
+
+// TlsCallback is one entry of the TLS callback array.
+type TlsCallback struct {
+	Offset     uint32 // file offset of the array slot
+	VA         uint64
+	RVA        uint32
+	FileOffset uint32 // file offset of the callback code, 0 if it is not in the file
+	Section    string // name of the containing section, "" if none
+}
+
+// TlsDirectory is the decoded TLS directory. Header is either an
+// *IMAGE_TLS_DIRECTORY32 or an *IMAGE_TLS_DIRECTORY64, matching the
+// optional header.
+type TlsDirectory struct {
+	Offset    uint32
+	Header    any
+	Callbacks []TlsCallback
+}
+
+// TLSDirectory decodes the TLS directory and follows AddressOfCallBacks to
+// list the callbacks the loader runs before the entry point.
+func (p *PeFull) TLSDirectory() (*TlsDirectory, error) {
+	tlsDir, ok := p.Directory("TLS Table")
+	if !ok || !tlsDir.Present() {
+		return nil, fmt.Errorf("no TLS directory")
+	}
+
+	offset, err := RvaToOffset(p.PeFile, tlsDir.VirtualAddress)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(offset) > uint64(len(p.FileData)) {
+		return nil, fmt.Errorf("TLS directory at 0x%X out of bounds", offset)
+	}
+
+	tls := &TlsDirectory{Offset: offset}
+	reader := bytes.NewReader(p.FileData[offset:])
+	var callbacksVA uint64
+	if p.Is64Bit() {
+		var header IMAGE_TLS_DIRECTORY64
+		if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+			return nil, fmt.Errorf("failed to read TLS directory: %v", err)
+		}
+		tls.Header = &header
+		callbacksVA = header.AddressOfCallBacks
+	} else {
+		var header IMAGE_TLS_DIRECTORY32
+		if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+			return nil, fmt.Errorf("failed to read TLS directory: %v", err)
+		}
+		tls.Header = &header
+		callbacksVA = uint64(header.AddressOfCallBacks)
+	}
+
+	if callbacksVA == 0 {
+		return tls, nil
+	}
+	imageBase := p.ImageBase()
+	if callbacksVA < imageBase {
+		return tls, fmt.Errorf("AddressOfCallBacks 0x%X is below ImageBase 0x%X", callbacksVA, imageBase)
+	}
+	slot, err := RvaToOffset(p.PeFile, uint32(callbacksVA-imageBase))
+	if err != nil {
+		return tls, fmt.Errorf("AddressOfCallBacks: %v", err)
+	}
+
+	for len(tls.Callbacks) < maxTlsCallbacks {
+		va, err := p.readThunk(slot)
+		if err != nil {
+			return tls, err
+		}
+		if va == 0 {
+			break
+		}
+
+		callback := TlsCallback{Offset: slot, VA: va}
+		if va >= imageBase && va-imageBase <= 0xFFFFFFFF {
+			callback.RVA = uint32(va - imageBase)
+			if section := p.SectionForRVA(callback.RVA); section != nil {
+				callback.Section = section.Name
+			}
+			if fileOffset, ok := p.FileBackedOffset(callback.RVA, 1); ok {
+				callback.FileOffset = fileOffset
+			}
+		}
+		tls.Callbacks = append(tls.Callbacks, callback)
+		slot += p.thunkSize()
+	}
+
+	return tls, nil
+}
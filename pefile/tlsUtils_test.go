@@ -0,0 +1,142 @@
+package pefile
+
+import (
+	"strings"
+	"testing"
+)
+
+// tlsTestImage has a .text section and a .rdata section holding the TLS
+// directory followed by the callback array.
+func tlsTestImage(pe32 bool, callbacks []uint64) testImage {
+	imageBase := uint64(testImageBase)
+	if pe32 {
+		imageBase = 0x400000
+	}
+
+	rdata := newTestData(testSectionRVA(1))
+	var dir uint32
+	array := rdata.next() + 0x40
+	if pe32 {
+		dir = rdata.putStruct(IMAGE_TLS_DIRECTORY32{AddressOfIndex: uint32(imageBase) + 0x2100, AddressOfCallBacks: uint32(imageBase) + array})
+	} else {
+		dir = rdata.putStruct(IMAGE_TLS_DIRECTORY64{AddressOfIndex: imageBase + 0x2100, AddressOfCallBacks: imageBase + uint64(array)})
+	}
+	rdata.put(make([]byte, array-rdata.next()))
+	for _, callback := range callbacks {
+		if pe32 {
+			rdata.putStruct(uint32(callback))
+		} else {
+			rdata.putStruct(callback)
+		}
+	}
+
+	return testImage{
+		pe32: pe32,
+		sections: []testSection{
+			{name: ".text", data: make([]byte, 0x20), characteristics: 0x60000020},
+			{name: ".rdata", data: rdata.bytes(), characteristics: 0x40000040},
+		},
+		dirs: map[int]DataDirectory{9: {VirtualAddress: dir, Size: 0x28}},
+	}
+}
+
+func TestTLSDirectory(t *testing.T) {
+	for _, pe32 := range []bool{false, true} {
+		imageBase := uint64(testImageBase)
+		if pe32 {
+			imageBase = 0x400000
+		}
+		p := tlsTestImage(pe32, []uint64{imageBase + 0x1010, imageBase + 0x1800, 0x10, 0}).parse(t)
+
+		tls, err := p.TLSDirectory()
+		if err != nil {
+			t.Fatalf("TLSDirectory (PE32 %v): %v", pe32, err)
+		}
+		if _, ok := tls.Header.(*IMAGE_TLS_DIRECTORY32); ok != pe32 {
+			t.Errorf("header is %T for PE32 %v", tls.Header, pe32)
+		}
+
+		slotSize := uint32(8)
+		if pe32 {
+			slotSize = 4
+		}
+		array := testFileAlignment + alignUp(0x20, testFileAlignment) + 0x40
+		want := []TlsCallback{
+			// In the file
+			{Offset: array, VA: imageBase + 0x1010, RVA: 0x1010, FileOffset: testFileAlignment + 0x10, Section: ".text"},
+			// In the section but past its raw data
+			{Offset: array + slotSize, VA: imageBase + 0x1800, RVA: 0x1800},
+			// Below the image base
+			{Offset: array + 2*slotSize, VA: 0x10},
+		}
+		if len(tls.Callbacks) != len(want) {
+			t.Fatalf("got %d callbacks, want %d (PE32 %v)", len(tls.Callbacks), len(want), pe32)
+		}
+		for i := range want {
+			if tls.Callbacks[i] != want[i] {
+				t.Errorf("callback %d = %+v, want %+v (PE32 %v)", i, tls.Callbacks[i], want[i], pe32)
+			}
+		}
+	}
+}
+
+func TestTLSDirectoryNoCallbacks(t *testing.T) {
+	image := tlsTestImage(false, nil)
+	image.sections[1].data = image.sections[1].data[:0x28]
+	// AddressOfCallBacks is the fourth field
+	clear(image.sections[1].data[0x18:0x20])
+
+	tls, err := image.parse(t).TLSDirectory()
+	if err != nil || len(tls.Callbacks) != 0 {
+		t.Errorf("TLSDirectory = %+v, %v", tls, err)
+	}
+}
+
+func TestTLSDirectoryCorrupt(t *testing.T) {
+	callbacks := []uint64{testImageBase + 0x1010, 0}
+
+	belowBase := tlsTestImage(false, callbacks)
+	belowBase.sections[1].data[0x18+4] = 0
+
+	unmappedArray := tlsTestImage(false, callbacks)
+	unmappedArray.sections[1].data[0x18+3] = 0x7F
+
+	// An array without a terminator running into the end of the file
+	unterminated := tlsTestImage(false, []uint64{testImageBase + 0x1010})
+	data := unterminated.bytes()
+	array := testFileAlignment + alignUp(0x20, testFileAlignment) + 0x40
+	for i := array + 8; i < uint32(len(data)); i++ {
+		data[i] = 0xFF
+	}
+
+	unmapped := tlsTestImage(false, callbacks)
+	unmapped.dirs[9] = DataDirectory{VirtualAddress: unmappedRVA, Size: 0x28}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+		ok   bool // whether the header is still returned
+	}{
+		{"callbacks below the image base", belowBase.bytes(), "below ImageBase", true},
+		{"unmapped callbacks", unmappedArray.bytes(), "AddressOfCallBacks", true},
+		{"unterminated callbacks", data, "out of bounds", true},
+		{"unmapped directory", unmapped.bytes(), "RVA", false},
+		{"truncated directory", tlsTestImage(false, callbacks).bytes()[:array-0x40+0x10], "TLS directory", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseBytes(tt.data)
+			if err != nil {
+				t.Fatalf("ParseBytes: %v", err)
+			}
+			tls, err := p.TLSDirectory()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("TLSDirectory error %v, want %q", err, tt.err)
+			}
+			if (tls != nil) != tt.ok {
+				t.Errorf("TLSDirectory = %+v", tls)
+			}
+		})
+	}
+}
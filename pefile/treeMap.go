@@ -0,0 +1,190 @@
+package pefile
+
+import (
+	"debug/pe"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// treeNodeSeparator joins the path of a nested tree node into its id, so
+// that nodes with the same label under different parents stay distinct.
+const treeNodeSeparator = "/"
+
+// TreeNodeID returns the id of the child called name under parent.
+func TreeNodeID(parent string, name string) string {
+	return parent + treeNodeSeparator + name
+}
+
+// TreeNodeLabel returns the text to display for a tree node id.
+func TreeNodeLabel(uid string) string {
+	if i := strings.LastIndex(uid, treeNodeSeparator); i >= 0 && !strings.HasPrefix(uid, "File: ") {
+		return uid[i+len(treeNodeSeparator):]
+	}
+	return uid
+}
+
+// TreeNodeParent returns the id of the parent of a nested tree node, or ""
+// for top level nodes.
+func TreeNodeParent(uid string) string {
+	if i := strings.LastIndex(uid, treeNodeSeparator); i >= 0 && !strings.HasPrefix(uid, "File: ") {
+		return uid[:i]
+	}
+	return ""
+}
+
+func GetDataDirectories(h any) ([]pe.DataDirectory, error) {
+	switch header := h.(type) {
+	case *pe.OptionalHeader64:
+		return header.DataDirectory[:], nil
+	case *pe.OptionalHeader32:
+		return header.DataDirectory[:], nil
+	default:
+		return nil, fmt.Errorf("unknown header type")
+	}
+}
+
+// GetOptionalHeader returns the PE32 or PE32+ optional header. The format
+// follows the Magic field rather than the machine type, so images for any
+// architecture are handled.
+func GetOptionalHeader(peFile *pe.File) (any, error) {
+	switch hdr := peFile.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if hdr.Magic == IMAGE_NT_OPTIONAL_HDR32_MAGIC {
+			return hdr, nil
+		}
+		return nil, fmt.Errorf("unexpected optional header magic: 0x%x", hdr.Magic)
+	case *pe.OptionalHeader64:
+		if hdr.Magic == IMAGE_NT_OPTIONAL_HDR64_MAGIC {
+			return hdr, nil
+		}
+		return nil, fmt.Errorf("unexpected optional header magic: 0x%x", hdr.Magic)
+	default:
+		return nil, fmt.Errorf("missing optional header for Machine type: %s", MachineName(peFile.FileHeader.Machine))
+	}
+}
+
+// GetPeTreeMap builds the node hierarchy shown in the left pane. The map is
+// keyed by node id and holds the ids of each node's children; the "" key
+// holds the root node.
+func GetPeTreeMap(peFull *PeFull, filePath string) map[string][]string {
+	data := map[string][]string{}
+	root := "File: " + filepath.Base(filePath)
+	data[""] = []string{root}
+	data[root] = []string{"Dos Header", "Nt Headers", "Section Headers"}
+	data["Nt Headers"] = []string{"File Header", "Optional Header"}
+	data["Optional Header"] = []string{"Data Directories"}
+
+	if _, err := peFull.RichHeader(); err == nil {
+		data["Dos Header"] = []string{"Rich Header"}
+	}
+
+	for _, dir := range peFull.Directories {
+		if dir.Index < len(DirectoryNames) && dir.Present() {
+			data[root] = append(data[root], dir.Name)
+		}
+	}
+
+	if imports, _ := peFull.ImportTable(); len(imports) > 0 {
+		dllNames := []string{}
+		for _, descriptor := range imports {
+			// A descriptor whose name can't be read is labelled by its offset
+			name := descriptor.DllName
+			if name == "" {
+				name = fmt.Sprintf("0x%X", descriptor.Offset)
+			}
+			dllNames = append(dllNames, name)
+		}
+		importNodes := []string{}
+		for i := range imports {
+			importNodes = append(importNodes, TreeNodeID("Import Table", importNodeName(dllNames, i)))
+		}
+		data["Import Table"] = importNodes
+	}
+
+	if delayImports, _ := peFull.DelayImportTable(); len(delayImports) > 0 {
+		dllNames := []string{}
+		for _, descriptor := range delayImports {
+			// A descriptor whose name can't be read is labelled by its offset
+			name := descriptor.DllName
+			if name == "" {
+				name = fmt.Sprintf("0x%X", descriptor.Offset)
+			}
+			dllNames = append(dllNames, name)
+		}
+		delayNodes := []string{}
+		for i := range delayImports {
+			delayNodes = append(delayNodes, TreeNodeID("Delay Import Descriptor", importNodeName(dllNames, i)))
+		}
+		data["Delay Import Descriptor"] = delayNodes
+	}
+
+	if metadata, _ := peFull.Metadata(); metadata != nil {
+		metadataNode := TreeNodeID("CLR Runtime Header", "Metadata")
+		data["CLR Runtime Header"] = []string{metadataNode}
+		for _, stream := range metadata.Streams {
+			streamNode := TreeNodeID(metadataNode, stream.Name)
+			data[metadataNode] = append(data[metadataNode], streamNode)
+			if (stream.Name == "#~" || stream.Name == "#-") && metadata.Tables != nil {
+				for _, table := range metadata.Tables.Tables {
+					data[streamNode] = append(data[streamNode], TreeNodeID(streamNode, table.Name))
+				}
+			}
+		}
+	}
+
+	if signatures, _ := peFull.Signatures(); len(signatures) > 0 {
+		signatureNodes := []string{}
+		seen := map[string]bool{}
+		for i, signature := range signatures {
+			name := signature.FileDigestAlgorithm + " signature"
+			if seen[name] {
+				name = fmt.Sprintf("%s #%d", name, i)
+			}
+			seen[signature.FileDigestAlgorithm+" signature"] = true
+			signatureNodes = append(signatureNodes, TreeNodeID("Certificate Table", name))
+		}
+		data["Certificate Table"] = signatureNodes
+	}
+
+	if entries, _ := peFull.DebugDirectory(); len(entries) > 0 {
+		debugNodes := []string{}
+		seen := map[string]bool{}
+		for i, entry := range entries {
+			name := entry.TypeName
+			if seen[name] {
+				name = fmt.Sprintf("%s #%d", name, i)
+			}
+			seen[entry.TypeName] = true
+			debugNodes = append(debugNodes, TreeNodeID("Debug", name))
+		}
+		data["Debug"] = debugNodes
+	}
+
+	if config, err := peFull.LoadConfig(); err == nil && len(config.Tables) > 0 {
+		tableNodes := []string{}
+		for _, table := range config.Tables {
+			tableNodes = append(tableNodes, TreeNodeID("Load Config Table", table.Name))
+		}
+		data["Load Config Table"] = tableNodes
+	}
+
+	// A damaged resource tree is still shown up to the first bad entry
+	if resources, _ := peFull.ResourceTable(); resources != nil {
+		addResourceTreeNodes(data, "Resource Table", resources)
+	}
+
+	return data
+}
+
+// importNodeName returns the label of an import descriptor node. A DLL that
+// is imported by more than one descriptor gets its index appended.
+func importNodeName(dllNames []string, index int) string {
+	dllName := dllNames[index]
+	for i, name := range dllNames {
+		if i != index && strings.EqualFold(name, dllName) {
+			return fmt.Sprintf("%s #%d", dllName, index)
+		}
+	}
+	return dllName
+}
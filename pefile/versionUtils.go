@@ -0,0 +1,258 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+)
+
+type VS_FIXEDFILEINFO struct {
+	Signature        uint32
+	StrucVersion     uint32
+	FileVersionMS    uint32
+	FileVersionLS    uint32
+	ProductVersionMS uint32
+	ProductVersionLS uint32
+	FileFlagsMask    uint32
+	FileFlags        uint32
+	FileOS           uint32
+	FileType         uint32
+	FileSubtype      uint32
+	FileDateMS       uint32
+	FileDateLS       uint32
+}
+
+const vsFixedFileInfoSignature = 0xFEEF04BD
+
+// VersionString is a single key/value pair of a StringTable.
+type VersionString struct {
+	Key   string
+	Value string
+}
+
+// VersionStringTable is a StringTable block, keyed by its language and code
+// page (e.g. "040904B0").
+type VersionStringTable struct {
+	Key      string
+	Language uint16
+	CodePage uint16
+	Strings  []VersionString
+}
+
+// VersionTranslation is a language and code page pair listed in
+// VarFileInfo\Translation.
+type VersionTranslation struct {
+	Language uint16
+	CodePage uint16
+}
+
+// VersionInfo is a decoded VS_VERSIONINFO resource.
+type VersionInfo struct {
+	Offset        uint32            // file offset of the resource data
+	FixedFileInfo *VS_FIXEDFILEINFO // nil if the resource has none
+	StringTables  []VersionStringTable
+	Translations  []VersionTranslation
+}
+
+// versionBlock is the generic header shared by every node of a
+// VS_VERSIONINFO tree, followed by its key, value and children.
+type versionBlock struct {
+	key      string
+	isText   bool
+	value    []byte
+	children []versionBlock
+}
+
+// VersionInfo decodes the first RT_VERSION resource of the image.
+func (p *PeFull) VersionInfo() (*VersionInfo, error) {
+	resources, err := p.ResourceTable()
+	if resources == nil {
+		return nil, err
+	}
+
+	data := resources.FindFirst(RT_VERSION)
+	if data == nil {
+		return nil, fmt.Errorf("no version information")
+	}
+
+	raw, err := p.ResourceBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := ParseVersionInfo(raw)
+	if err != nil {
+		return nil, err
+	}
+	info.Offset = data.DataOffset
+	return info, nil
+}
+
+// ParseVersionInfo decodes the raw bytes of an RT_VERSION resource.
+func ParseVersionInfo(raw []byte) (*VersionInfo, error) {
+	root, _, err := readVersionBlock(raw, 0)
+	if err != nil {
+		return nil, err
+	}
+	if root.key != "VS_VERSION_INFO" {
+		return nil, fmt.Errorf("unexpected version resource key %q", root.key)
+	}
+
+	info := &VersionInfo{}
+	if len(root.value) >= binary.Size(VS_FIXEDFILEINFO{}) {
+		var fixed VS_FIXEDFILEINFO
+		if err := binary.Read(bytes.NewReader(root.value), binary.LittleEndian, &fixed); err != nil {
+			return nil, err
+		}
+		if fixed.Signature == vsFixedFileInfoSignature {
+			info.FixedFileInfo = &fixed
+		}
+	}
+
+	for _, child := range root.children {
+		switch child.key {
+		case "StringFileInfo":
+			for _, table := range child.children {
+				stringTable := VersionStringTable{Key: table.key}
+				if langAndCodePage, err := strconv.ParseUint(table.key, 16, 32); err == nil {
+					stringTable.Language = uint16(langAndCodePage >> 16)
+					stringTable.CodePage = uint16(langAndCodePage)
+				}
+				for _, str := range table.children {
+					stringTable.Strings = append(stringTable.Strings, VersionString{
+						Key:   str.key,
+						Value: decodeVersionText(str.value),
+					})
+				}
+				info.StringTables = append(info.StringTables, stringTable)
+			}
+		case "VarFileInfo":
+			for _, v := range child.children {
+				if v.key != "Translation" {
+					continue
+				}
+				for i := 0; i+4 <= len(v.value); i += 4 {
+					info.Translations = append(info.Translations, VersionTranslation{
+						Language: binary.LittleEndian.Uint16(v.value[i:]),
+						CodePage: binary.LittleEndian.Uint16(v.value[i+2:]),
+					})
+				}
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// readVersionBlock reads the block at offset and returns it together with
+// the offset of the next sibling.
+func readVersionBlock(raw []byte, offset int) (versionBlock, int, error) {
+	var block versionBlock
+	if offset+6 > len(raw) {
+		return block, 0, fmt.Errorf("version block at 0x%X out of bounds", offset)
+	}
+
+	length := int(binary.LittleEndian.Uint16(raw[offset:]))
+	valueLength := int(binary.LittleEndian.Uint16(raw[offset+2:]))
+	block.isText = binary.LittleEndian.Uint16(raw[offset+4:]) == 1
+	end := offset + length
+	if length < 6 || end > len(raw) {
+		return block, 0, fmt.Errorf("bad version block length %d at 0x%X", length, offset)
+	}
+
+	// szKey is a null terminated UTF-16 string
+	pos := offset + 6
+	var key []uint16
+	for pos+2 <= end {
+		c := binary.LittleEndian.Uint16(raw[pos:])
+		pos += 2
+		if c == 0 {
+			break
+		}
+		key = append(key, c)
+	}
+	block.key = string(utf16.Decode(key))
+	pos = alignDword(pos)
+
+	// Text values are measured in WORDs, binary ones in bytes
+	if block.isText {
+		valueLength *= 2
+	}
+	if pos+valueLength > end {
+		valueLength = max(end-pos, 0)
+	}
+	block.value = raw[min(pos, end) : min(pos, end)+valueLength]
+	pos = alignDword(pos + valueLength)
+
+	for pos+6 <= end {
+		// Some linkers pad the last child with zeroes
+		if binary.LittleEndian.Uint16(raw[pos:]) == 0 {
+			break
+		}
+		child, next, err := readVersionBlock(raw[:end], pos)
+		if err != nil {
+			return block, 0, err
+		}
+		block.children = append(block.children, child)
+		if next <= pos {
+			break
+		}
+		pos = next
+	}
+
+	return block, alignDword(end), nil
+}
+
+func alignDword(offset int) int {
+	return (offset + 3) &^ 3
+}
+
+// decodeVersionText decodes a UTF-16 value up to its null terminator.
+func decodeVersionText(value []byte) string {
+	var units []uint16
+	for i := 0; i+2 <= len(value); i += 2 {
+		c := binary.LittleEndian.Uint16(value[i:])
+		if c == 0 {
+			break
+		}
+		units = append(units, c)
+	}
+	return string(utf16.Decode(units))
+}
+
+// Get returns the value of a StringFileInfo key, looking at the tables that
+// match a declared translation first.
+func (v *VersionInfo) Get(key string) string {
+	for _, translation := range v.Translations {
+		for _, table := range v.StringTables {
+			if table.Language != translation.Language || table.CodePage != translation.CodePage {
+				continue
+			}
+			for _, str := range table.Strings {
+				if str.Key == key && str.Value != "" {
+					return str.Value
+				}
+			}
+		}
+	}
+	for _, table := range v.StringTables {
+		for _, str := range table.Strings {
+			if str.Key == key && str.Value != "" {
+				return str.Value
+			}
+		}
+	}
+	return ""
+}
+
+// FileVersion formats the binary file version as "major.minor.build.private".
+func (f *VS_FIXEDFILEINFO) FileVersion() string {
+	return fmt.Sprintf("%d.%d.%d.%d", f.FileVersionMS>>16, f.FileVersionMS&0xFFFF, f.FileVersionLS>>16, f.FileVersionLS&0xFFFF)
+}
+
+// ProductVersion formats the binary product version the same way.
+func (f *VS_FIXEDFILEINFO) ProductVersion() string {
+	return fmt.Sprintf("%d.%d.%d.%d", f.ProductVersionMS>>16, f.ProductVersionMS&0xFFFF, f.ProductVersionLS>>16, f.ProductVersionLS&0xFFFF)
+}
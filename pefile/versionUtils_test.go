@@ -0,0 +1,172 @@
+package pefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// versionNode encodes a VS_VERSIONINFO block. Text values are given as
+// strings and stored null terminated.
+func versionNode(key string, value any, children ...[]byte) []byte {
+	valueBuf := new(bytes.Buffer)
+	valueLength, isText := 0, uint16(0)
+	switch v := value.(type) {
+	case string:
+		units := append(utf16.Encode([]rune(v)), 0)
+		binary.Write(valueBuf, binary.LittleEndian, units)
+		valueLength, isText = len(units), 1
+	case nil:
+	default:
+		binary.Write(valueBuf, binary.LittleEndian, v)
+		valueLength = valueBuf.Len()
+	}
+
+	d := newTestData(0)
+	d.putStruct([]uint16{0, uint16(valueLength), isText})
+	d.putStruct(append(utf16.Encode([]rune(key)), 0))
+	d.align(4)
+	d.put(valueBuf.Bytes())
+	for _, child := range children {
+		d.align(4)
+		d.put(child)
+	}
+	node := d.bytes()
+	binary.LittleEndian.PutUint16(node, uint16(len(node)))
+	return node
+}
+
+func testVersionInfo() []byte {
+	fixed := VS_FIXEDFILEINFO{
+		Signature:        vsFixedFileInfoSignature,
+		StrucVersion:     0x10000,
+		FileVersionMS:    1<<16 | 2,
+		FileVersionLS:    3<<16 | 4,
+		ProductVersionMS: 5 << 16,
+		ProductVersionLS: 6,
+	}
+	return versionNode("VS_VERSION_INFO", fixed,
+		versionNode("StringFileInfo", nil,
+			versionNode("000004B0", nil,
+				versionNode("FileDescription", "Fallback"),
+				versionNode("InternalName", "neutral"),
+			),
+			versionNode("040904B0", nil,
+				versionNode("CompanyName", "Acme Ünicode"),
+				versionNode("FileDescription", ""),
+				versionNode("InternalName", "english"),
+			),
+		),
+		versionNode("VarFileInfo", nil,
+			versionNode("Translation", []uint16{0x0409, 0x04B0}),
+		),
+	)
+}
+
+func TestParseVersionInfo(t *testing.T) {
+	info, err := ParseVersionInfo(testVersionInfo())
+	if err != nil {
+		t.Fatalf("ParseVersionInfo: %v", err)
+	}
+
+	if info.FixedFileInfo == nil {
+		t.Fatal("no VS_FIXEDFILEINFO")
+	}
+	if got := info.FixedFileInfo.FileVersion(); got != "1.2.3.4" {
+		t.Errorf("FileVersion = %s", got)
+	}
+	if got := info.FixedFileInfo.ProductVersion(); got != "5.0.0.6" {
+		t.Errorf("ProductVersion = %s", got)
+	}
+
+	if len(info.StringTables) != 2 || len(info.StringTables[1].Strings) != 3 {
+		t.Fatalf("string tables = %+v", info.StringTables)
+	}
+	if table := info.StringTables[1]; table.Key != "040904B0" || table.Language != 0x0409 || table.CodePage != 0x04B0 {
+		t.Errorf("string table = %s %X %X", table.Key, table.Language, table.CodePage)
+	}
+	if len(info.Translations) != 1 || info.Translations[0] != (VersionTranslation{0x0409, 0x04B0}) {
+		t.Errorf("translations = %+v", info.Translations)
+	}
+
+	tests := []struct {
+		key, want string
+	}{
+		{"CompanyName", "Acme Ünicode"},
+		// The translated table comes first, but empty values are skipped
+		{"InternalName", "english"},
+		{"FileDescription", "Fallback"},
+		{"Missing", ""},
+	}
+	for _, tt := range tests {
+		if got := info.Get(tt.key); got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+// Blocks may be followed by zero padding up to the parent's length.
+func TestParseVersionInfoPadding(t *testing.T) {
+	raw := versionNode("VS_VERSION_INFO", nil, versionNode("VarFileInfo", nil), make([]byte, 8))
+	info, err := ParseVersionInfo(raw)
+	if err != nil || info.FixedFileInfo != nil {
+		t.Errorf("ParseVersionInfo = %+v, %v", info, err)
+	}
+}
+
+func TestParseVersionInfoCorrupt(t *testing.T) {
+	wrongKey := versionNode("VS_VERSION", nil)
+
+	// A child whose length runs past its parent
+	overrun := testVersionInfo()
+	stringFileInfo := 6 + 2*len("VS_VERSION_INFO\x00") + 2 + binary.Size(VS_FIXEDFILEINFO{})
+	binary.LittleEndian.PutUint16(overrun[stringFileInfo:], 0x7FFF)
+
+	// A block shorter than its own header
+	tooShort := testVersionInfo()
+	binary.LittleEndian.PutUint16(tooShort[stringFileInfo:], 2)
+
+	tests := []struct {
+		name string
+		raw  []byte
+		err  string
+	}{
+		{"empty", nil, "out of bounds"},
+		{"wrong key", wrongKey, "unexpected"},
+		{"truncated", testVersionInfo()[:100], "length"},
+		{"child past its parent", overrun, "length"},
+		{"block shorter than its header", tooShort, "length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ParseVersionInfo(tt.raw)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseVersionInfo = %+v, %v, want an error containing %q", info, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVersionInfo(t *testing.T) {
+	rsrc := resourceSection(testSectionRVA(0), []testResource{
+		{typ: RT_VERSION, id: 1, lang: 0x409, data: testVersionInfo()},
+	})
+	p := testImage{
+		sections: []testSection{{name: ".rsrc", data: rsrc, characteristics: 0x40000040}},
+		dirs:     map[int]DataDirectory{2: {VirtualAddress: testSectionRVA(0), Size: uint32(len(rsrc))}},
+	}.parse(t)
+
+	info, err := p.VersionInfo()
+	if err != nil {
+		t.Fatalf("VersionInfo: %v", err)
+	}
+	if info.Offset == 0 || info.Get("CompanyName") != "Acme Ünicode" {
+		t.Errorf("VersionInfo = %+v", info)
+	}
+
+	if _, err := resourceTestImage().parse(t).VersionInfo(); err == nil {
+		t.Error("VersionInfo of an image without RT_VERSION succeeded")
+	}
+}
@@ -0,0 +1,477 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"PEGo/pefile"
+)
+
+// The dump* types define the JSON schema of "pego dump". Field names are part
+// of the output format, so they must not be renamed.
+
+type dumpField struct {
+	Offset uint32  `json:"offset"`
+	Name   string  `json:"name"`
+	Value  any     `json:"value"`
+	Size   uintptr `json:"size"`
+}
+
+type dumpHeader struct {
+	Offset uint32      `json:"offset"`
+	Fields []dumpField `json:"fields"`
+}
+
+type dumpDirectory struct {
+	Index          int    `json:"index"`
+	Name           string `json:"name"`
+	Offset         uint32 `json:"offset"`
+	VirtualAddress uint32 `json:"virtualAddress"`
+	Size           uint32 `json:"size"`
+}
+
+type dumpSection struct {
+	Offset               uint32 `json:"offset"`
+	Name                 string `json:"name"`
+	VirtualSize          uint32 `json:"virtualSize"`
+	VirtualAddress       uint32 `json:"virtualAddress"`
+	SizeOfRawData        uint32 `json:"sizeOfRawData"`
+	PointerToRawData     uint32 `json:"pointerToRawData"`
+	PointerToRelocations uint32 `json:"pointerToRelocations"`
+	NumberOfRelocations  uint16 `json:"numberOfRelocations"`
+	PointerToLineNumbers uint32 `json:"pointerToLineNumbers"`
+	NumberOfLineNumbers  uint16 `json:"numberOfLineNumbers"`
+	Characteristics      uint32 `json:"characteristics"`
+}
+
+type dumpExport struct {
+	Offset      uint32 `json:"offset"`
+	Ordinal     uint32 `json:"ordinal"`
+	FunctionRVA uint32 `json:"functionRva"`
+	NameRVA     uint32 `json:"nameRva,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+type dumpExports struct {
+	DllName   string       `json:"dllName"`
+	Directory dumpHeader   `json:"directory"`
+	Functions []dumpExport `json:"functions"`
+	Error     string       `json:"error,omitempty"`
+}
+
+type dumpSignature struct {
+	Offset               uint32 `json:"offset"`
+	Algorithm            string `json:"algorithm"`
+	Signer               string `json:"signer,omitempty"`
+	ComputedDigest       string `json:"computedDigest"`
+	DigestMatches        bool   `json:"digestMatches"`
+	MessageDigestMatches bool   `json:"messageDigestMatches"`
+	SignatureError       string `json:"signatureError,omitempty"`
+	ChainError           string `json:"chainError,omitempty"`
+	Timestamp            string `json:"timestamp,omitempty"`
+	TimestampError       string `json:"timestampError,omitempty"`
+	Verdict              string `json:"verdict"`
+}
+
+type dumpAuthenticode struct {
+	Verdict    string          `json:"verdict"`
+	Signatures []dumpSignature `json:"signatures"`
+}
+
+type dumpCheckSum struct {
+	Stored   uint32 `json:"stored"`
+	Computed uint32 `json:"computed"`
+	Matches  bool   `json:"matches"`
+}
+
+type dumpReport struct {
+	File            string           `json:"file"`
+	Machine         string           `json:"machine"`
+	DosHeader       dumpHeader       `json:"dosHeader"`
+	NtHeaders       dumpHeader       `json:"ntHeaders"`
+	FileHeader      dumpHeader       `json:"fileHeader"`
+	OptionalHeader  dumpHeader       `json:"optionalHeader"`
+	DataDirectories []dumpDirectory  `json:"dataDirectories"`
+	SectionHeaders  []dumpSection    `json:"sectionHeaders"`
+	Exports         *dumpExports     `json:"exports,omitempty"`
+	CheckSum        dumpCheckSum     `json:"checkSum"`
+	Authenticode    dumpAuthenticode `json:"authenticode"`
+}
+
+// dumpFieldNames pins the names header fields are dumped under, in field
+// order, so that renaming a Go field does not change the output.
+var dumpFieldNames = map[reflect.Type][]string{
+	reflect.TypeOf(pefile.DOSHeader{}): {
+		"E_magic", "E_cblp", "E_cp", "E_crlc", "E_cparhdr", "E_minalloc", "E_maxalloc", "E_ss", "E_sp", "E_csum",
+		"E_ip", "E_cs", "E_lfarlc", "E_ovno", "E_res", "E_oemid", "E_oeminfo", "E_res2", "E_ifanew",
+	},
+	reflect.TypeOf(pefile.NtHeaders{}): {"Signature"},
+	reflect.TypeOf(pefile.FileHeader{}): {
+		"Machine", "NumberOfSections", "TimeDateStamp", "PointerToSymbolTable", "NumberOfSymbols",
+		"SizeOfOptionalHeader", "Characteristics",
+	},
+	reflect.TypeOf(pefile.OptionalHeader32{}): {
+		"Magic", "MajorLinkerVersion", "MinorLinkerVersion", "SizeOfCode", "SizeOfInitializedData",
+		"SizeOfUninitializedData", "AddressOfEntryPoint", "BaseOfCode", "BaseOfData", "ImageBase",
+		"SectionAlignment", "FileAlignment", "MajorOperatingSystemVersion", "MinorOperatingSystemVersion",
+		"MajorImageVersion", "MinorImageVersion", "MajorSubsystemVersion", "MinorSubsystemVersion",
+		"Win32VersionValue", "SizeOfImage", "SizeOfHeaders", "CheckSum", "Subsystem", "DllCharacteristics",
+		"SizeOfStackReserve", "SizeOfStackCommit", "SizeOfHeapReserve", "SizeOfHeapCommit", "LoaderFlags",
+		"NumberOfRvaAndSizes", "DataDirectory",
+	},
+	reflect.TypeOf(pefile.OptionalHeader64{}): {
+		"Magic", "MajorLinkerVersion", "MinorLinkerVersion", "SizeOfCode", "SizeOfInitializedData",
+		"SizeOfUninitializedData", "AddressOfEntryPoint", "BaseOfCode", "ImageBase",
+		"SectionAlignment", "FileAlignment", "MajorOperatingSystemVersion", "MinorOperatingSystemVersion",
+		"MajorImageVersion", "MinorImageVersion", "MajorSubsystemVersion", "MinorSubsystemVersion",
+		"Win32VersionValue", "SizeOfImage", "SizeOfHeaders", "CheckSum", "Subsystem", "DllCharacteristics",
+		"SizeOfStackReserve", "SizeOfStackCommit", "SizeOfHeapReserve", "SizeOfHeapCommit", "LoaderFlags",
+		"NumberOfRvaAndSizes", "DataDirectory",
+	},
+	reflect.TypeOf(pefile.IMAGE_EXPORT_DIRECTORY{}): {
+		"Characteristics", "TimeDateStamp", "MajorVersion", "MinorVersion", "Name", "Base",
+		"NumberOfFunctions", "NumberOfNames", "AddressOfFunctions", "AddressOfNames", "AddressOfNameOrdinals",
+	},
+}
+
+// runDump implements "pego dump <file> [--format json|text] [--trust-anchors
+// file] [--verify-time time]" and returns the process exit code.
+func runDump(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "output format: json or text")
+	anchorsPath := flags.String("trust-anchors", "", "PEM or DER file of root certificates to verify signatures against")
+	verifyTime := flags.String("verify-time", "", "RFC 3339 time to check signatures without a trusted timestamp at; by default their chain is not checked, so the output only depends on the file")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: pego dump <file> [--format json|text] [--trust-anchors file] [--verify-time time]")
+		flags.PrintDefaults()
+	}
+
+	// Accept the flags both before and after the file name
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return 2
+	}
+	filePath := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	var at time.Time
+	if *verifyTime != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, *verifyTime); err != nil {
+			fmt.Fprintf(stderr, "Error parsing --verify-time: %v\n", err)
+			return 2
+		}
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error opening file: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+
+	peFull, err := pefile.Parse(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error parsing file: %v\n", err)
+		return 1
+	}
+
+	var roots *x509.CertPool
+	if *anchorsPath != "" {
+		roots, err = pefile.LoadTrustAnchors(*anchorsPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error loading trust anchors: %v\n", err)
+			return 1
+		}
+	}
+
+	report, err := buildDumpReport(peFull, filePath, roots, at)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error parsing file: %v\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	case "text":
+		err = writeDumpText(stdout, report)
+	default:
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error writing output: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// buildDumpReport collects what "pego dump" prints. Signatures without a
+// trusted timestamp are checked at the given time, or not at all when it is
+// zero.
+func buildDumpReport(peFull *pefile.PeFull, filePath string, roots *x509.CertPool, at time.Time) (*dumpReport, error) {
+	optHeader, err := pefile.GetOptionalHeader(peFull.PeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dumpReport{
+		File:    filepath.Base(filePath),
+		Machine: pefile.MachineName(peFull.PeFile.FileHeader.Machine),
+	}
+	headers := []struct {
+		dump   *dumpHeader
+		header any
+		offset uint32
+	}{
+		{&report.DosHeader, peFull.Dos, 0},
+		{&report.NtHeaders, peFull.Nt, peFull.NtHeadersOffset()},
+		{&report.FileHeader, &peFull.PeFile.FileHeader, peFull.FileHeaderOffset()},
+		{&report.OptionalHeader, optHeader, peFull.OptionalHeaderOffset()},
+	}
+	for _, h := range headers {
+		if *h.dump, err = dumpStruct(h.header, h.offset); err != nil {
+			return nil, err
+		}
+	}
+
+	// The data directories are listed separately
+	fields := report.OptionalHeader.Fields
+	if len(fields) > 0 && fields[len(fields)-1].Name == "DataDirectory" {
+		report.OptionalHeader.Fields = fields[:len(fields)-1]
+	}
+
+	for _, dir := range peFull.Directories {
+		report.DataDirectories = append(report.DataDirectories, dumpDirectory{
+			Index:          dir.Index,
+			Name:           dir.Name,
+			Offset:         dir.Offset,
+			VirtualAddress: dir.VirtualAddress,
+			Size:           dir.Size,
+		})
+	}
+
+	offset := peFull.SectionHeadersOffset()
+	for _, section := range peFull.PeFile.Sections {
+		header := section.SectionHeader
+		report.SectionHeaders = append(report.SectionHeaders, dumpSection{
+			Offset:               offset,
+			Name:                 header.Name,
+			VirtualSize:          header.VirtualSize,
+			VirtualAddress:       header.VirtualAddress,
+			SizeOfRawData:        header.Size,
+			PointerToRawData:     header.Offset,
+			PointerToRelocations: header.PointerToRelocations,
+			NumberOfRelocations:  header.NumberOfRelocations,
+			PointerToLineNumbers: header.PointerToLineNumbers,
+			NumberOfLineNumbers:  header.NumberOfLineNumbers,
+			Characteristics:      header.Characteristics,
+		})
+		offset += 0x28
+	}
+
+	if exportDir, ok := peFull.Directory("Export Table"); ok && exportDir.Present() {
+		// A broken export table is reported rather than failing the dump
+		exports, err := peFull.ExportTable()
+		if err != nil {
+			report.Exports = &dumpExports{
+				Directory: dumpHeader{Fields: []dumpField{}},
+				Functions: []dumpExport{},
+				Error:     err.Error(),
+			}
+		} else {
+			directory, err := dumpStruct(exports.Header, exports.Offset)
+			if err != nil {
+				return nil, err
+			}
+			report.Exports = &dumpExports{
+				DllName:   exports.DllName,
+				Directory: directory,
+				Functions: []dumpExport{},
+			}
+			for _, function := range exports.Functions {
+				report.Exports.Functions = append(report.Exports.Functions, dumpExport{
+					Offset:      function.Offset,
+					Ordinal:     function.Ordinal,
+					FunctionRVA: function.FunctionRVA,
+					NameRVA:     function.NameRVA,
+					Name:        function.Name,
+				})
+			}
+		}
+	}
+
+	report.CheckSum = dumpCheckSum{
+		Stored:   peFull.StoredCheckSum(),
+		Computed: peFull.ComputeCheckSum(),
+		Matches:  peFull.CheckSumMatches(),
+	}
+
+	results, _ := peFull.VerifyAuthenticodeAt(roots, at)
+	report.Authenticode = dumpAuthenticode{
+		Verdict:    pefile.AuthenticodeVerdict(results),
+		Signatures: []dumpSignature{},
+	}
+	for _, result := range results {
+		signature := dumpSignature{
+			Offset:               result.Signature.Offset,
+			Algorithm:            result.Signature.FileDigestAlgorithm,
+			ComputedDigest:       fmt.Sprintf("%X", result.ComputedDigest),
+			DigestMatches:        result.DigestMatches,
+			MessageDigestMatches: result.MessageDigestMatches,
+			Verdict:              result.Verdict,
+		}
+		if len(result.Signature.Signers) > 0 && result.Signature.Signers[0].Certificate != nil {
+			signature.Signer = result.Signature.Signers[0].Certificate.Subject.String()
+		}
+		if result.SignatureErr != nil {
+			signature.SignatureError = result.SignatureErr.Error()
+		}
+		if result.ChainErr != nil {
+			signature.ChainError = result.ChainErr.Error()
+		}
+		if !result.Timestamp.IsZero() {
+			signature.Timestamp = result.Timestamp.UTC().Format(time.RFC3339)
+		}
+		if result.TimestampErr != nil {
+			signature.TimestampError = result.TimestampErr.Error()
+		}
+		report.Authenticode.Signatures = append(report.Authenticode.Signatures, signature)
+	}
+
+	return report, nil
+}
+
+// dumpStruct flattens a header struct into its fields, the same way
+// createTableFromStruct lays it out in the GUI, named after dumpFieldNames.
+func dumpStruct(header any, offset uint32) (dumpHeader, error) {
+	v := reflect.Indirect(reflect.ValueOf(header))
+	t := v.Type()
+	names, ok := dumpFieldNames[t]
+	if !ok || len(names) != t.NumField() {
+		return dumpHeader{}, fmt.Errorf("no dump field names for %s", t)
+	}
+
+	dump := dumpHeader{Offset: offset, Fields: []dumpField{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		dump.Fields = append(dump.Fields, dumpField{
+			Offset: offset,
+			Name:   names[i],
+			Value:  v.Field(i).Interface(),
+			Size:   field.Type.Size(),
+		})
+		offset += uint32(field.Type.Size())
+	}
+	return dump, nil
+}
+
+func formatDumpValue(value any) string {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Array {
+		var parts []string
+		for j := 0; j < v.Len(); j++ {
+			parts = append(parts, fmt.Sprintf("%#x", v.Index(j).Interface()))
+		}
+		return strings.Join(parts, " ")
+	}
+	return fmt.Sprintf("%#x", value)
+}
+
+func writeDumpText(w io.Writer, report *dumpReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "File: %s\n", report.File)
+	fmt.Fprintf(tw, "Machine: %s\n", report.Machine)
+	checkSumStatus := "match"
+	if report.CheckSum.Stored == 0 {
+		checkSumStatus = "not set"
+	} else if !report.CheckSum.Matches {
+		checkSumStatus = "mismatch"
+	}
+	fmt.Fprintf(tw, "CheckSum: 0x%08X (computed 0x%08X, %s)\n", report.CheckSum.Stored, report.CheckSum.Computed, checkSumStatus)
+	fmt.Fprintf(tw, "Authenticode: %s\n", report.Authenticode.Verdict)
+
+	headers := []struct {
+		title  string
+		header dumpHeader
+	}{
+		{"Dos Header", report.DosHeader},
+		{"Nt Headers", report.NtHeaders},
+		{"File Header", report.FileHeader},
+		{"Optional Header", report.OptionalHeader},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(tw, "\n%s\n", h.title)
+		fmt.Fprintln(tw, "Offset\tField\tValue\tSize")
+		for _, field := range h.header.Fields {
+			fmt.Fprintf(tw, "0x%X\t%s\t%s\t%d\n", field.Offset, field.Name, formatDumpValue(field.Value), field.Size)
+		}
+	}
+
+	fmt.Fprintf(tw, "\nData Directories\n")
+	fmt.Fprintln(tw, "Offset\tDirectory\tRVA\tSize")
+	for _, dir := range report.DataDirectories {
+		fmt.Fprintf(tw, "0x%X\t%s\t0x%X\t%d\n", dir.Offset, dir.Name, dir.VirtualAddress, dir.Size)
+	}
+
+	fmt.Fprintf(tw, "\nSection Headers\n")
+	fmt.Fprintln(tw, "Offset\tName\tVirtual Size\tVirtual Address\tRaw Size\tRaw data *\tCharacteristics")
+	for _, s := range report.SectionHeaders {
+		fmt.Fprintf(tw, "0x%X\t%s\t0x%X\t0x%X\t%d\t0x%X\t0x%X\n",
+			s.Offset, s.Name, s.VirtualSize, s.VirtualAddress, s.SizeOfRawData, s.PointerToRawData, s.Characteristics)
+	}
+
+	if report.Exports != nil {
+		fmt.Fprintf(tw, "\nExport Table: %s\n", report.Exports.DllName)
+		if report.Exports.Error != "" {
+			fmt.Fprintf(tw, "Error: %s\n", report.Exports.Error)
+		}
+		fmt.Fprintln(tw, "Offset\tField\tValue\tSize")
+		for _, field := range report.Exports.Directory.Fields {
+			fmt.Fprintf(tw, "0x%X\t%s\t%s\t%d\n", field.Offset, field.Name, formatDumpValue(field.Value), field.Size)
+		}
+		fmt.Fprintln(tw, "\nOffset\tOrdinal\tFunction RVA\tName RVA\tName")
+		for _, function := range report.Exports.Functions {
+			nameRva := "N/A"
+			name := "N/A"
+			if function.NameRVA != 0 {
+				nameRva = fmt.Sprintf("0x%X", function.NameRVA)
+				name = function.Name
+			}
+			fmt.Fprintf(tw, "0x%X\t0x%X\t0x%X\t%s\t%s\n", function.Offset, function.Ordinal, function.FunctionRVA, nameRva, name)
+		}
+	}
+
+	if len(report.Authenticode.Signatures) > 0 {
+		fmt.Fprintf(tw, "\nSignatures\n")
+		fmt.Fprintln(tw, "Offset\tAlgorithm\tComputed Digest\tImage Digest\tMessage Digest\tVerdict\tSigner")
+		for _, s := range report.Authenticode.Signatures {
+			fmt.Fprintf(tw, "0x%X\t%s\t%s\t%t\t%t\t%s\t%s\n",
+				s.Offset, s.Algorithm, s.ComputedDigest, s.DigestMatches, s.MessageDigestMatches, s.Verdict, s.Signer)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"PEGo/pefile"
+)
+
+// dumpTestImage builds a small PE32+ image with a single section.
+func dumpTestImage() []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, pefile.DOSHeader{E_magic: 0x5A4D, E_ifanew: 0x80})
+	buf.Write(make([]byte, 0x80-buf.Len()))
+	binary.Write(buf, binary.LittleEndian, pefile.NtHeaders{Signature: 0x00004550})
+	binary.Write(buf, binary.LittleEndian, pefile.FileHeader{
+		Machine:              pefile.IMAGE_FILE_MACHINE_ARM64,
+		NumberOfSections:     1,
+		TimeDateStamp:        0x5F5E1000,
+		SizeOfOptionalHeader: uint16(binary.Size(pefile.OptionalHeader64{})),
+		Characteristics:      0x22,
+	})
+	binary.Write(buf, binary.LittleEndian, pefile.OptionalHeader64{
+		Magic:               pefile.IMAGE_NT_OPTIONAL_HDR64_MAGIC,
+		ImageBase:           0x140000000,
+		SectionAlignment:    0x1000,
+		FileAlignment:       0x200,
+		SizeOfImage:         0x2000,
+		SizeOfHeaders:       0x200,
+		NumberOfRvaAndSizes: 16,
+	})
+	// IMAGE_SECTION_HEADER
+	binary.Write(buf, binary.LittleEndian, struct {
+		Name                 [8]byte
+		VirtualSize          uint32
+		VirtualAddress       uint32
+		SizeOfRawData        uint32
+		PointerToRawData     uint32
+		PointerToRelocations uint32
+		PointerToLineNumbers uint32
+		NumberOfRelocations  uint16
+		NumberOfLineNumbers  uint16
+		Characteristics      uint32
+	}{
+		Name:             [8]byte{'.', 't', 'e', 'x', 't'},
+		VirtualSize:      0x200,
+		VirtualAddress:   0x1000,
+		SizeOfRawData:    0x200,
+		PointerToRawData: 0x200,
+		Characteristics:  0x60000020,
+	})
+	buf.Write(make([]byte, 0x400-buf.Len()))
+	return buf.Bytes()
+}
+
+// jsonKeys returns the sorted keys of a JSON object.
+func jsonKeys(t *testing.T, data []byte) []string {
+	t.Helper()
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	var keys []string
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// The JSON output is diffed between builds, so its keys must not change.
+func TestDumpReportKeys(t *testing.T) {
+	peFull, err := pefile.ParseBytes(dumpTestImage())
+	if err != nil {
+		t.Fatalf("ParseBytes: %v", err)
+	}
+	report, err := buildDumpReport(peFull, "test.exe", nil, time.Time{})
+	if err != nil {
+		t.Fatalf("buildDumpReport: %v", err)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var object struct {
+		FileHeader      json.RawMessage
+		DataDirectories []json.RawMessage
+		SectionHeaders  []json.RawMessage
+		CheckSum        json.RawMessage
+		Authenticode    json.RawMessage
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(object.DataDirectories) != 16 || len(object.SectionHeaders) != 1 {
+		t.Fatalf("%d data directories and %d section headers", len(object.DataDirectories), len(object.SectionHeaders))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{"report", data, []string{"authenticode", "checkSum", "dataDirectories", "dosHeader", "fileHeader", "file", "machine", "ntHeaders", "optionalHeader", "sectionHeaders"}},
+		{"header", object.FileHeader, []string{"fields", "offset"}},
+		{"data directory", object.DataDirectories[0], []string{"index", "name", "offset", "size", "virtualAddress"}},
+		{"section header", object.SectionHeaders[0], []string{"characteristics", "name", "numberOfLineNumbers", "numberOfRelocations", "offset",
+			"pointerToLineNumbers", "pointerToRawData", "pointerToRelocations", "sizeOfRawData", "virtualAddress", "virtualSize"}},
+		{"checksum", object.CheckSum, []string{"computed", "matches", "stored"}},
+		{"authenticode", object.Authenticode, []string{"signatures", "verdict"}},
+	}
+	for _, tt := range tests {
+		want := slices.Sorted(slices.Values(tt.want))
+		if got := jsonKeys(t, tt.data); !slices.Equal(got, want) {
+			t.Errorf("%s keys = %q, want %q", tt.name, got, want)
+		}
+	}
+
+	var fields []string
+	for _, field := range report.FileHeader.Fields {
+		fields = append(fields, field.Name)
+	}
+	want := []string{"Machine", "NumberOfSections", "TimeDateStamp", "PointerToSymbolTable", "NumberOfSymbols", "SizeOfOptionalHeader", "Characteristics"}
+	if !slices.Equal(fields, want) {
+		t.Errorf("file header fields = %q, want %q", fields, want)
+	}
+	if report.Machine != "ARM64" || report.Exports != nil || report.Authenticode.Verdict != pefile.AuthenticodeUnsigned {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+// Every header that is dumped has a name for each of its fields.
+func TestDumpFieldNames(t *testing.T) {
+	for typ := range dumpFieldNames {
+		dump, err := dumpStruct(reflect.New(typ).Interface(), 0x100)
+		if err != nil {
+			t.Errorf("dumpStruct(%s): %v", typ, err)
+			continue
+		}
+		last := dump.Fields[len(dump.Fields)-1]
+		if want := 0x100 + uint32(typ.Size()) - uint32(last.Size); last.Offset != want {
+			t.Errorf("%s: last field at 0x%X, want 0x%X", typ, last.Offset, want)
+		}
+	}
+	if _, err := dumpStruct(pefile.IMAGE_DEBUG_DIRECTORY{}, 0); err == nil {
+		t.Error("dumpStruct named the fields of a header it has no names for")
+	}
+}
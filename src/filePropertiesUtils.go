@@ -1,45 +1,37 @@
-//go:build windows
-
 package main
 
 import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
-	"debug/pe"
+	"crypto/x509"
 	"encoding/hex"
 	"fmt"
-	"os"
 	"reflect"
 	"time"
 
-	"golang.org/x/sys/windows"
+	"PEGo/pefile"
 )
 
-type FileResources struct {
-	CompanyName      string
-	ProductName      string
-	FileVersion      string
-	ProductVersion   string
-	OriginalFilename string
-	Copyright        string
-}
-
 type FileProperties struct {
-	FileName      string
-	FileType      string
-	FileSize      int64
-	CreationDate  windows.Filetime
-	ModifiedDate  windows.Filetime
-	AccessDate    windows.Filetime
-	Md5Hash       [16]byte
-	Sha1Hash      [20]byte
-	Sha256Hash    [32]byte
-	FileResources FileResources
+	FileName       string
+	FileType       string
+	FileSize       int64
+	CreationDate   time.Time
+	ModifiedDate   time.Time
+	AccessDate     time.Time
+	Md5Hash        [16]byte
+	Sha1Hash       [20]byte
+	Sha256Hash     [32]byte
+	Imphash        string
+	ExportHash     string
+	RichHeaderHash string
+	Authenticode   string
+	VersionInfo    *pefile.VersionInfo
 }
 
-func getFileType(peFull *PeFull) (string, error) {
-	optionalHdr, err := getOptionalHeader(peFull.peFile)
+func getFileType(peFull *pefile.PeFull) (string, error) {
+	optionalHdr, err := pefile.GetOptionalHeader(peFull.PeFile)
 	if err != nil {
 		return "", err
 	}
@@ -47,9 +39,9 @@ func getFileType(peFull *PeFull) (string, error) {
 	// Retrieve the Magic field from the optional header.
 	var magic uint16
 	switch hdr := optionalHdr.(type) {
-	case *pe.OptionalHeader32:
+	case *pefile.OptionalHeader32:
 		magic = hdr.Magic
-	case *pe.OptionalHeader64:
+	case *pefile.OptionalHeader64:
 		magic = hdr.Magic
 	default:
 		return "", fmt.Errorf("unexpected optional header type: %T", hdr)
@@ -58,34 +50,19 @@ func getFileType(peFull *PeFull) (string, error) {
 	// Determine the PE type based on the Magic value.
 	var fileType string
 	switch magic {
-	case 0x10b:
+	case pefile.IMAGE_NT_OPTIONAL_HDR32_MAGIC:
 		fileType = "PE32"
-	case 0x20b:
-		fileType = "PE32+ (x64)"
+	case pefile.IMAGE_NT_OPTIONAL_HDR64_MAGIC:
+		fileType = "PE32+"
 	default:
 		fileType = "Unknown PE type"
 	}
+	fileType += " (" + pefile.MachineName(peFull.PeFile.FileHeader.Machine) + ")"
 
 	return fileType, nil
 }
 
-func getFileTimes(filePath string) (windows.Filetime, windows.Filetime, windows.Filetime, error) {
-	var creation windows.Filetime
-	var access windows.Filetime
-	var modified windows.Filetime
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return creation, access, modified, err
-	}
-	defer file.Close()
-
-	h := windows.Handle(file.Fd())
-	err = windows.GetFileTime(h, &creation, &access, &modified)
-	return creation, access, modified, err
-}
-
-func getFileProperties(peFull *PeFull, filePath string) (FileProperties, error) {
+func getFileProperties(peFull *pefile.PeFull, filePath string, roots *x509.CertPool) (FileProperties, error) {
 	var fileProperties FileProperties
 	var err error
 	fileProperties.FileName = filePath
@@ -93,78 +70,74 @@ func getFileProperties(peFull *PeFull, filePath string) (FileProperties, error)
 	if err != nil {
 		return fileProperties, err
 	}
-	fileProperties.FileSize = int64(len(peFull.fileData))
+	fileProperties.FileSize = int64(len(peFull.FileData))
 	fileProperties.CreationDate, fileProperties.AccessDate, fileProperties.ModifiedDate, err = getFileTimes(filePath)
 	if err != nil {
 		return fileProperties, err
 	}
 
-	fileProperties.Md5Hash = md5.Sum(peFull.fileData)
-	fileProperties.Sha1Hash = sha1.Sum(peFull.fileData)
-	fileProperties.Sha256Hash = sha256.Sum256(peFull.fileData)
-
-	frc, err := NewFileResourceCollector(filePath)
-	if err != nil {
-		return fileProperties, err
+	fileProperties.Md5Hash = md5.Sum(peFull.FileData)
+	fileProperties.Sha1Hash = sha1.Sum(peFull.FileData)
+	fileProperties.Sha256Hash = sha256.Sum256(peFull.FileData)
+
+	// Hashes of parts the image may not have are shown as N/A
+	hashes := []struct {
+		value *string
+		hash  func() (string, error)
+	}{
+		{&fileProperties.Imphash, peFull.ImpHash},
+		{&fileProperties.ExportHash, peFull.ExportHash},
+		{&fileProperties.RichHeaderHash, peFull.RichHeaderHash},
+	}
+	for _, h := range hashes {
+		if *h.value, err = h.hash(); err != nil {
+			*h.value = "N/A"
+		}
 	}
 
-	fileProperties.FileResources.CompanyName = frc.GetResource("CompanyName")
-	fileProperties.FileResources.Copyright = frc.GetResource("LegalCopyright")
-	fileProperties.FileResources.ProductName = frc.GetResource("ProductName")
-	fileProperties.FileResources.OriginalFilename = frc.GetResource("OriginalFilename")
-	fileProperties.FileResources.FileVersion = frc.GetResource("FileVersion")
-	fileProperties.FileResources.ProductVersion = frc.GetResource("ProductVersion")
+	results, _ := peFull.VerifyAuthenticode(roots)
+	fileProperties.Authenticode = pefile.AuthenticodeVerdict(results)
 
-	return fileProperties, nil
-}
+	// Not every file carries version information
+	fileProperties.VersionInfo, _ = peFull.VersionInfo()
 
-func filetimeToTime(ft windows.Filetime) time.Time {
-	// Combine high and low parts (in 100-ns units)
-	ft100 := (int64(ft.HighDateTime) << 32) | int64(ft.LowDateTime)
-	const offset int64 = 116444736000000000
-	diff := ft100 - offset
-	sec := diff / 10000000
-	nsec := (diff % 10000000) * 100
-	return time.Unix(sec, nsec)
+	return fileProperties, nil
 }
 
-func createTableForResources(fileResources FileResources) (*sortableTable, error) {
-	t := reflect.TypeOf(fileResources)
-	v := reflect.ValueOf(fileResources)
-
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
-		v = v.Elem()
-	}
-	if t.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("expected a struct or a pointer to a struct, got %s", t.Kind())
-	}
-
-	// Prepare the data slice
+func createTableForVersionInfo(info *pefile.VersionInfo) (*sortableTable, error) {
 	data := [][]string{
-		{"Property", "Value"},
+		{"Table", "Property", "Value"},
 	}
 
-	var longestFieldName = 0
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		value := v.Field(i)
-		valueStr := value.String()
-
-		data = append(data, []string{
-			field.Name,
-			valueStr,
-		})
-		if len(field.Name) > longestFieldName {
-			longestFieldName = len(field.Name)
+	if info == nil {
+		data = append(data, []string{"", "No version information", ""})
+	} else {
+		if fixed := info.FixedFileInfo; fixed != nil {
+			data = append(data,
+				[]string{"VS_FIXEDFILEINFO", "FileVersion", fixed.FileVersion()},
+				[]string{"VS_FIXEDFILEINFO", "ProductVersion", fixed.ProductVersion()},
+				[]string{"VS_FIXEDFILEINFO", "FileFlags", fmt.Sprintf("0x%X (mask 0x%X)", fixed.FileFlags, fixed.FileFlagsMask)},
+				[]string{"VS_FIXEDFILEINFO", "FileOS", fmt.Sprintf("0x%X", fixed.FileOS)},
+				[]string{"VS_FIXEDFILEINFO", "FileType", fmt.Sprintf("0x%X", fixed.FileType)},
+				[]string{"VS_FIXEDFILEINFO", "FileSubtype", fmt.Sprintf("0x%X", fixed.FileSubtype)},
+				[]string{"VS_FIXEDFILEINFO", "FileDate", fmt.Sprintf("0x%08X%08X", fixed.FileDateMS, fixed.FileDateLS)})
+		}
+		for _, table := range info.StringTables {
+			for _, str := range table.Strings {
+				data = append(data, []string{"StringFileInfo " + table.Key, str.Key, str.Value})
+			}
+		}
+		for _, translation := range info.Translations {
+			data = append(data, []string{"VarFileInfo", "Translation",
+				fmt.Sprintf("%04X %04X", translation.Language, translation.CodePage)})
 		}
 	}
 
-	colWidths := []float32{200, float32(longestFieldName) * 30}
-	colTypes := []ColumnType{unsortableCol, unsortableCol}
-	colProps := []ColumnProps{{false, false}, {false, true}}
+	colWidths := []float32{200, 200, 500}
+	colTypes := []ColumnType{strCol, strCol, unsortableCol}
+	colProps := []ColumnProps{{true, false}, {true, false}, {false, true}}
 
-	return createNewSortableTable(colWidths, data, colTypes, colProps)
+	return createNewSortableTable(colWidths, data, nil, colTypes, colProps)
 }
 
 func createTableForProperties(fileProperties FileProperties) (*sortableTable, error) {
@@ -197,9 +170,13 @@ func createTableForProperties(fileProperties FileProperties) (*sortableTable, er
 			valueStr = fmt.Sprintf("%d", value.Int())
 		case reflect.Struct:
 			switch field.Type {
-			case reflect.TypeOf(windows.Filetime{}):
-				tm := filetimeToTime(value.Interface().(windows.Filetime))
-				valueStr = tm.Format("Monday 02 January 2006, 15:04:05")
+			case reflect.TypeOf(time.Time{}):
+				tm := value.Interface().(time.Time)
+				if tm.IsZero() {
+					valueStr = "Unknown"
+				} else {
+					valueStr = tm.Format("Monday 02 January 2006, 15:04:05")
+				}
 			default:
 				continue
 			}
@@ -231,5 +208,5 @@ func createTableForProperties(fileProperties FileProperties) (*sortableTable, er
 	colTypes := []ColumnType{unsortableCol, unsortableCol}
 	colProps := []ColumnProps{{false, false}, {false, true}}
 
-	return createNewSortableTable(colWidths, data, colTypes, colProps)
+	return createNewSortableTable(colWidths, data, nil, colTypes, colProps)
 }
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// getFileTimes only knows the modification time outside Windows; the
+// creation and access times are left zero and shown as unknown.
+func getFileTimes(filePath string) (time.Time, time.Time, time.Time, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return time.Time{}, time.Time{}, time.Time{}, err
+	}
+	return time.Time{}, time.Time{}, info.ModTime(), nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+func getFileTimes(filePath string) (time.Time, time.Time, time.Time, error) {
+	var creation windows.Filetime
+	var access windows.Filetime
+	var modified windows.Filetime
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return time.Time{}, time.Time{}, time.Time{}, err
+	}
+	defer file.Close()
+
+	h := windows.Handle(file.Fd())
+	err = windows.GetFileTime(h, &creation, &access, &modified)
+	return filetimeToTime(creation), filetimeToTime(access), filetimeToTime(modified), err
+}
+
+func filetimeToTime(ft windows.Filetime) time.Time {
+	// Combine high and low parts (in 100-ns units)
+	ft100 := (int64(ft.HighDateTime) << 32) | int64(ft.LowDateTime)
+	const offset int64 = 116444736000000000
+	diff := ft100 - offset
+	sec := diff / 10000000
+	nsec := (diff % 10000000) * 100
+	return time.Unix(sec, nsec)
+}
@@ -2,14 +2,16 @@ package main
 
 import (
 	"bytes"
-	"debug/pe"
+	"crypto/x509"
 	_ "embed"
 	"encoding/binary"
 	"fmt"
 	"image/png"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -17,6 +19,8 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/sqweek/dialog"
+
+	"PEGo/pefile"
 )
 
 //go:embed winres\\logosmall.png
@@ -83,6 +87,10 @@ func displayErrorOnRightPane(ui *MyAppUI, msg string) {
 	ui.rightPane.Add(widget.NewLabel(msg))
 }
 
+// trustAnchors holds the root certificates loaded with "Load Trust
+// Anchors...", against which signatures are verified without going online.
+var trustAnchors *x509.CertPool
+
 func InitPaneView(window fyne.Window) {
 	// Create two panes
 	ui := initUIElements()
@@ -90,8 +98,9 @@ func InitPaneView(window fyne.Window) {
 	data := map[string][]string{}
 
 	var filePath string
-	var peFull *PeFull
+	var peFull *pefile.PeFull
 	var rootName string
+	var fileIcon fyne.Resource
 
 	// Create the tree widget
 	tree := widget.NewTree(
@@ -117,18 +126,12 @@ func InitPaneView(window fyne.Window) {
 			box := obj.(*fyne.Container)
 			icon := box.Objects[0].(*canvas.Image)
 			txt := box.Objects[1].(*canvas.Text)
-			txt.Text = uid
+			txt.Text = pefile.TreeNodeLabel(uid)
 			if strings.HasPrefix(uid, "File: ") {
-				iconImg, err := extractExeIcon(filePath)
-				if err != nil {
-					icon.Resource = theme.FileIcon()
+				if fileIcon != nil {
+					icon.Resource = fileIcon
 				} else {
-					buf := new(bytes.Buffer)
-					if err := png.Encode(buf, iconImg); err != nil {
-						icon.Resource = theme.FileIcon()
-					} else {
-						icon.Resource = fyne.NewStaticResource("exeIcon.png", buf.Bytes())
-					}
+					icon.Resource = theme.FileIcon()
 				}
 				icon.Show()
 			} else {
@@ -139,6 +142,7 @@ func InitPaneView(window fyne.Window) {
 	)
 
 	ui.leftPane.Add(tree)
+	fileHexView = newHexView()
 
 	fileMenu := fyne.NewMenu("File",
 		fyne.NewMenuItem("Open", func() {
@@ -154,54 +158,64 @@ func InitPaneView(window fyne.Window) {
 			file, err := os.Open(filePath)
 			if err != nil {
 				errorMessage := fmt.Sprintf("Error opening file: %v", err)
+				displayErrorOnRightPane(ui, errorMessage)
 				fmt.Println(errorMessage)
+				return
 			}
-			peFile, err := pe.NewFile(file)
+			defer file.Close()
+
+			parsed, err := pefile.Parse(file)
 			if err != nil {
-				file.Close()
-				errorMessage := fmt.Sprintf("Error opening file: %v", err)
+				errorMessage := fmt.Sprintf("Error parsing file: %v", err)
 				displayErrorOnRightPane(ui, "Unsupported file format")
 				fmt.Println(errorMessage)
 				return
 			}
-			defer file.Close()
 
-			fileData, err := os.ReadFile(filePath)
-			if err != nil {
-				displayErrorOnRightPane(ui, err.Error())
+			peFull = parsed
+			fileIcon = exeIconResource(peFull)
+			fileHexView.setFile(peFull)
+			data = pefile.GetPeTreeMap(peFull, filePath)
+			rootName = data[""][0]
+			fmt.Printf("rootName: %s\n", rootName)
+			tree.Refresh()
+			tree.OpenAllBranches()
+			tree.Select(rootName)
+		}),
+		fyne.NewMenuItem("Save Icon...", func() {
+			if peFull == nil {
+				displayPopup("Save Icon", "No file is open")
 				return
 			}
-
-			dos, err := parseDOSHeader(fileData)
+			groups, err := peFull.IconGroups()
+			if len(groups) == 0 {
+				displayPopup("Save Icon", err.Error())
+				return
+			}
+			saveIconGroup(groups[0])
+		}),
+		fyne.NewMenuItem("Load Trust Anchors...", func() {
+			anchorsPath, err := dialog.File().Filter("Certificates", "pem", "crt", "cer").Title("Load Trust Anchors").Load()
 			if err != nil {
-				errorMessage := fmt.Sprintf("Error parsing dos header: %v", err)
-				displayErrorOnRightPane(ui, "Error parsing dos header")
-				fmt.Println(errorMessage)
+				if err.Error() != "cancelled" {
+					fmt.Println("Error loading trust anchors:", err)
+				}
 				return
 			}
-
-			nt, err := parseNtHeaders(fileData, dos)
+			roots, err := pefile.LoadTrustAnchors(anchorsPath)
 			if err != nil {
-				errorMessage := fmt.Sprintf("Error parsing nt headers: %v", err)
-				displayErrorOnRightPane(ui, "Error parsing nt headers")
-				fmt.Println(errorMessage)
+				displayPopup("Load Trust Anchors", err.Error())
 				return
 			}
-
-			peFull = NewPeFull(dos, nt, peFile, fileData)
-			data = getPeTreeMap(peFile, filePath)
-			rootName = data[""][0]
-			fmt.Printf("rootName: %s\n", rootName)
-			tree.Refresh()
-			tree.OpenAllBranches()
-			tree.Select(rootName)
+			trustAnchors = roots
+			displayPopup("Load Trust Anchors", "Signatures are now verified against "+filepath.Base(anchorsPath))
 		}),
 	)
 
 	tree.OnSelected = func(uid widget.TreeNodeID) {
 		switch uid {
 		case rootName:
-			properties, err := getFileProperties(peFull, filePath)
+			properties, err := getFileProperties(peFull, filePath, trustAnchors)
 			if err != nil {
 				displayErrorOnRightPane(ui, err.Error())
 				return
@@ -209,52 +223,86 @@ func InitPaneView(window fyne.Window) {
 			displayFileProperties(ui, properties)
 		case "Dos Header":
 			// Call the function to display DOS header details
-			displayDosHeaderDetails(ui, peFull.dos, 0)
+			displayDosHeaderDetails(ui, peFull.Dos, 0)
+		case "Rich Header":
+			displayRichHeaderDetails(ui, peFull)
 		case "Nt Headers":
-			displayNtHeadersDetails(ui, peFull.nt, uintptr(peFull.dos.E_ifanew))
+			displayNtHeadersDetails(ui, peFull.Nt, uintptr(peFull.NtHeadersOffset()))
 		case "File Header":
-			// fmt.Printf("sizeof nt: %d\n", unsafe.Sizeof(peFull.nt))
-			// fmt.Printf("sizeof nt.signature: %d\n", unsafe.Sizeof(peFull.nt.Signature))
-			displayFileHeaderDetails(ui, &peFull.peFile.FileHeader, uintptr(peFull.dos.E_ifanew)+uintptr(binary.Size(peFull.nt)))
+			displayFileHeaderDetails(ui, peFull, uintptr(peFull.FileHeaderOffset()))
 		case "Optional Header":
-			optHeader, err := getOptionalHeader(peFull.peFile)
+			optHeader, err := pefile.GetOptionalHeader(peFull.PeFile)
 			if err != nil {
 				displayErrorOnRightPane(ui, err.Error())
 				return
 			}
-			displayOptionalHeaderDetails(ui, optHeader, uintptr(peFull.dos.E_ifanew)+uintptr(binary.Size(peFull.nt))+uintptr(binary.Size(peFull.peFile.FileHeader)))
+			displayOptionalHeaderDetails(ui, peFull, optHeader, uintptr(peFull.OptionalHeaderOffset()))
 		case "Data Directories":
-			optHeader, err := getOptionalHeader(peFull.peFile)
+			optHeader, err := pefile.GetOptionalHeader(peFull.PeFile)
 			if err != nil {
 				displayErrorOnRightPane(ui, err.Error())
 				return
 			}
-			dataDirs, err := getDataDirectories(optHeader)
+			dataDirs, err := pefile.GetDataDirectories(optHeader)
 			if err != nil {
 				displayErrorOnRightPane(ui, err.Error())
 				return
 			}
 
-			displayDataDirectoryDetails(ui, dataDirs, uintptr(peFull.dos.E_ifanew)+uintptr(binary.Size(peFull.nt))+uintptr(binary.Size(peFull.peFile.FileHeader))+uintptr(binary.Size(peFull.peFile.OptionalHeader))-uintptr(binary.Size(dataDirs)))
+			displayDataDirectoryDetails(ui, dataDirs, uintptr(peFull.DataDirectoriesOffset()))
 
 		case "Section Headers":
-			displaySectionHeadersDetails(ui, peFull.peFile.Sections, uintptr(peFull.dos.E_ifanew)+uintptr(binary.Size(peFull.nt))+uintptr(binary.Size(peFull.peFile.FileHeader))+uintptr(binary.Size(peFull.peFile.OptionalHeader)))
+			displaySectionHeadersDetails(ui, peFull)
 		case "Export Table":
-			optHeader, err := getOptionalHeader(peFull.peFile)
-			if err != nil {
-				displayErrorOnRightPane(ui, err.Error())
-				return
-			}
-			dataDirs, err := getDataDirectories(optHeader)
-			if err != nil {
-				displayErrorOnRightPane(ui, err.Error())
-				return
-			}
-
-			displayExportTableDetails(ui, dataDirs[0], peFull.peFile, peFull.fileData)
+			displayExportTableDetails(ui, peFull)
+		case "Import Table":
+			displayImportTableDetails(ui, peFull)
+		case "Delay Import Descriptor":
+			displayDelayImportTableDetails(ui, peFull)
+		case "Bound Import":
+			displayBoundImportDetails(ui, peFull)
+		case "IAT":
+			displayIatDetails(ui, peFull)
+		case "CLR Runtime Header":
+			displayClrHeaderDetails(ui, peFull)
+		case "Resource Table":
+			displayResourceTableDetails(ui, peFull, uid)
+		case "Base Relocation Table":
+			displayBaseRelocationDetails(ui, peFull)
+		case "Exception Table":
+			displayExceptionTableDetails(ui, peFull)
+		case "Certificate Table":
+			displayCertificateTableDetails(ui, peFull)
+		case "Debug":
+			displayDebugDirectoryDetails(ui, peFull)
+		case "TLS Table":
+			displayTlsDetails(ui, peFull)
+		case "Load Config Table":
+			displayLoadConfigDetails(ui, peFull)
 		default:
-			ui.rightPane.RemoveAll()
-			ui.rightPane.Add(widget.NewLabel(rootName))
+			switch {
+			case pefile.TreeNodeParent(uid) == "Import Table":
+				displayImportDetails(ui, peFull, childIndex(data, uid))
+			case pefile.TreeNodeParent(uid) == "Delay Import Descriptor":
+				displayDelayImportDetails(ui, peFull, childIndex(data, uid))
+			case strings.HasPrefix(uid, "Resource Table/"):
+				displayResourceTableDetails(ui, peFull, uid)
+			case pefile.TreeNodeParent(uid) == "Certificate Table":
+				displaySignatureDetails(ui, peFull, childIndex(data, uid))
+			case pefile.TreeNodeParent(uid) == "Debug":
+				displayDebugEntryDetails(ui, peFull, childIndex(data, uid))
+			case uid == pefile.TreeNodeID("CLR Runtime Header", "Metadata"):
+				displayMetadataDetails(ui, peFull)
+			case pefile.TreeNodeParent(uid) == pefile.TreeNodeID("CLR Runtime Header", "Metadata"):
+				displayMetadataStreamDetails(ui, peFull, pefile.TreeNodeLabel(uid))
+			case pefile.TreeNodeParent(pefile.TreeNodeParent(uid)) == pefile.TreeNodeID("CLR Runtime Header", "Metadata"):
+				displayMetadataTableDetails(ui, peFull, pefile.TreeNodeLabel(uid))
+			case pefile.TreeNodeParent(uid) == "Load Config Table":
+				displayGuardTableDetails(ui, peFull, pefile.TreeNodeLabel(uid))
+			default:
+				ui.rightPane.RemoveAll()
+				ui.rightPane.Add(widget.NewLabel(rootName))
+			}
 
 		}
 
@@ -263,8 +311,12 @@ func InitPaneView(window fyne.Window) {
 	// Create the main menu
 	mainMenu := fyne.NewMainMenu(fileMenu)
 
+	// The hex pane sits under the details of the selected node
+	detailsSplit := container.NewVSplit(ui.rightPane, fileHexView.content)
+	detailsSplit.SetOffset(0.65)
+
 	// Create a horizontal split
-	split := container.NewHSplit(ui.leftPane, ui.rightPane)
+	split := container.NewHSplit(ui.leftPane, detailsSplit)
 	split.SetOffset(0.3) // Set the split ratio (0.5 means equal halves)
 
 	// Set the menu and content in the window
@@ -272,11 +324,105 @@ func InitPaneView(window fyne.Window) {
 	window.SetContent(container.NewStack(loadBackgroundImage(), split))
 
 	// Show and run the application
-	window.Resize(fyne.NewSize(800, 600))
+	window.Resize(fyne.NewSize(1200, 800))
 	window.ShowAndRun()
 }
 
+// exeIconResource renders the first icon group of the image at tree node
+// size, or returns nil when the image has no usable icon.
+func exeIconResource(peFull *pefile.PeFull) fyne.Resource {
+	// Groups that fail to decode are skipped in favour of the next one
+	groups, _ := peFull.IconGroups()
+	for _, group := range groups {
+		icon := group.Best(32)
+		if icon == nil {
+			continue
+		}
+		img, err := icon.Decode()
+		if err != nil {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		if err := png.Encode(buf, img); err != nil {
+			continue
+		}
+		return fyne.NewStaticResource("exeIcon.png", buf.Bytes())
+	}
+	return nil
+}
+
+func saveIconGroup(group *pefile.IconGroup) {
+	savePath, err := dialog.File().Filter("Icon files", "ico").Title("Save Icon").Save()
+	if err != nil {
+		if err.Error() != "cancelled" {
+			fmt.Println("Error saving icon:", err)
+		}
+		return
+	}
+	if !strings.HasSuffix(strings.ToLower(savePath), ".ico") {
+		savePath += ".ico"
+	}
+
+	file, err := os.Create(savePath)
+	if err != nil {
+		displayPopup("Save Icon", fmt.Sprintf("Error creating file: %v", err))
+		return
+	}
+	defer file.Close()
+
+	if err := group.WriteICO(file); err != nil {
+		displayPopup("Save Icon", fmt.Sprintf("Error writing icon: %v", err))
+	}
+}
+
+// createIconGroupPanel shows every image of an icon group at its own size,
+// with a button to save the group as a .ico file.
+func createIconGroupPanel(group *pefile.IconGroup) fyne.CanvasObject {
+	images := container.NewHBox()
+	for i := range group.Icons {
+		icon := &group.Icons[i]
+		caption := fmt.Sprintf("%dx%d, %d bpp", icon.Width(), icon.Height(), icon.Entry.BitCount)
+		if icon.IsPNG() {
+			caption += ", PNG"
+		}
+
+		img, err := icon.Decode()
+		if err != nil {
+			images.Add(container.NewVBox(widget.NewLabel(caption), widget.NewLabel(err.Error())))
+			continue
+		}
+		buf := new(bytes.Buffer)
+		if err := png.Encode(buf, img); err != nil {
+			continue
+		}
+		iconImage := canvas.NewImageFromResource(fyne.NewStaticResource(fmt.Sprintf("icon%d.png", icon.Entry.ID), buf.Bytes()))
+		iconImage.FillMode = canvas.ImageFillOriginal
+		images.Add(container.NewVBox(iconImage, widget.NewLabel(caption)))
+	}
+
+	saveButton := widget.NewButton("Save as .ico", func() {
+		saveIconGroup(group)
+	})
+	return container.NewBorder(container.NewHBox(saveButton), nil, nil, nil, container.NewHScroll(images))
+}
+
+// childIndex returns the position of a nested tree node among its siblings.
+func childIndex(data map[string][]string, uid string) int {
+	for i, child := range data[pefile.TreeNodeParent(uid)] {
+		if child == uid {
+			return i
+		}
+	}
+	return -1
+}
+
 func createTableFromStruct(header any, offset uintptr, lowercaseField bool) (*sortableTable, error) {
+	return createTableFromStructPrefix(header, offset, lowercaseField, ^uintptr(0))
+}
+
+// createTableFromStructPrefix is createTableFromStruct for structures that
+// grow between versions, listing only the fields within the first limit bytes.
+func createTableFromStructPrefix(header any, offset uintptr, lowercaseField bool, limit uintptr) (*sortableTable, error) {
 	// Use reflection to iterate over the struct fields
 	t := reflect.TypeOf(header)
 	v := reflect.ValueOf(header)
@@ -292,14 +438,19 @@ func createTableFromStruct(header any, offset uintptr, lowercaseField bool) (*so
 	// Prepare the data slice
 	// Now the first column is "Index" but we will fill it with hex size values
 	data := [][]string{
-		{"Offset", "Field", "Value", "Size"},
+		{"Offset", "Field", "Value", "Meaning", "Size"},
 	}
 
+	var sizes []uint64
 	var longestFieldName = 0
+	var used uintptr
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		value := v.Field(i)
 		size := field.Type.Size()
+		if used += size; used > limit {
+			break
+		}
 
 		// Handle arrays separately
 		var valueStr string
@@ -310,7 +461,6 @@ func createTableFromStruct(header any, offset uintptr, lowercaseField bool) (*so
 		} else {
 			valueStr = fmt.Sprintf("%#x", value.Interface())
 		}
-
 		fieldStr := field.Name
 		if lowercaseField {
 			fieldStr = strings.ToLower(fieldStr)
@@ -321,59 +471,156 @@ func createTableFromStruct(header any, offset uintptr, lowercaseField bool) (*so
 			fmt.Sprintf("0x%X", offset), // hex representation of size
 			fieldStr,
 			valueStr,
+			fieldMeaning(header, field.Name, value),
 			fmt.Sprintf("%d", size), // keep decimal bytes in the "Size" column
 		})
+		sizes = append(sizes, uint64(size))
 		offset += size
 		if len(field.Name) > longestFieldName {
 			longestFieldName = len(field.Name)
 		}
 	}
 
-	colWidths := []float32{90, float32(longestFieldName) * 10, 150, 100}
-	colTypes := []ColumnType{hexCol, strCol, unsortableCol, decCol}
-	colProps := []ColumnProps{{true, true}, {true, true}, {false, true}, {true, true}}
+	colWidths := []float32{90, float32(longestFieldName) * 10, 150, 300, 100}
+	colTypes := []ColumnType{hexCol, strCol, unsortableCol, unsortableCol, decCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {false, true}, {false, true}, {true, true}}
 
-	return createNewSortableTable(colWidths, data, colTypes, colProps)
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
 }
 
-func createTableForDataDirectories(dataDirs []pe.DataDirectory, offset uintptr) (*sortableTable, error) {
+// checkSumMeaning compares the stored CheckSum with the one CheckSumMappedFile
+// would compute. A zero CheckSum is only enforced for drivers and boot
+// components, so it is reported as not set rather than as a mismatch.
+func checkSumMeaning(peFull *pefile.PeFull) string {
+	computed := peFull.ComputeCheckSum()
+	switch stored := peFull.StoredCheckSum(); {
+	case stored == computed:
+		return fmt.Sprintf("computed 0x%08X, match", computed)
+	case stored == 0:
+		return fmt.Sprintf("computed 0x%08X, not set", computed)
+	default:
+		return fmt.Sprintf("computed 0x%08X, MISMATCH", computed)
+	}
+}
+
+// fieldMeaning decodes the value of a header field when a raw number is not
+// enough to read it, and returns "" otherwise.
+func fieldMeaning(header any, name string, value reflect.Value) string {
+	if meaning := structFieldMeaning(header, name, value); meaning != "" {
+		return meaning
+	}
+
+	// Fields that read the same way in every structure
+	switch {
+	case name == "TimeDateStamp":
+		return timeDateStampMeaning(uint32(value.Uint()))
+	case strings.HasPrefix(name, "Major") && value.CanUint():
+		// Versions are split into a Major and a Minor field
+		minor := reflect.Indirect(reflect.ValueOf(header)).FieldByName("Minor" + strings.TrimPrefix(name, "Major"))
+		if minor.IsValid() && minor.CanUint() {
+			return fmt.Sprintf("%d.%d", value.Uint(), minor.Uint())
+		}
+	case strings.HasPrefix(name, "SizeOf") && value.CanUint() && value.Uint() >= 1024:
+		return kibText(value.Uint())
+	}
+	return ""
+}
+
+// structFieldMeaning decodes the fields whose meaning depends on the
+// structure they are part of.
+func structFieldMeaning(header any, name string, value reflect.Value) string {
+	switch header.(type) {
+	case *pefile.FileHeader:
+		switch name {
+		case "Machine":
+			return pefile.MachineName(uint16(value.Uint()))
+		case "Characteristics":
+			return strings.Join(pefile.FileCharacteristicNames(uint16(value.Uint())), " | ")
+		}
+	case *pefile.OptionalHeader32, *pefile.OptionalHeader64:
+		switch name {
+		case "Magic":
+			return pefile.OptionalHeaderMagicName(uint16(value.Uint()))
+		case "Subsystem":
+			return pefile.SubsystemName(uint16(value.Uint()))
+		case "DllCharacteristics":
+			return strings.Join(pefile.DllCharacteristicNames(uint16(value.Uint())), " | ")
+		}
+	case *pefile.IMAGE_TLS_DIRECTORY32, *pefile.IMAGE_TLS_DIRECTORY64:
+		// Bits 20-23 hold the alignment the same way section flags do
+		if align := value.Uint() >> 20 & 0xF; name == "Characteristics" && align != 0 {
+			return fmt.Sprintf("align %d bytes", 1<<(align-1))
+		}
+	case *pefile.IMAGE_LOAD_CONFIG_DIRECTORY32, *pefile.IMAGE_LOAD_CONFIG_DIRECTORY64:
+		if name == "GuardFlags" {
+			flags := uint32(value.Uint())
+			stride := 4 + flags>>pefile.IMAGE_GUARD_CF_FUNCTION_TABLE_SIZE_SHIFT
+			return fmt.Sprintf("%s; table stride %d", strings.Join(pefile.GuardFlagNames(flags), " | "), stride)
+		}
+	case pefile.IMAGE_DELAYLOAD_DESCRIPTOR:
+		if name == "Attributes" {
+			if value.Uint()&1 != 0 {
+				return "RVA based"
+			}
+			return "VA based"
+		}
+	case pefile.IMAGE_COR20_HEADER:
+		if name == "Flags" {
+			return strings.Join(pefile.ComImageFlagNames(uint32(value.Uint())), " | ")
+		}
+	case pefile.IMAGE_DEBUG_DIRECTORY:
+		if name == "Type" {
+			return pefile.DebugTypeName(uint32(value.Uint()))
+		}
+	}
+	return ""
+}
+
+func createTableForDataDirectories(dataDirs []pefile.DataDirectory, offset uintptr) (*sortableTable, error) {
 	data := [][]string{
 		{"Offset", "Directory", "RVA", "Size"},
 	}
+	var sizes []uint64
 
 	var longestFieldName = 0
 
+	entrySize := uintptr(binary.Size(pefile.DataDirectory{}))
 	for i, dir := range dataDirs {
-		if i < len(directoryNames) {
+		if i < len(pefile.DirectoryNames) {
 			data = append(data, []string{fmt.Sprintf("0x%X", offset),
-				directoryNames[i],
+				pefile.DirectoryNames[i],
 				fmt.Sprintf("0x%X", dir.VirtualAddress),
 				fmt.Sprintf("%d", dir.Size)})
+			sizes = append(sizes, uint64(entrySize))
 
-			if len(directoryNames[i]) > longestFieldName {
-				longestFieldName = len(directoryNames[i])
+			if len(pefile.DirectoryNames[i]) > longestFieldName {
+				longestFieldName = len(pefile.DirectoryNames[i])
 			}
 		}
-		offset += 4
+		offset += entrySize
 	}
 
 	colWidths := []float32{65, float32(longestFieldName) * 10, 150, 100}
 	colTypes := []ColumnType{hexCol, strCol, hexCol, decCol}
 	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}}
 
-	return createNewSortableTable(colWidths, data, colTypes, colProps)
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
 }
 
-func createTableForSectionHeaders(sections []*pe.Section, offset uintptr) (*sortableTable, error) {
+func createTableForSectionHeaders(sections []*pefile.Section, stats []pefile.RegionStats, offset uintptr) (*sortableTable, error) {
 
 	data := [][]string{
 		{"Offset", "Name", "Virtual Size", "Virtual Address",
 			"Raw Size", "Raw data *", "Relocations *", "Relocations #",
-			"Line Numbers *", "Line Numbers #", "Characteristics"},
+			"Line Numbers *", "Line Numbers #", "Characteristics", "Flags",
+			"MD5", "SHA256", "Entropy"},
 	}
+	var sizes []uint64
+	var flagged []bool
 
-	for _, section := range sections {
+	for i, section := range sections {
 		header := section.SectionHeader
+		executable := header.Characteristics&(pefile.IMAGE_SCN_CNT_CODE|pefile.IMAGE_SCN_MEM_EXECUTE) != 0
 		data = append(data, []string{
 			fmt.Sprintf("0x%X", offset),
 			header.Name,
@@ -385,161 +632,1111 @@ func createTableForSectionHeaders(sections []*pe.Section, offset uintptr) (*sort
 			fmt.Sprintf("%d", header.NumberOfRelocations),
 			fmt.Sprintf("0x%X", header.PointerToLineNumbers),
 			fmt.Sprintf("%d", header.NumberOfLineNumbers),
-			fmt.Sprintf("0x%X", header.Characteristics)})
+			fmt.Sprintf("0x%X", header.Characteristics),
+			strings.Join(pefile.SectionCharacteristicNames(header.Characteristics), " | "),
+			stats[i].MD5,
+			stats[i].SHA256,
+			entropyText(stats[i], executable)})
+		sizes = append(sizes, 0x28)
+		flagged = append(flagged, entropySuspicious(stats[i], executable))
 		offset += 0x28
 	}
 
-	colWidths := []float32{65, 80, 100, 110, 100, 100, 100, 100, 120, 120, 110}
+	colWidths := []float32{65, 80, 100, 110, 100, 100, 100, 100, 120, 120, 110, 220, 150, 200, 130}
 	colTypes := []ColumnType{hexCol, strCol, hexCol, hexCol, decCol, hexCol, hexCol, decCol,
-		hexCol, decCol, hexCol}
-	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+		hexCol, decCol, hexCol, strCol, strCol, strCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {false, true}, {false, true}, {false, true}, {true, true}}
 
-	return createNewSortableTable(colWidths, data, colTypes, colProps)
+	table, err := createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+	if err != nil {
+		return nil, err
+	}
+	table.flagged = flagged
+	return table, nil
 }
 
-func getOffsetArrayUint32(peFile *pe.File, fileData []byte, rva uint32, size uint32) ([]uint32, error) {
-	offset, err := rvaToOffset(peFile, rva)
+// createTableForRegionStats lists the hashes and entropy of the parts of the
+// file outside the sections, the headers and the overlay.
+func createTableForRegionStats(regions []pefile.RegionStats) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Region", "Size", "MD5", "SHA256", "Entropy"},
+	}
+	var sizes []uint64
+	var flagged []bool
+
+	for _, region := range regions {
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", region.Offset),
+			region.Name,
+			fmt.Sprintf("%d", region.Size),
+			region.MD5,
+			region.SHA256,
+			entropyText(region, false)})
+		sizes = append(sizes, uint64(region.Size))
+		flagged = append(flagged, entropySuspicious(region, false))
+	}
+
+	colWidths := []float32{65, 80, 100, 150, 200, 130}
+	colTypes := []ColumnType{hexCol, strCol, decCol, strCol, strCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {false, true}, {false, true}, {true, true}}
+
+	table, err := createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
 	if err != nil {
 		return nil, err
 	}
+	table.flagged = flagged
+	return table, nil
+}
 
-	arr := make([]uint32, size)
-	addressOfFunctionsReader := bytes.NewReader(fileData[offset:])
-	if err := binary.Read(addressOfFunctionsReader, binary.LittleEndian, &arr); err != nil {
-		return nil, err
+// entropyText formats the entropy of a region, noting why entropySuspicious
+// flags it.
+func entropyText(stats pefile.RegionStats, executable bool) string {
+	text := fmt.Sprintf("%.3f", stats.Entropy)
+	switch {
+	case !entropySuspicious(stats, executable):
+	case stats.Entropy > pefile.HighEntropy:
+		text += " (high, packed?)"
+	default:
+		text += " (zero, executable)"
 	}
+	return text
+}
 
-	return arr, nil
+// entropySuspicious reports the regions worth a closer look: packed or
+// encrypted data, and code sections holding nothing but one repeated byte,
+// which an unpacking stub fills in at run time. A section without raw data,
+// like .textbss, has nothing to measure.
+func entropySuspicious(stats pefile.RegionStats, executable bool) bool {
+	if stats.Entropy > pefile.HighEntropy {
+		return true
+	}
+	return stats.Entropy == 0 && stats.Size > 0 && executable
 }
 
-func getOffsetArrayUint16(peFile *pe.File, fileData []byte, rva uint32, size uint32) ([]uint16, error) {
-	offset, err := rvaToOffset(peFile, rva)
-	if err != nil {
-		return nil, err
+func createTableForExports(exports *pefile.ExportTable) (*sortableTable, error) {
+	// Table header
+	data := [][]string{
+		{"Offset", "Ordinal", "Function RVA", "Name RVA", "Name"},
 	}
+	var sizes []uint64
 
-	arr := make([]uint16, size)
-	addressOfFunctionsReader := bytes.NewReader(fileData[offset:])
-	if err := binary.Read(addressOfFunctionsReader, binary.LittleEndian, &arr); err != nil {
-		return nil, err
+	for _, function := range exports.Functions {
+		name := "N/A"
+		nameRva := "N/A"
+		if function.NameRVA != 0 {
+			name = function.Name
+			nameRva = fmt.Sprintf("0x%X", function.NameRVA)
+		}
+
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", function.Offset),      // file offset of this function entry
+			fmt.Sprintf("0x%X", function.Ordinal),     // actual ordinal we display
+			fmt.Sprintf("0x%X", function.FunctionRVA), // RVA
+			nameRva, // name RVA if present
+			name,    // function name if present
+		})
+		sizes = append(sizes, 4) // an RVA in the export address table
 	}
 
-	return arr, nil
+	colWidths := []float32{90, 65, 100, 90, 700}
+	colTypes := []ColumnType{hexCol, hexCol, hexCol, hexCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
 }
 
-func readStringFromRVA(peFile *pe.File, fileData []byte, rva uint32) (string, error) {
-	offset, err := rvaToOffset(peFile, rva)
-	if err != nil {
-		return "", err
+func createTableForImportDescriptors(imports []pefile.ImportDescriptor) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "DLL", "OriginalFirstThunk", "TimeDateStamp", "ForwarderChain", "Name", "FirstThunk", "Functions #"},
+	}
+	var sizes []uint64
+
+	var longestFieldName = 0
+	for _, descriptor := range imports {
+		dllName := descriptor.DllName
+		if descriptor.Err != nil && dllName == "" {
+			dllName = "N/A"
+		}
+
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", descriptor.Offset),
+			dllName,
+			fmt.Sprintf("0x%X", descriptor.Descriptor.OriginalFirstThunk),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.TimeDateStamp),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.ForwarderChain),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.Name),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.FirstThunk),
+			fmt.Sprintf("%d", len(descriptor.Functions)),
+		})
+		sizes = append(sizes, uint64(binary.Size(descriptor.Descriptor)))
+		if len(dllName) > longestFieldName {
+			longestFieldName = len(dllName)
+		}
 	}
 
-	// Ensure offset is within bounds
-	if offset >= uint32(len(fileData)) {
-		return "", fmt.Errorf("offset out of bounds")
+	colWidths := []float32{90, float32(longestFieldName) * 10, 140, 120, 120, 90, 90, 100}
+	colTypes := []ColumnType{hexCol, strCol, hexCol, hexCol, hexCol, hexCol, hexCol, decCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForDelayImportDescriptors(imports []pefile.DelayImportDescriptor) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "DLL", "Attributes", "Name", "Module Handle", "Delay IAT", "Delay INT", "Bound IAT", "Unload IAT", "TimeDateStamp", "Functions #"},
 	}
+	var sizes []uint64
 
-	// Read until the first null terminator
-	var strBytes []byte
-	for i := offset; i < uint32(len(fileData)); i++ {
-		if fileData[i] == 0 { // Null terminator found
-			break
+	var longestFieldName = 0
+	for _, descriptor := range imports {
+		attributes := "VA based"
+		if descriptor.RvaBased {
+			attributes = "RVA based"
+		}
+		dllName := descriptor.DllName
+		if descriptor.Err != nil && dllName == "" {
+			dllName = "N/A"
+		}
+
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", descriptor.Offset),
+			dllName,
+			fmt.Sprintf("0x%X (%s)", descriptor.Descriptor.Attributes, attributes),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.DllNameRVA),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.ModuleHandleRVA),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.ImportAddressTableRVA),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.ImportNameTableRVA),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.BoundImportAddressTableRVA),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.UnloadInformationTableRVA),
+			fmt.Sprintf("0x%X", descriptor.Descriptor.TimeDateStamp),
+			fmt.Sprintf("%d", len(descriptor.Functions)),
+		})
+		sizes = append(sizes, uint64(binary.Size(descriptor.Descriptor)))
+		if len(dllName) > longestFieldName {
+			longestFieldName = len(dllName)
 		}
-		strBytes = append(strBytes, fileData[i])
 	}
 
-	return string(strBytes), nil
+	colWidths := []float32{90, float32(longestFieldName) * 10, 130, 90, 120, 90, 90, 90, 90, 120, 100}
+	colTypes := []ColumnType{hexCol, strCol, strCol, hexCol, hexCol, hexCol, hexCol, hexCol, hexCol, hexCol, decCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
 }
 
-func createTableForExports(peFile *pe.File, fileData []byte, exportHeader IMAGE_EXPORT_DIRECTORY) (*sortableTable, error) {
-	// Table header
+// timeDateStampDate shows a TimeDateStamp field as a UTC date.
+func timeDateStampDate(stamp uint32) string {
+	return time.Unix(int64(stamp), 0).UTC().Format(certificateTimeFormat)
+}
+
+// firstPeTimestamp is about when the first PE images were linked; earlier
+// dates, like dates in the future, are unlikely to be real.
+var firstPeTimestamp = time.Date(1992, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// timeDateStampMeaning shows a TimeDateStamp as a date, noting when it does
+// not look like one. Reproducible builds store a hash of the build there.
+func timeDateStampMeaning(stamp uint32) string {
+	if stamp == 0 || stamp == 0xFFFFFFFF {
+		return ""
+	}
+	date := timeDateStampDate(stamp)
+	if t := time.Unix(int64(stamp), 0); t.Before(firstPeTimestamp) || t.After(time.Now()) {
+		date += ", not a plausible date: likely a reproducible-build hash"
+	}
+	return date
+}
+
+// pointerSize is the size of the VAs and import thunks of the image.
+func pointerSize(peFull *pefile.PeFull) uint64 {
+	if peFull.Is64Bit() {
+		return 8
+	}
+	return 4
+}
+
+// kibText shows a size in KiB.
+func kibText(size uint64) string {
+	if size%1024 == 0 {
+		return fmt.Sprintf("%d KiB", size/1024)
+	}
+	return fmt.Sprintf("%.2f KiB", float64(size)/1024)
+}
+
+// rvaMeaning shows an RVA as a VA together with what contains it.
+func rvaMeaning(peFull *pefile.PeFull, rva uint32) string {
+	va := peFull.ImageBase() + uint64(rva)
+	if section := peFull.SectionForRVA(rva); section != nil {
+		return fmt.Sprintf("VA 0x%X in %s", va, section.Name)
+	}
+	if rva < peFull.SizeOfHeaders() {
+		return fmt.Sprintf("VA 0x%X in the headers", va)
+	}
+	return fmt.Sprintf("VA 0x%X, outside any section", va)
+}
+
+// entryPointMeaning is rvaMeaning for AddressOfEntryPoint, which DLLs
+// without an entry point leave at zero.
+func entryPointMeaning(peFull *pefile.PeFull, rva uint32) string {
+	if rva == 0 {
+		return "no entry point"
+	}
+	return rvaMeaning(peFull, rva)
+}
+
+func createTableForBoundImports(bound []pefile.BoundImport) (*sortableTable, error) {
 	data := [][]string{
-		{"Offset", "Ordinal", "Function RVA", "Name RVA", "Name"},
+		{"Offset", "Module", "TimeDateStamp", "Date", "OffsetModuleName", "Forwarders #", "Import Table"},
 	}
+	var sizes []uint64
 
-	// Read the function/address arrays
-	functions, err := getOffsetArrayUint32(peFile, fileData,
-		exportHeader.AddressOfFunctions,
-		exportHeader.NumberOfFunctions)
-	if err != nil {
-		return nil, err
+	var longestFieldName = 0
+	for _, entry := range bound {
+		importStatus := "match"
+		switch {
+		case entry.Import == nil:
+			importStatus = "MISMATCH: not imported"
+		case !entry.TimestampMatches():
+			importStatus = fmt.Sprintf("MISMATCH: 0x%X", entry.Import.Descriptor.TimeDateStamp)
+		}
+
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", entry.Offset),
+			entry.ModuleName,
+			fmt.Sprintf("0x%X", entry.Descriptor.TimeDateStamp),
+			timeDateStampDate(entry.Descriptor.TimeDateStamp),
+			fmt.Sprintf("0x%X", entry.Descriptor.OffsetModuleName),
+			fmt.Sprintf("%d", entry.Descriptor.NumberOfModuleForwarderRefs),
+			importStatus,
+		})
+		sizes = append(sizes, uint64(binary.Size(entry.Descriptor)))
+		if len(entry.ModuleName) > longestFieldName {
+			longestFieldName = len(entry.ModuleName)
+		}
 	}
 
-	names, err := getOffsetArrayUint32(peFile, fileData,
-		exportHeader.AddressOfNames,
-		exportHeader.NumberOfNames)
-	if err != nil {
-		return nil, err
+	colWidths := []float32{90, float32(longestFieldName) * 10, 120, 190, 140, 110, 200}
+	colTypes := []ColumnType{hexCol, strCol, hexCol, strCol, hexCol, decCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForBoundForwarders(bound []pefile.BoundImport) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Module", "Forwarder", "TimeDateStamp", "Date", "OffsetModuleName"},
 	}
+	var sizes []uint64
 
-	nameOrdinals, err := getOffsetArrayUint16(peFile, fileData,
-		exportHeader.AddressOfNameOrdinals,
-		exportHeader.NumberOfNames)
-	if err != nil {
-		return nil, err
+	var longestFieldName = 0
+	for _, entry := range bound {
+		for _, forwarder := range entry.Forwarders {
+			data = append(data, []string{
+				fmt.Sprintf("0x%X", forwarder.Offset),
+				entry.ModuleName,
+				forwarder.ModuleName,
+				fmt.Sprintf("0x%X", forwarder.Ref.TimeDateStamp),
+				timeDateStampDate(forwarder.Ref.TimeDateStamp),
+				fmt.Sprintf("0x%X", forwarder.Ref.OffsetModuleName),
+			})
+			sizes = append(sizes, uint64(binary.Size(forwarder.Ref)))
+			longestFieldName = max(longestFieldName, len(entry.ModuleName), len(forwarder.ModuleName))
+		}
 	}
 
-	// Convert the Functions RVA to a file offset (for display only)
-	offset, err := rvaToOffset(peFile, exportHeader.AddressOfFunctions)
-	if err != nil {
-		return nil, err
+	colWidths := []float32{90, float32(longestFieldName) * 10, float32(longestFieldName) * 10, 120, 190, 140}
+	colTypes := []ColumnType{hexCol, strCol, strCol, hexCol, strCol, hexCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForIatSlots(slots []pefile.IatSlot, thunkSize uint64) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "RVA", "Value", "Import"},
 	}
+	var sizes []uint64
+
+	for _, slot := range slots {
+		offset, value := "N/A", "N/A"
+		if slot.FileBacked {
+			offset = fmt.Sprintf("0x%X", slot.Offset)
+			value = fmt.Sprintf("0x%X", slot.Value)
+		}
+
+		var imported string
+		switch {
+		case !slot.Claimed():
+			imported = "UNCLAIMED"
+		case slot.Function == nil:
+			imported = slot.DllName + " (end of thunks)"
+		case slot.Function.ByOrdinal:
+			imported = fmt.Sprintf("%s!#%d", slot.DllName, slot.Function.Ordinal)
+		default:
+			imported = slot.DllName + "!" + slot.Function.Name
+		}
+		if slot.Delayed {
+			imported += " (delay-load)"
+		}
 
-	ordToNameId := make(map[uint16]uint32)
-	for i := range names {
-		ordToNameId[nameOrdinals[i]] = uint32(i)
-		fmt.Printf("ordToNameId[0x%X] = 0x%X\n", nameOrdinals[i], i)
+		data = append(data, []string{
+			offset,
+			fmt.Sprintf("0x%X", slot.RVA),
+			value,
+			imported,
+		})
+		sizes = append(sizes, thunkSize)
 	}
 
-	nameIndex := 0
-	// Loop over each function “slot” (i is 0-based, ordinal – base)
-	for i := range functions {
+	colWidths := []float32{90, 90, 160, 500}
+	colTypes := []ColumnType{hexCol, hexCol, hexCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
 
-		// fmt.
-		// fmt.Printf("nameOrdinals[%d] = %d\n", nameIndex, nameOrdinals[nameIndex])
-		// fmt.Printf("functionIndex = %d\n", i)
+func createTableForImportFunctions(functions []pefile.ImportFunction, thunkSize uint64) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Hint", "Name / Ordinal", "ILT RVA", "IAT RVA", "IAT Offset", "Thunk"},
+	}
+	var sizes []uint64
 
-		realOrdinal := exportHeader.Base + uint32(i) // actual ordinal
-		name := "N/A"
-		nameRva := "N/A"
+	for _, function := range functions {
+		hint := "N/A"
+		name := function.Name
+		if function.ByOrdinal {
+			name = fmt.Sprintf("Ordinal 0x%X", function.Ordinal)
+		} else {
+			hint = fmt.Sprintf("0x%X", function.Hint)
+		}
+
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", function.IltOffset), // file offset of the ILT entry
+			hint,
+			name,
+			fmt.Sprintf("0x%X", function.IltRVA),
+			fmt.Sprintf("0x%X", function.IatRVA),
+			fmt.Sprintf("0x%X", function.IatOffset),
+			fmt.Sprintf("0x%X", function.ThunkValue),
+		})
+		sizes = append(sizes, thunkSize)
+	}
+
+	colWidths := []float32{90, 65, 400, 90, 90, 90, 160}
+	colTypes := []ColumnType{hexCol, hexCol, strCol, hexCol, hexCol, hexCol, hexCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForRelocationBlocks(blocks []pefile.RelocationBlock) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Page RVA", "Block Size", "Entries"},
+	}
+	var sizes []uint64
+
+	for _, block := range blocks {
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", block.Offset),
+			fmt.Sprintf("0x%X", block.Header.VirtualAddress),
+			fmt.Sprintf("%d", block.Header.SizeOfBlock),
+			fmt.Sprintf("%d", len(block.Entries)),
+		})
+		sizes = append(sizes, uint64(block.Header.SizeOfBlock))
+	}
+
+	colWidths := []float32{90, 90, 90, 90}
+	colTypes := []ColumnType{hexCol, hexCol, decCol, decCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForRelocationEntries(entries []pefile.RelocationEntry) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Entry", "Type", "Target RVA", "Target Offset", "Value"},
+	}
+	var sizes []uint64
+
+	for _, entry := range entries {
+		typeName := entry.TypeName
+		if entry.Type == pefile.IMAGE_REL_BASED_HIGHADJ {
+			typeName = fmt.Sprintf("%s (low 0x%X)", typeName, entry.Param)
+		}
+		targetOffset, value := "N/A", "N/A"
+		if entry.TargetOffset != 0 {
+			targetOffset = fmt.Sprintf("0x%X", entry.TargetOffset)
+		}
+		if entry.HasValue {
+			value = fmt.Sprintf("0x%0*X", entry.Size*2, entry.Value)
+		}
+
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", entry.Offset),
+			fmt.Sprintf("0x%04X", entry.Raw),
+			typeName,
+			fmt.Sprintf("0x%X", entry.TargetRVA),
+			targetOffset,
+			value,
+		})
+		sizes = append(sizes, uint64(binary.Size(entry.Raw)))
+	}
+
+	colWidths := []float32{90, 70, 200, 90, 100, 160}
+	colTypes := []ColumnType{hexCol, hexCol, strCol, hexCol, hexCol, hexCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForRuntimeFunctions(functions []pefile.RuntimeFunction, arm64 bool) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Begin RVA", "End RVA", "Section", "Unwind Data", "Details"},
+	}
+	var sizes []uint64
+
+	for _, function := range functions {
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", function.Offset),
+			fmt.Sprintf("0x%X", function.BeginAddress),
+			fmt.Sprintf("0x%X", function.EndAddress),
+			function.Section,
+			fmt.Sprintf("0x%X", function.UnwindData),
+			runtimeFunctionSummary(function, arm64),
+		})
+		sizes = append(sizes, runtimeFunctionSize(arm64))
+	}
+
+	colWidths := []float32{90, 90, 90, 80, 100, 500}
+	colTypes := []ColumnType{hexCol, hexCol, hexCol, strCol, hexCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+// runtimeFunctionSize is the size of an exception directory entry.
+func runtimeFunctionSize(arm64 bool) uint64 {
+	if arm64 {
+		return uint64(binary.Size(pefile.IMAGE_ARM64_RUNTIME_FUNCTION_ENTRY{}))
+	}
+	return uint64(binary.Size(pefile.IMAGE_RUNTIME_FUNCTION_ENTRY{}))
+}
+
+// runtimeFunctionSummary is the one line description of a function's
+// unwind data shown in the exception table.
+func runtimeFunctionSummary(function pefile.RuntimeFunction, arm64 bool) string {
+	var parts []string
+	if function.Err != nil {
+		parts = append(parts, function.Err.Error())
+	}
+	if packed := function.Packed; packed != nil {
+		parts = append(parts, fmt.Sprintf("Packed, RegI=%d RegF=%d H=%t, %s, frame 0x%X", packed.RegI, packed.RegF, packed.H, packed.CRName(), packed.FrameSize))
+		if packed.Flag == 2 {
+			parts = append(parts, "no prolog or epilog")
+		}
+	}
+	if unwind := function.Unwind; unwind != nil {
+		switch {
+		case arm64:
+			parts = append(parts, fmt.Sprintf("%d epilogs, %d code words", unwind.EpilogCount, unwind.CodeWords))
+		case unwind.Offset != 0:
+			parts = append(parts, fmt.Sprintf("v%d %s, prolog %d, %d codes", unwind.Version, strings.Join(unwind.FlagNames(), " | "), unwind.SizeOfProlog, unwind.CountOfCodes))
+			if frame := unwind.FrameRegisterName(); frame != "" {
+				parts = append(parts, "frame "+frame)
+			}
+		}
+		if unwind.HasHandler {
+			parts = append(parts, fmt.Sprintf("handler 0x%X", unwind.HandlerRVA))
+		}
+		if unwind.Chained != nil {
+			parts = append(parts, fmt.Sprintf("chained to 0x%X", unwind.Chained.BeginAddress))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// createTableForUnwindInfo lists the decoded fields of a function's unwind
+// data, following chained unwind infos.
+func createTableForUnwindInfo(function pefile.RuntimeFunction, arm64 bool) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Property", "Value"},
+	}
+	var sizes []uint64
+	add := func(offset uint32, size uint64, name string, value string) {
+		data = append(data, []string{fmt.Sprintf("0x%X", offset), name, value})
+		sizes = append(sizes, size)
+	}
+	entrySize := runtimeFunctionSize(arm64)
+
+	add(function.Offset, 4, "BeginAddress", fmt.Sprintf("0x%X", function.BeginAddress))
+	if arm64 {
+		add(function.Offset+4, 4, "UnwindData", fmt.Sprintf("0x%X", function.UnwindData))
+	} else {
+		add(function.Offset+4, 4, "EndAddress", fmt.Sprintf("0x%X", function.EndAddress))
+		add(function.Offset+8, 4, "UnwindInfoAddress", fmt.Sprintf("0x%X", function.UnwindData))
+	}
+	if function.Err != nil {
+		add(function.Offset, entrySize, "Error", function.Err.Error())
+	}
+
+	if packed := function.Packed; packed != nil {
+		add(function.Offset+4, 4, "Flag", fmt.Sprintf("%d", packed.Flag))
+		add(function.Offset+4, 4, "FunctionLength", fmt.Sprintf("0x%X", packed.FunctionLength))
+		add(function.Offset+4, 4, "RegF", fmt.Sprintf("%d", packed.RegF))
+		add(function.Offset+4, 4, "RegI", fmt.Sprintf("%d", packed.RegI))
+		add(function.Offset+4, 4, "H", fmt.Sprintf("%t", packed.H))
+		add(function.Offset+4, 4, "CR", fmt.Sprintf("%d (%s)", packed.CR, packed.CRName()))
+		add(function.Offset+4, 4, "FrameSize", fmt.Sprintf("0x%X", packed.FrameSize))
+	}
+
+	for unwind := function.Unwind; unwind != nil; {
+		switch {
+		case arm64:
+			// The header fields share its first word
+			add(unwind.Offset, 4, "FunctionLength", fmt.Sprintf("0x%X", unwind.FunctionLength))
+			add(unwind.Offset, 4, "Version", fmt.Sprintf("%d", unwind.Version))
+			add(unwind.Offset, 4, "X", fmt.Sprintf("%t", unwind.HasHandler))
+			add(unwind.Offset, 4, "E", fmt.Sprintf("%t", unwind.EpilogInHeader))
+			add(unwind.Offset, 4, "EpilogCount", fmt.Sprintf("%d", unwind.EpilogCount))
+			add(unwind.Offset, 4, "CodeWords", fmt.Sprintf("%d", unwind.CodeWords))
+			for _, scope := range unwind.EpilogScopes {
+				add(scope.Offset, 4, "Epilog", fmt.Sprintf("RVA 0x%X, first code %d", function.BeginAddress+scope.StartOffset, scope.StartIndex))
+			}
+		case unwind.Offset != 0:
+			add(unwind.Offset, 1, "Version", fmt.Sprintf("%d", unwind.Version))
+			add(unwind.Offset, 1, "Flags", strings.Join(unwind.FlagNames(), " | "))
+			add(unwind.Offset+1, 1, "SizeOfProlog", fmt.Sprintf("%d", unwind.SizeOfProlog))
+			add(unwind.Offset+2, 1, "CountOfCodes", fmt.Sprintf("%d", unwind.CountOfCodes))
+			frame := unwind.FrameRegisterName()
+			if frame == "" {
+				frame = "none"
+			}
+			add(unwind.Offset+3, 1, "FrameRegister", frame)
+			add(unwind.Offset+3, 1, "FrameOffset", fmt.Sprintf("0x%X", uint32(unwind.FrameOffset)*16))
+		}
+		if unwind.HasHandler {
+			add(unwind.HandlerOffset, 4, "ExceptionHandler", fmt.Sprintf("0x%X", unwind.HandlerRVA))
+			add(unwind.HandlerOffset+4, uint64(len(unwind.HandlerData)), "HandlerData", fmt.Sprintf("RVA 0x%X: % X", unwind.HandlerDataRVA, unwind.HandlerData))
+		}
+
+		chained := unwind.Chained
+		if chained == nil {
+			break
+		}
+		add(chained.Offset, entrySize, "Chained function", fmt.Sprintf("0x%X-0x%X, unwind info 0x%X", chained.BeginAddress, chained.EndAddress, chained.UnwindData))
+		if chained.Err != nil {
+			add(chained.Offset, entrySize, "Error", chained.Err.Error())
+		}
+		unwind = chained.Unwind
+	}
+
+	colWidths := []float32{90, 200, 500}
+	colTypes := []ColumnType{hexCol, strCol, unsortableCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {false, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+// createTableForUnwindCodes lists the unwind codes of a function together
+// with those of the unwind infos it is chained to.
+func createTableForUnwindCodes(function pefile.RuntimeFunction) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Function", "Code", "Prolog Offset", "Operation", "Instruction"},
+	}
+	var sizes []uint64
+
+	for current := &function; current != nil && current.Unwind != nil; current = current.Unwind.Chained {
+		for _, code := range current.Unwind.Codes {
+			prologOffset := "N/A"
+			if code.CodeOffset >= 0 {
+				prologOffset = fmt.Sprintf("0x%X", code.CodeOffset)
+			}
+			data = append(data, []string{
+				fmt.Sprintf("0x%X", code.Offset),
+				fmt.Sprintf("0x%X", current.BeginAddress),
+				fmt.Sprintf("%X", code.Raw),
+				prologOffset,
+				code.Op,
+				code.Text,
+			})
+			sizes = append(sizes, uint64(len(code.Raw)))
+		}
+	}
+
+	colWidths := []float32{90, 90, 100, 100, 220, 300}
+	colTypes := []ColumnType{hexCol, hexCol, strCol, hexCol, strCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+// certificateTimeFormat is how signing and validity times are shown.
+const certificateTimeFormat = "2006-01-02 15:04:05 MST"
+
+func createTableForCertificateEntries(entries []pefile.CertificateEntry) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Length", "Revision", "Type", "Details"},
+	}
+	var sizes []uint64
+
+	for _, entry := range entries {
+		details := ""
+		switch {
+		case entry.Err != nil:
+			details = entry.Err.Error()
+		case entry.Signature != nil:
+			details = signatureSummary(entry.Signature)
+		}
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", entry.Offset),
+			fmt.Sprintf("%d", entry.Header.Length),
+			fmt.Sprintf("0x%04X", entry.Header.Revision),
+			pefile.CertificateTypeName(entry.Header.CertificateType),
+			details,
+		})
+		sizes = append(sizes, uint64(entry.Header.Length))
+	}
+
+	colWidths := []float32{90, 70, 80, 150, 500}
+	colTypes := []ColumnType{hexCol, decCol, hexCol, strCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+// signatureSummary names who signed a signature and with which digest.
+func signatureSummary(signature *pefile.Signature) string {
+	parts := []string{signature.FileDigestAlgorithm}
+	for _, signer := range signature.Signers {
+		if signer.Certificate != nil {
+			parts = append(parts, signer.Certificate.Subject.CommonName)
+		}
+	}
+	if len(signature.Nested) > 0 {
+		parts = append(parts, fmt.Sprintf("%d nested", len(signature.Nested)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// createTableForSignature lists the signers of a signature together with
+// their timestamps and any nested signatures.
+func createTableForSignature(signature *pefile.Signature) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Property", "Value"},
+	}
+	var sizes []uint64
+	// Every property is read from the DER encoding as a whole
+	add := func(name string, value string) {
+		data = append(data, []string{fmt.Sprintf("0x%X", signature.Offset), name, value})
+		sizes = append(sizes, uint64(len(signature.Raw)))
+	}
+	addSigner := func(prefix string, signer pefile.SignerInfo) {
+		if signer.Certificate != nil {
+			add(prefix+"Signer", signer.Certificate.Subject.String())
+		} else {
+			add(prefix+"Signer", "certificate not included")
+		}
+		if signer.SubjectKeyID != nil {
+			add(prefix+"Subject key ID", fmt.Sprintf("%X", signer.SubjectKeyID))
+		} else {
+			add(prefix+"Issuer", signer.Issuer)
+			add(prefix+"Serial number", fmt.Sprintf("%X", signer.SerialNumber))
+		}
+		add(prefix+"Digest algorithm", signer.DigestAlgorithm)
+		add(prefix+"Signature algorithm", signer.EncryptionAlgorithm)
+		if signer.MessageDigest != nil {
+			add(prefix+"Message digest", fmt.Sprintf("%X", signer.MessageDigest))
+		}
+		if !signer.SigningTime.IsZero() {
+			add(prefix+"Signing time", signer.SigningTime.UTC().Format(certificateTimeFormat))
+		}
+		if signer.ProgramName != "" {
+			add(prefix+"Program name", signer.ProgramName)
+		}
+		if signer.MoreInfo != "" {
+			add(prefix+"More info", signer.MoreInfo)
+		}
+	}
+
+	add("Content type", signature.ContentType)
+	add("Digest algorithm", signature.DigestAlgorithm)
+	if signature.FileDigest != nil {
+		add("File digest", fmt.Sprintf("%s %X", signature.FileDigestAlgorithm, signature.FileDigest))
+	}
+	add("Certificates", fmt.Sprintf("%d", len(signature.Certificates)))
+
+	for _, signer := range signature.Signers {
+		addSigner("", signer)
+		for _, countersignature := range signer.Countersignatures {
+			add("Timestamp", fmt.Sprintf("%s, %s", countersignature.Type, countersignature.Time.UTC().Format(certificateTimeFormat)))
+			if countersignature.SerialNumber != nil {
+				add("Timestamp serial number", fmt.Sprintf("%X", countersignature.SerialNumber))
+			}
+			if countersignature.Policy != "" {
+				add("Timestamp policy", countersignature.Policy)
+			}
+			addSigner("Timestamp ", countersignature.Signer)
+		}
+	}
 
-		// If there are still named exports left *and* this slot matches nameOrdinals
-		if uint32(nameIndex) < exportHeader.NumberOfNames {
-			ordinal, exists := ordToNameId[uint16(nameIndex)]
-			if exists {
-				// read the ASCII name at names[nameIndex]
-				if nameStr, err := readStringFromRVA(peFile, fileData, names[ordinal]); err == nil {
-					name = nameStr
-					nameRva = fmt.Sprintf("0x%X", names[ordinal])
+	for _, nested := range signature.Nested {
+		add("Nested signature", signatureSummary(nested))
+	}
+
+	colWidths := []float32{90, 200, 600}
+	colTypes := []ColumnType{hexCol, strCol, unsortableCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {false, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+// createTableForSignatureCertificates lists the certificates shipped with a
+// signature and its timestamps, marking the signer chains.
+func createTableForSignatureCertificates(signature *pefile.Signature) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Role", "Subject", "Issuer", "Serial Number", "Valid From", "Valid To", "Algorithm"},
+	}
+	var sizes []uint64
+
+	roles := map[*x509.Certificate]string{}
+	certificates := append([]*x509.Certificate{}, signature.Certificates...)
+	for _, signer := range signature.Signers {
+		for i, cert := range pefile.CertificateChain(signer.Certificate, signature.Certificates) {
+			if i == 0 {
+				roles[cert] = "Signer"
+			} else if roles[cert] == "" {
+				roles[cert] = "Signer chain"
+			}
+		}
+		for _, countersignature := range signer.Countersignatures {
+			certificates = append(certificates, countersignature.Certificates...)
+			pool := append(append([]*x509.Certificate{}, countersignature.Certificates...), signature.Certificates...)
+			for i, cert := range pefile.CertificateChain(countersignature.Signer.Certificate, pool) {
+				if i == 0 {
+					roles[cert] = "Timestamp signer"
+				} else if roles[cert] == "" {
+					roles[cert] = "Timestamp chain"
 				}
-			} else {
-				fmt.Printf("no ordinal at index 0x%x\n", nameIndex)
 			}
-			nameIndex++
 		}
+	}
 
+	for _, cert := range certificates {
+		offset := "N/A"
+		if certOffset := signature.CertificateOffset(cert); certOffset != 0 {
+			offset = fmt.Sprintf("0x%X", certOffset)
+		}
 		data = append(data, []string{
-			fmt.Sprintf("0x%X", offset),       // file offset of this function entry
-			fmt.Sprintf("0x%X", realOrdinal),  // actual ordinal we display
-			fmt.Sprintf("0x%X", functions[i]), // RVA
-			nameRva,                           // name RVA if present
-			name,                              // function name if present
+			offset,
+			roles[cert],
+			cert.Subject.String(),
+			cert.Issuer.String(),
+			fmt.Sprintf("%X", cert.SerialNumber),
+			cert.NotBefore.UTC().Format(certificateTimeFormat),
+			cert.NotAfter.UTC().Format(certificateTimeFormat),
+			cert.SignatureAlgorithm.String(),
 		})
+		sizes = append(sizes, uint64(len(cert.Raw)))
+	}
 
-		offset += 4 // each entry is a 4-byte RVA
+	colWidths := []float32{90, 130, 300, 300, 200, 170, 170, 120}
+	colTypes := []ColumnType{hexCol, strCol, strCol, strCol, strCol, strCol, strCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForAuthenticode(results []pefile.AuthenticodeResult) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Algorithm", "Computed Digest", "Image Digest", "Message Digest", "Signer Signature", "Chain", "Verdict"},
 	}
+	var sizes []uint64
 
-	colWidths := []float32{90, 65, 100, 90, 700}
+	matchText := func(match bool) string {
+		if match {
+			return "match"
+		}
+		return "mismatch"
+	}
+	for _, result := range results {
+		signatureText, chainText := "valid", "N/A"
+		if result.SignatureErr != nil {
+			signatureText = result.SignatureErr.Error()
+		}
+		switch {
+		case result.ChainErr != nil:
+			chainText = result.ChainErr.Error()
+		case len(result.Chain) > 0:
+			chainText = "trusted, root " + result.Chain[len(result.Chain)-1].Subject.CommonName
+		}
+		// The chain is checked at the time of a trusted timestamp only
+		switch {
+		case !result.Timestamp.IsZero():
+			chainText += ", at timestamp " + result.Timestamp.UTC().Format(certificateTimeFormat)
+		case result.TimestampErr != nil:
+			chainText += ", timestamp not trusted: " + result.TimestampErr.Error()
+		}
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", result.Signature.Offset),
+			result.Signature.FileDigestAlgorithm,
+			fmt.Sprintf("%X", result.ComputedDigest),
+			matchText(result.DigestMatches),
+			matchText(result.MessageDigestMatches),
+			signatureText,
+			chainText,
+			result.Verdict,
+		})
+		sizes = append(sizes, uint64(len(result.Signature.Raw)))
+	}
+
+	colWidths := []float32{90, 80, 300, 90, 100, 150, 300, 120}
+	colTypes := []ColumnType{hexCol, strCol, strCol, strCol, strCol, strCol, strCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForDebugEntries(entries []pefile.DebugEntry) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Type", "TimeDateStamp", "Version", "Size", "RVA", "Pointer", "Details"},
+	}
+	var sizes []uint64
+
+	for _, entry := range entries {
+		dir := entry.Directory
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", entry.Offset),
+			entry.TypeName,
+			fmt.Sprintf("0x%X", dir.TimeDateStamp),
+			fmt.Sprintf("%d.%d", dir.MajorVersion, dir.MinorVersion),
+			fmt.Sprintf("%d", dir.SizeOfData),
+			fmt.Sprintf("0x%X", dir.AddressOfRawData),
+			fmt.Sprintf("0x%X", dir.PointerToRawData),
+			debugEntrySummary(entry),
+		})
+		sizes = append(sizes, uint64(binary.Size(dir)))
+	}
+
+	colWidths := []float32{90, 200, 110, 70, 70, 90, 90, 500}
+	colTypes := []ColumnType{hexCol, strCol, hexCol, strCol, decCol, hexCol, hexCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+// debugEntrySummary is the one line description of a debug record shown in
+// the debug directory table.
+func debugEntrySummary(entry pefile.DebugEntry) string {
+	switch {
+	case entry.Err != nil:
+		return entry.Err.Error()
+	case entry.CodeView != nil:
+		return fmt.Sprintf("%s %s", entry.CodeView.PdbPath, entry.CodeView.SymbolServerKey())
+	case entry.Pogo != nil:
+		return fmt.Sprintf("%s, %d sections", entry.Pogo.Signature, len(entry.Pogo.Entries))
+	case entry.VCFeature != nil:
+		f := entry.VCFeature
+		return fmt.Sprintf("Pre-VC++ 11.00=%d, C/C++=%d, /GS=%d, /sdl=%d, guardN=%d", f.PreVC11, f.CCpp, f.Gs, f.Sdl, f.GuardN)
+	case entry.Directory.Type == pefile.IMAGE_DEBUG_TYPE_REPRO:
+		if len(entry.Repro) == 0 {
+			return "Deterministic build"
+		}
+		return fmt.Sprintf("%X", entry.Repro)
+	case entry.Directory.Type == pefile.IMAGE_DEBUG_TYPE_EX_DLLCHARACTERISTICS:
+		return strings.Join(entry.ExDllFlags, " | ")
+	case entry.EmbeddedPdb != nil:
+		return fmt.Sprintf("%d bytes compressed, %d uncompressed", entry.EmbeddedPdb.CompressedSize, entry.EmbeddedPdb.UncompressedSize)
+	case entry.PdbChecksum != nil:
+		return fmt.Sprintf("%s %X", entry.PdbChecksum.Algorithm, entry.PdbChecksum.Checksum)
+	}
+	return ""
+}
+
+// createTableForDebugRecord lists the decoded fields of a debug record.
+func createTableForDebugRecord(entry pefile.DebugEntry) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Property", "Value"},
+	}
+	var sizes []uint64
+	offset := entry.Directory.PointerToRawData
+	add := func(fieldOffset uint32, size uint64, name string, value string) {
+		data = append(data, []string{fmt.Sprintf("0x%X", offset+fieldOffset), name, value})
+		sizes = append(sizes, size)
+	}
+	// Values derived from the whole record cover all of it
+	record := uint64(entry.Directory.SizeOfData)
+
+	switch {
+	case entry.Err != nil:
+		add(0, record, "Error", entry.Err.Error())
+	case entry.CodeView != nil:
+		cv := entry.CodeView
+		add(0, 4, "Signature", cv.Signature)
+		if cv.Signature == "NB10" {
+			add(4, 4, "Offset", fmt.Sprintf("0x%X", cv.Offset))
+			add(8, 4, "TimeStamp", fmt.Sprintf("0x%X", cv.TimeStamp))
+			add(12, 4, "Age", fmt.Sprintf("%d", cv.Age))
+			add(16, uint64(len(cv.PdbPath))+1, "PdbFileName", cv.PdbPath)
+		} else {
+			add(4, 16, "Guid", cv.GUIDString())
+			add(20, 4, "Age", fmt.Sprintf("%d", cv.Age))
+			add(24, uint64(len(cv.PdbPath))+1, "PdbFileName", cv.PdbPath)
+		}
+		add(0, record, "Symbol server key", cv.SymbolServerKey())
+	case entry.Pogo != nil:
+		add(0, 4, "Signature", entry.Pogo.Signature)
+		for _, pogo := range entry.Pogo.Entries {
+			data = append(data, []string{fmt.Sprintf("0x%X", pogo.Offset), pogo.Name,
+				fmt.Sprintf("RVA 0x%X, size 0x%X", pogo.RVA, pogo.Size)})
+			sizes = append(sizes, 8+uint64(len(pogo.Name))+1)
+		}
+	case entry.VCFeature != nil:
+		f := entry.VCFeature
+		add(0, 4, "Pre-VC++ 11.00", fmt.Sprintf("%d", f.PreVC11))
+		add(4, 4, "C/C++", fmt.Sprintf("%d", f.CCpp))
+		add(8, 4, "/GS", fmt.Sprintf("%d", f.Gs))
+		add(12, 4, "/sdl", fmt.Sprintf("%d", f.Sdl))
+		add(16, 4, "guardN", fmt.Sprintf("%d", f.GuardN))
+	case entry.EmbeddedPdb != nil:
+		add(0, 4, "Signature", "MPDB")
+		add(4, 4, "Uncompressed size", fmt.Sprintf("%d", entry.EmbeddedPdb.UncompressedSize))
+		add(8, 4, "Compressed size", fmt.Sprintf("%d", entry.EmbeddedPdb.CompressedSize))
+		add(8, record-8, "Portable PDB", fmt.Sprintf("%t", entry.EmbeddedPdb.Valid))
+	case entry.PdbChecksum != nil:
+		add(0, uint64(len(entry.PdbChecksum.Algorithm))+1, "Algorithm", entry.PdbChecksum.Algorithm)
+		add(uint32(len(entry.PdbChecksum.Algorithm)+1), uint64(len(entry.PdbChecksum.Checksum)), "Checksum", fmt.Sprintf("%X", entry.PdbChecksum.Checksum))
+	default:
+		add(0, record, entry.TypeName, debugEntrySummary(entry))
+	}
+
+	colWidths := []float32{90, 200, 600}
+	colTypes := []ColumnType{hexCol, strCol, unsortableCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {false, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForRichHeader(rich *pefile.RichHeader) (*sortableTable, error) {
+	checksum := fmt.Sprintf("0x%08X (valid)", rich.Checksum)
+	if !rich.Valid() {
+		checksum = fmt.Sprintf("0x%08X (mismatch)", rich.Checksum)
+	}
+	richOffset := rich.Offset + rich.Size - 8
+
+	data := [][]string{
+		{"Offset", "Field", "Value"},
+		{fmt.Sprintf("0x%X", rich.Offset), "DanS", fmt.Sprintf("%d entries", len(rich.Entries))},
+		{fmt.Sprintf("0x%X", richOffset), "Rich", fmt.Sprintf("%d bytes", rich.Size)},
+		{fmt.Sprintf("0x%X", richOffset+4), "Key", fmt.Sprintf("0x%08X", rich.Key)},
+		{fmt.Sprintf("0x%X", richOffset+4), "Checksum", checksum},
+	}
+	// The entries run up to "Rich"; the checksum is stored as the key
+	sizes := []uint64{uint64(richOffset - rich.Offset), 4, 4, 4}
+
+	colWidths := []float32{90, 150, 300}
+	colTypes := []ColumnType{hexCol, strCol, unsortableCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {false, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForRichEntries(entries []pefile.RichEntry) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Product ID", "Product", "Build", "Count", "Visual Studio"},
+	}
+	var sizes []uint64
+
+	for _, entry := range entries {
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", entry.Offset),
+			fmt.Sprintf("0x%X", entry.ProductID),
+			entry.ProductName(),
+			fmt.Sprintf("%d", entry.Build),
+			fmt.Sprintf("%d", entry.Count),
+			entry.VisualStudioVersion(),
+		})
+		sizes = append(sizes, 8) // the @comp.id and count dwords
+	}
+
+	colWidths := []float32{90, 90, 200, 70, 70, 200}
+	colTypes := []ColumnType{hexCol, hexCol, strCol, decCol, decCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForTlsCallbacks(callbacks []pefile.TlsCallback, vaSize uint64) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Callback VA", "RVA", "File Offset", "Section"},
+	}
+	var sizes []uint64
+
+	for _, callback := range callbacks {
+		fileOffset := "N/A"
+		if callback.FileOffset != 0 {
+			fileOffset = fmt.Sprintf("0x%X", callback.FileOffset)
+		}
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", callback.Offset), // file offset of the array slot
+			fmt.Sprintf("0x%X", callback.VA),
+			fmt.Sprintf("0x%X", callback.RVA),
+			fileOffset,
+			callback.Section,
+		})
+		sizes = append(sizes, vaSize)
+	}
+
+	colWidths := []float32{90, 160, 90, 100, 100}
 	colTypes := []ColumnType{hexCol, hexCol, hexCol, hexCol, strCol}
 	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
-	return createNewSortableTable(colWidths, data, colTypes, colProps)
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
 }
 
-func createNewSortableTable(colWidths []float32, data [][]string, colTypes []ColumnType, colProps []ColumnProps) (*sortableTable, error) {
+func createTableForGuardTable(table *pefile.GuardTable) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "RVA", "Section", "Flags"},
+	}
+	var sizes []uint64
+
+	for _, entry := range table.Entries {
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", entry.Offset),
+			fmt.Sprintf("0x%X", entry.RVA),
+			entry.Section,
+			strings.Join(entry.FlagNames(), " | "),
+		})
+		sizes = append(sizes, uint64(table.Stride))
+	}
+
+	colWidths := []float32{90, 90, 100, 300}
+	colTypes := []ColumnType{hexCol, hexCol, strCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForResourceEntries(entries []pefile.ResourceEntry) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Name / ID", "Name", "OffsetToData", "Kind", "Data RVA", "Data Offset", "Size", "CodePage"},
+	}
+	var sizes []uint64
+
+	for _, entry := range entries {
+		label := entry.Label()
+		if !entry.IsNamed && entry.Level == 0 {
+			label = fmt.Sprintf("%s (%d)", label, entry.ID)
+		}
+
+		row := []string{
+			fmt.Sprintf("0x%X", entry.Offset),
+			label,
+			fmt.Sprintf("0x%X", entry.Entry.Name),
+			fmt.Sprintf("0x%X", entry.Entry.OffsetToData),
+		}
+		if entry.Data != nil {
+			row = append(row,
+				"Data",
+				fmt.Sprintf("0x%X", entry.Data.Entry.OffsetToData),
+				fmt.Sprintf("0x%X", entry.Data.DataOffset),
+				fmt.Sprintf("%d", entry.Data.Entry.Size),
+				fmt.Sprintf("%d", entry.Data.Entry.CodePage))
+		} else {
+			row = append(row, "Directory", "N/A", "N/A", "N/A", "N/A")
+		}
+		data = append(data, row)
+		sizes = append(sizes, uint64(binary.Size(entry.Entry)))
+	}
+
+	colWidths := []float32{90, 200, 100, 110, 90, 100, 100, 90, 90}
+	colTypes := []ColumnType{hexCol, strCol, hexCol, hexCol, strCol, hexCol, hexCol, decCol, decCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createNewSortableTable(colWidths []float32, data [][]string, sizes []uint64, colTypes []ColumnType, colProps []ColumnProps) (*sortableTable, error) {
 
 	// Measure row heights (assuming measureRowsHeights supports 4 columns)
 	colHeights := measureRowsHeights(data, colWidths)
 
-	st := newSortableTable(data, colWidths, colTypes, colProps)
+	st := newSortableTable(data, sizes, colWidths, colTypes, colProps)
 
 	// Apply column widths
 	for colIndex, width := range colWidths {
@@ -551,3 +1748,149 @@ func createNewSortableTable(colWidths []float32, data [][]string, colTypes []Col
 	}
 	return st, nil
 }
+
+// appendFieldMeaning adds a meaning that needs more than the struct itself
+// to the Meaning of a createTableFromStruct row.
+func appendFieldMeaning(table *sortableTable, field string, meaning string) {
+	for _, row := range table.data[1:] {
+		if row[1] != field {
+			continue
+		}
+		if row[3] != "" {
+			row[3] += "; "
+		}
+		row[3] += meaning
+	}
+	table.updateRowHeights()
+}
+
+func createTableForMetadataRoot(metadata *pefile.Metadata) (*sortableTable, error) {
+	offset := metadata.Offset
+	data := [][]string{
+		{"Offset", "Field", "Value"},
+		{fmt.Sprintf("0x%X", offset), "Signature", fmt.Sprintf("0x%X (BSJB)", metadata.Root.Signature)},
+		{fmt.Sprintf("0x%X", offset+4), "MajorVersion", fmt.Sprintf("%d", metadata.Root.MajorVersion)},
+		{fmt.Sprintf("0x%X", offset+6), "MinorVersion", fmt.Sprintf("%d", metadata.Root.MinorVersion)},
+		{fmt.Sprintf("0x%X", offset+8), "Reserved", fmt.Sprintf("0x%X", metadata.Root.Reserved)},
+		{fmt.Sprintf("0x%X", offset+12), "Length", fmt.Sprintf("%d", metadata.Root.Length)},
+		{fmt.Sprintf("0x%X", offset+16), "Version", metadata.Version},
+		{fmt.Sprintf("0x%X", offset+16+metadata.Root.Length), "Flags", fmt.Sprintf("0x%X", metadata.Flags)},
+		{fmt.Sprintf("0x%X", offset+18+metadata.Root.Length), "Streams", fmt.Sprintf("%d", len(metadata.Streams))},
+	}
+	sizes := []uint64{4, 2, 2, 4, 4, uint64(metadata.Root.Length), 2, 2}
+
+	colWidths := []float32{90, 150, 300}
+	colTypes := []ColumnType{hexCol, strCol, unsortableCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {false, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForMetadataStreams(streams []pefile.MetadataStream) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Name", "Stream Offset", "File Offset", "Size"},
+	}
+	var sizes []uint64
+
+	for _, stream := range streams {
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", stream.HeaderOffset),
+			stream.Name,
+			fmt.Sprintf("0x%X", stream.Offset),
+			fmt.Sprintf("0x%X", stream.FileOffset),
+			fmt.Sprintf("%d", stream.Size),
+		})
+		// Offset and Size, then the name padded to 4 bytes
+		sizes = append(sizes, uint64(8+(len(stream.Name)+4)&^3))
+	}
+
+	colWidths := []float32{90, 120, 120, 120, 100}
+	colTypes := []ColumnType{hexCol, strCol, hexCol, hexCol, decCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForMetadataTables(tables *pefile.MetadataTables) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Number", "Table", "Rows", "Row Size", "Sorted"},
+	}
+	var sizes []uint64
+
+	for _, table := range tables.Tables {
+		sorted := "No"
+		if tables.Header.Sorted&(1<<table.ID) != 0 {
+			sorted = "Yes"
+		}
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", table.Offset),
+			fmt.Sprintf("0x%02X", table.ID),
+			table.Name,
+			fmt.Sprintf("%d", table.RowCount),
+			fmt.Sprintf("%d", table.RowSize),
+			sorted,
+		})
+		sizes = append(sizes, uint64(table.RowCount)*uint64(table.RowSize))
+	}
+
+	colWidths := []float32{90, 80, 200, 80, 90, 80}
+	colTypes := []ColumnType{hexCol, hexCol, strCol, decCol, decCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+// createTableForMetadataRows lists the rows of a metadata table, with heap
+// indexes and references to other tables resolved.
+func createTableForMetadataRows(metadata *pefile.Metadata, table *pefile.MetadataTable) (*sortableTable, error) {
+	header := []string{"Offset", "Token"}
+	colWidths := []float32{90, 100}
+	colTypes := []ColumnType{hexCol, hexCol}
+	colProps := []ColumnProps{{true, true}, {true, true}}
+	for _, column := range table.Columns {
+		header = append(header, column.Name)
+		colProps = append(colProps, ColumnProps{true, true})
+		if column.Numeric() {
+			colWidths = append(colWidths, 100)
+			colTypes = append(colTypes, hexCol)
+		} else {
+			colWidths = append(colWidths, 250)
+			colTypes = append(colTypes, strCol)
+		}
+	}
+
+	data := [][]string{header}
+	var sizes []uint64
+	for i, values := range table.Rows {
+		row := []string{
+			fmt.Sprintf("0x%X", table.Offset+uint32(i)*table.RowSize),
+			fmt.Sprintf("0x%08X", pefile.Token(table.ID, uint32(i+1))),
+		}
+		for j, column := range table.Columns {
+			row = append(row, metadata.FormatValue(column, values[j]))
+		}
+		data = append(data, row)
+		sizes = append(sizes, uint64(table.RowSize))
+	}
+
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
+
+func createTableForHeapEntries(entries []pefile.HeapEntry) (*sortableTable, error) {
+	data := [][]string{
+		{"Offset", "Index", "Size", "Value"},
+	}
+	var sizes []uint64
+
+	for _, entry := range entries {
+		data = append(data, []string{
+			fmt.Sprintf("0x%X", entry.FileOffset),
+			fmt.Sprintf("0x%X", entry.Index),
+			fmt.Sprintf("%d", entry.Size),
+			entry.Value,
+		})
+		sizes = append(sizes, uint64(entry.Size))
+	}
+
+	colWidths := []float32{90, 90, 70, 600}
+	colTypes := []ColumnType{hexCol, hexCol, decCol, strCol}
+	colProps := []ColumnProps{{true, true}, {true, true}, {true, true}, {true, true}}
+	return createNewSortableTable(colWidths, data, sizes, colTypes, colProps)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"PEGo/pefile"
+)
+
+const (
+	hexBytesPerRow = 16
+	// Columns of a row: "00000000  00 01 .. 07  08 .. 0F  |0123456789ABCDEF|"
+	hexFirstByteCol = 10
+	hexAsciiCol     = hexFirstByteCol + hexBytesPerRow*3 + 3
+	hexRowWidth     = hexAsciiCol + hexBytesPerRow + 1
+)
+
+// hexRegion is a part of the file drawn with its own background.
+type hexRegion struct {
+	start, end uint64
+	name       string
+	background color.Color
+}
+
+// hexView shows the bytes of the open file as offset, hex and ASCII
+// columns, colored by the structure they belong to.
+type hexView struct {
+	list    *widget.List
+	info    *widget.Label
+	content fyne.CanvasObject
+
+	data    []byte
+	regions []hexRegion
+	// the highlighted byte range, empty when selEnd == selStart
+	selStart, selEnd uint64
+}
+
+// fileHexView is the hex pane of the window. Selecting a row of any
+// sortableTable highlights the row's bytes in it.
+var fileHexView *hexView
+
+func newHexView() *hexView {
+	h := &hexView{info: widget.NewLabel("No file is open")}
+	h.list = widget.NewList(
+		func() int {
+			return (len(h.data) + hexBytesPerRow - 1) / hexBytesPerRow
+		},
+		func() fyne.CanvasObject {
+			// Start from a full blank row so the list sizes its items to it
+			return widget.NewTextGridFromString(strings.Repeat(" ", hexRowWidth))
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			grid := obj.(*widget.TextGrid)
+			grid.SetRow(0, h.row(uint64(id)*hexBytesPerRow))
+			grid.Refresh()
+		},
+	)
+	h.content = container.NewBorder(h.info, nil, nil, nil, h.list)
+	return h
+}
+
+// setFile shows the bytes of a newly opened file.
+func (h *hexView) setFile(peFull *pefile.PeFull) {
+	h.data = peFull.FileData
+	h.regions = fileRegions(peFull)
+	h.selStart, h.selEnd = 0, 0
+	h.info.SetText(fmt.Sprintf("%d bytes", len(h.data)))
+	h.list.ScrollToTop()
+	h.list.Refresh()
+}
+
+// highlight scrolls to size bytes at offset and marks them.
+func (h *hexView) highlight(offset uint64, size uint64) {
+	if offset >= uint64(len(h.data)) {
+		h.info.SetText(fmt.Sprintf("0x%X is past the end of the file", offset))
+		return
+	}
+	h.selStart = offset
+	h.selEnd = min(offset+max(size, 1), uint64(len(h.data)))
+
+	info := fmt.Sprintf("0x%X - 0x%X (%d bytes)", h.selStart, h.selEnd-1, h.selEnd-h.selStart)
+	if region := h.regionAt(offset); region != nil {
+		info += " in " + region.name
+	}
+	h.info.SetText(info)
+	h.list.ScrollTo(widget.ListItemID(offset / hexBytesPerRow))
+	h.list.Refresh()
+}
+
+func (h *hexView) regionAt(offset uint64) *hexRegion {
+	for i := range h.regions {
+		if offset >= h.regions[i].start && offset < h.regions[i].end {
+			return &h.regions[i]
+		}
+	}
+	return nil
+}
+
+// row lays out the bytes of the row that starts at offset.
+func (h *hexView) row(offset uint64) widget.TextGridRow {
+	cells := make([]widget.TextGridCell, hexRowWidth)
+	for i := range cells {
+		cells[i].Rune = ' '
+	}
+	for i, r := range fmt.Sprintf("%08X", offset) {
+		cells[i].Rune = r
+	}
+	cells[hexAsciiCol-1].Rune = '|'
+
+	end := min(offset+hexBytesPerRow, uint64(len(h.data)))
+	for pos := offset; pos < end; pos++ {
+		i := int(pos - offset)
+		b := h.data[pos]
+		hexCol := hexFirstByteCol + i*3
+		if i >= hexBytesPerRow/2 {
+			hexCol++
+		}
+		digits := fmt.Sprintf("%02X", b)
+		ascii := '.'
+		if b >= 0x20 && b < 0x7F {
+			ascii = rune(b)
+		}
+
+		style := h.byteStyle(pos)
+		cells[hexCol] = widget.TextGridCell{Rune: rune(digits[0]), Style: style}
+		cells[hexCol+1] = widget.TextGridCell{Rune: rune(digits[1]), Style: style}
+		cells[hexAsciiCol+i] = widget.TextGridCell{Rune: ascii, Style: style}
+		// Keep the highlight unbroken between the bytes of the selection
+		if pos >= h.selStart && pos+1 < h.selEnd && i != hexBytesPerRow-1 {
+			cells[hexCol+2].Style = style
+			if i == hexBytesPerRow/2-1 {
+				cells[hexCol+3].Style = style
+			}
+		}
+	}
+	cells[hexAsciiCol+int(end-offset)].Rune = '|'
+
+	return widget.TextGridRow{Cells: cells}
+}
+
+func (h *hexView) byteStyle(pos uint64) widget.TextGridStyle {
+	if pos >= h.selStart && pos < h.selEnd {
+		return &widget.CustomTextGridStyle{
+			TextStyle: fyne.TextStyle{Bold: true},
+			BGColor:   tintColor(theme.ColorNamePrimary, 0xA0),
+		}
+	}
+	if region := h.regionAt(pos); region != nil {
+		return &widget.CustomTextGridStyle{BGColor: region.background}
+	}
+	return nil
+}
+
+// fileRegions splits the file into the headers, the raw data of each
+// section and the overlay.
+func fileRegions(peFull *pefile.PeFull) []hexRegion {
+	regions := []hexRegion{{0, uint64(peFull.SizeOfHeaders()), "Headers", tintColor(theme.ColorNameHyperlink, 0x30)}}
+	for i, section := range peFull.PeFile.Sections {
+		if section.Size == 0 {
+			continue
+		}
+		// Neighbouring sections alternate so the boundary stays visible
+		background := tintColor(theme.ColorNameSuccess, 0x30)
+		if i%2 == 1 {
+			background = tintColor(theme.ColorNameSuccess, 0x18)
+		}
+		start := uint64(section.Offset)
+		regions = append(regions, hexRegion{start, start + uint64(section.Size), section.Name, background})
+	}
+	if overlay := uint64(peFull.OverlayOffset()); overlay < uint64(len(peFull.FileData)) {
+		regions = append(regions, hexRegion{overlay, uint64(len(peFull.FileData)), "Overlay", tintColor(theme.ColorNameWarning, 0x30)})
+	}
+	return regions
+}
+
+// tintColor is a theme color made translucent enough to put text on.
+func tintColor(name fyne.ThemeColorName, alpha uint8) color.Color {
+	r, g, b, _ := theme.Color(name).RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: alpha}
+}
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"os"
+
 	"fyne.io/fyne/v2/app"
 )
 
 func main() {
 
+	// "pego dump <file>" prints the parsed file instead of opening a window
+	if len(os.Args) > 1 && os.Args[1] == "dump" {
+		os.Exit(runDump(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Create the application
 	MyApp = app.New()
 	myWindow := MyApp.NewWindow("PEGo")
@@ -1,12 +1,14 @@
 package main
 
 import (
-	"bytes"
-	"debug/pe"
-	"encoding/binary"
 	"fmt"
+	"strings"
 
+	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"PEGo/pefile"
 )
 
 func displayFileProperties(ui *MyAppUI, fileProperties FileProperties) {
@@ -17,7 +19,7 @@ func displayFileProperties(ui *MyAppUI, fileProperties FileProperties) {
 		displayErrorOnRightPane(ui, err.Error())
 		return
 	}
-	resourcesTable, err := createTableForResources(fileProperties.FileResources)
+	resourcesTable, err := createTableForVersionInfo(fileProperties.VersionInfo)
 	if err != nil {
 		displayErrorOnRightPane(ui, err.Error())
 		return
@@ -28,7 +30,7 @@ func displayFileProperties(ui *MyAppUI, fileProperties FileProperties) {
 	ui.rightPane.Add(split)
 }
 
-func displayDosHeaderDetails(ui *MyAppUI, dosHeader *DOSHeader, offset uintptr) {
+func displayDosHeaderDetails(ui *MyAppUI, dosHeader *pefile.DOSHeader, offset uintptr) {
 
 	table, err := createTableFromStruct(dosHeader, offset, true)
 	if err != nil {
@@ -42,7 +44,32 @@ func displayDosHeaderDetails(ui *MyAppUI, dosHeader *DOSHeader, offset uintptr)
 
 }
 
-func displayNtHeadersDetails(ui *MyAppUI, ntHeaders *NtHeaders, offset uintptr) {
+func displayRichHeaderDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	rich, err := peFull.RichHeader()
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableForRichHeader(rich)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table2, err := createTableForRichEntries(rich.Entries)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	split := container.NewVSplit(table.table, table2.table)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+func displayNtHeadersDetails(ui *MyAppUI, ntHeaders *pefile.NtHeaders, offset uintptr) {
 
 	table, err := createTableFromStruct(ntHeaders, offset, false)
 	if err != nil {
@@ -56,21 +83,25 @@ func displayNtHeadersDetails(ui *MyAppUI, ntHeaders *NtHeaders, offset uintptr)
 
 }
 
-func displayFileHeaderDetails(ui *MyAppUI, fileHeader *pe.FileHeader, offset uintptr) {
+func displayFileHeaderDetails(ui *MyAppUI, peFull *pefile.PeFull, offset uintptr) {
 
-	table, err := createTableFromStruct(fileHeader, offset, false)
+	table, err := createTableFromStruct(&peFull.PeFile.FileHeader, offset, false)
 	if err != nil {
 		displayErrorOnRightPane(ui, err.Error())
 		return
 	}
 
+	if peFull.IsReproducible() {
+		appendFieldMeaning(table, "TimeDateStamp", "REPRO debug entry: a hash of the build, not its time")
+	}
+
 	// Replace rightPane with the table
 	ui.rightPane.RemoveAll()
 	ui.rightPane.Add(table.table)
 
 }
 
-func displayOptionalHeaderDetails(ui *MyAppUI, optHeader any, offset uintptr) {
+func displayOptionalHeaderDetails(ui *MyAppUI, peFull *pefile.PeFull, optHeader any, offset uintptr) {
 
 	table, err := createTableFromStruct(optHeader, offset, false)
 	if err != nil {
@@ -81,13 +112,32 @@ func displayOptionalHeaderDetails(ui *MyAppUI, optHeader any, offset uintptr) {
 	// Remove DataDirectories row
 	table.removeRow(len(table.data) - 1)
 
+	// Show the recomputed checksum next to the stored one
+	appendFieldMeaning(table, "CheckSum", checkSumMeaning(peFull))
+
+	// Show addresses as they are once the image is loaded
+	var sizeOfImage uint32
+	switch hdr := optHeader.(type) {
+	case *pefile.OptionalHeader32:
+		sizeOfImage = hdr.SizeOfImage
+		appendFieldMeaning(table, "BaseOfData", rvaMeaning(peFull, hdr.BaseOfData))
+		appendFieldMeaning(table, "BaseOfCode", rvaMeaning(peFull, hdr.BaseOfCode))
+		appendFieldMeaning(table, "AddressOfEntryPoint", entryPointMeaning(peFull, hdr.AddressOfEntryPoint))
+	case *pefile.OptionalHeader64:
+		sizeOfImage = hdr.SizeOfImage
+		appendFieldMeaning(table, "BaseOfCode", rvaMeaning(peFull, hdr.BaseOfCode))
+		appendFieldMeaning(table, "AddressOfEntryPoint", entryPointMeaning(peFull, hdr.AddressOfEntryPoint))
+	}
+	imageBase := peFull.ImageBase()
+	appendFieldMeaning(table, "ImageBase", fmt.Sprintf("VA 0x%X to 0x%X", imageBase, imageBase+uint64(sizeOfImage)))
+
 	// Replace rightPane with the table
 	ui.rightPane.RemoveAll()
 	ui.rightPane.Add(table.table)
 
 }
 
-func displayDataDirectoryDetails(ui *MyAppUI, dataDirs []pe.DataDirectory, offset uintptr) {
+func displayDataDirectoryDetails(ui *MyAppUI, dataDirs []pefile.DataDirectory, offset uintptr) {
 	table, err := createTableForDataDirectories(dataDirs, offset)
 	if err != nil {
 		displayErrorOnRightPane(ui, err.Error())
@@ -99,8 +149,61 @@ func displayDataDirectoryDetails(ui *MyAppUI, dataDirs []pe.DataDirectory, offse
 	ui.rightPane.Add(table.table)
 }
 
-func displaySectionHeadersDetails(ui *MyAppUI, sectionHeaders []*pe.Section, offset uintptr) {
-	table, err := createTableForSectionHeaders(sectionHeaders, offset)
+func displaySectionHeadersDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	table, err := createTableForSectionHeaders(peFull.PeFile.Sections, peFull.SectionStats(), uintptr(peFull.SectionHeadersOffset()))
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	regions := []pefile.RegionStats{peFull.HeaderStats()}
+	if overlay, err := peFull.OverlayStats(); err == nil {
+		regions = append(regions, overlay)
+	}
+	table2, err := createTableForRegionStats(regions)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	// Replace rightPane with the tables
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(container.NewVSplit(table.table, table2.table))
+}
+
+func displayExportTableDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	exports, err := peFull.ExportTable()
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableFromStruct(exports.Header, uintptr(exports.Offset), false)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table2, err := createTableForExports(exports)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	split := container.NewVSplit(table.table, table2.table)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+func displayImportTableDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	imports, err := peFull.ImportTable()
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableForImportDescriptors(imports)
 	if err != nil {
 		displayErrorOnRightPane(ui, err.Error())
 		return
@@ -111,36 +214,105 @@ func displaySectionHeadersDetails(ui *MyAppUI, sectionHeaders []*pe.Section, off
 	ui.rightPane.Add(table.table)
 }
 
-func displayExportTableDetails(ui *MyAppUI, exportDir pe.DataDirectory, peFile *pe.File, fileData []byte) {
+func displayImportDetails(ui *MyAppUI, peFull *pefile.PeFull, index int) {
+	imports, err := peFull.ImportTable()
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+	if index < 0 || index >= len(imports) {
+		displayErrorOnRightPane(ui, "import descriptor not found")
+		return
+	}
+	descriptor := imports[index]
 
-	// exportDir.VirtualAddress
-	var exportHeader IMAGE_EXPORT_DIRECTORY
+	table, err := createTableFromStruct(descriptor.Descriptor, uintptr(descriptor.Offset), false)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
 
-	// read from osFile at ExportDir.VirtualAddress into exportHeader
-	// if _, err := osFile.Seek(int64(exportDir.VirtualAddress), io.SeekStart); err != nil {
-	// 	displayErrorOnRightPane(ui, err.Error())
-	// 	return
-	// }
-	fmt.Printf("virtual address: 0x%x\n", exportDir.VirtualAddress)
-	fmt.Printf("file size: %d\n", len(fileData))
-	exportDirRawOffset, err := rvaToOffset(peFile, exportDir.VirtualAddress)
+	table2, err := createTableForImportFunctions(descriptor.Functions, pointerSize(peFull))
 	if err != nil {
 		displayErrorOnRightPane(ui, err.Error())
 		return
 	}
 
-	reader := bytes.NewReader(fileData[exportDirRawOffset:])
-	if err := binary.Read(reader, binary.LittleEndian, &exportHeader); err != nil {
+	var functions fyne.CanvasObject = table2.table
+	if descriptor.Err != nil {
+		functions = container.NewBorder(widget.NewLabel(descriptor.Err.Error()), nil, nil, nil, table2.table)
+	}
+	split := container.NewVSplit(table.table, functions)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+func displayDelayImportTableDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	imports, err := peFull.DelayImportTable()
+	if err != nil {
 		displayErrorOnRightPane(ui, err.Error())
 		return
 	}
-	table, err := createTableFromStruct(exportHeader, uintptr(exportDirRawOffset), false)
+
+	table, err := createTableForDelayImportDescriptors(imports)
 	if err != nil {
 		displayErrorOnRightPane(ui, err.Error())
 		return
 	}
 
-	table2, err := createTableForExports(peFile, fileData, exportHeader)
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(table.table)
+}
+
+func displayDelayImportDetails(ui *MyAppUI, peFull *pefile.PeFull, index int) {
+	imports, err := peFull.DelayImportTable()
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+	if index < 0 || index >= len(imports) {
+		displayErrorOnRightPane(ui, "delay import descriptor not found")
+		return
+	}
+	descriptor := imports[index]
+
+	table, err := createTableFromStruct(descriptor.Descriptor, uintptr(descriptor.Offset), false)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table2, err := createTableForImportFunctions(descriptor.Functions, pointerSize(peFull))
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	var functions fyne.CanvasObject = table2.table
+	if descriptor.Err != nil {
+		functions = container.NewBorder(widget.NewLabel(descriptor.Err.Error()), nil, nil, nil, table2.table)
+	}
+	split := container.NewVSplit(table.table, functions)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+func displayBoundImportDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	bound, err := peFull.BoundImports()
+	if len(bound) == 0 && err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableForBoundImports(bound)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table2, err := createTableForBoundForwarders(bound)
 	if err != nil {
 		displayErrorOnRightPane(ui, err.Error())
 		return
@@ -151,3 +323,489 @@ func displayExportTableDetails(ui *MyAppUI, exportDir pe.DataDirectory, peFile *
 	ui.rightPane.RemoveAll()
 	ui.rightPane.Add(split)
 }
+
+func displayIatDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	slots, err := peFull.IatSlots()
+	if len(slots) == 0 && err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableForIatSlots(slots, pointerSize(peFull))
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(table.table)
+}
+
+func displayBaseRelocationDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	blocks, err := peFull.BaseRelocations()
+	if len(blocks) == 0 && err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableForRelocationBlocks(blocks)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	// The entries of the selected block are shown under the table
+	details := container.NewStack(widget.NewLabel("Select a block to see its entries"))
+	table.onSelect = func(row []string) {
+		for _, block := range blocks {
+			if fmt.Sprintf("0x%X", block.Offset) == row[0] {
+				details.Objects = []fyne.CanvasObject{relocationBlockDetails(block)}
+				details.Refresh()
+				return
+			}
+		}
+	}
+	split := container.NewVSplit(table.table, details)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+// relocationBlockDetails lays out the header and entries of one block of
+// the base relocation table.
+func relocationBlockDetails(block pefile.RelocationBlock) fyne.CanvasObject {
+	table, err := createTableFromStruct(block.Header, uintptr(block.Offset), false)
+	if err != nil {
+		return widget.NewLabel(err.Error())
+	}
+
+	table2, err := createTableForRelocationEntries(block.Entries)
+	if err != nil {
+		return widget.NewLabel(err.Error())
+	}
+
+	return container.NewVSplit(table.table, table2.table)
+}
+
+func displayExceptionTableDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	functions, err := peFull.ExceptionTable()
+	if len(functions) == 0 && err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	arm64 := peFull.PeFile.FileHeader.Machine == pefile.IMAGE_FILE_MACHINE_ARM64
+	table, err := createTableForRuntimeFunctions(functions, arm64)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	// The unwind info of the selected function is shown under the table
+	details := container.NewStack(widget.NewLabel("Select a function to see its unwind info"))
+	table.onSelect = func(row []string) {
+		for _, function := range functions {
+			if fmt.Sprintf("0x%X", function.Offset) == row[0] {
+				details.Objects = []fyne.CanvasObject{runtimeFunctionDetails(function, arm64)}
+				details.Refresh()
+				return
+			}
+		}
+	}
+	split := container.NewVSplit(table.table, details)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+// runtimeFunctionDetails lays out the unwind info and unwind codes of one
+// function of the exception table.
+func runtimeFunctionDetails(function pefile.RuntimeFunction, arm64 bool) fyne.CanvasObject {
+	table, err := createTableForUnwindInfo(function, arm64)
+	if err != nil {
+		return widget.NewLabel(err.Error())
+	}
+
+	table2, err := createTableForUnwindCodes(function)
+	if err != nil {
+		return widget.NewLabel(err.Error())
+	}
+
+	return container.NewVSplit(table.table, table2.table)
+}
+
+func displayCertificateTableDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	entries, err := peFull.CertificateTable()
+	if len(entries) == 0 && err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableForCertificateEntries(entries)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	results, _ := peFull.VerifyAuthenticode(trustAnchors)
+	table2, err := createTableForAuthenticode(results)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	split := container.NewVSplit(table.table, table2.table)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+func displaySignatureDetails(ui *MyAppUI, peFull *pefile.PeFull, index int) {
+	signatures, err := peFull.Signatures()
+	if index < 0 || index >= len(signatures) {
+		if err == nil {
+			err = fmt.Errorf("signature not found")
+		}
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+	signature := signatures[index]
+
+	table, err := createTableForSignature(signature)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table2, err := createTableForSignatureCertificates(signature)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	split := container.NewVSplit(table.table, table2.table)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+func displayDebugDirectoryDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	entries, err := peFull.DebugDirectory()
+	if len(entries) == 0 && err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableForDebugEntries(entries)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(table.table)
+}
+
+func displayDebugEntryDetails(ui *MyAppUI, peFull *pefile.PeFull, index int) {
+	entries, err := peFull.DebugDirectory()
+	if index < 0 || index >= len(entries) {
+		if err == nil {
+			err = fmt.Errorf("debug directory entry not found")
+		}
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+	entry := entries[index]
+
+	table, err := createTableFromStruct(entry.Directory, uintptr(entry.Offset), false)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table2, err := createTableForDebugRecord(entry)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	split := container.NewVSplit(table.table, table2.table)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+func displayTlsDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	tls, err := peFull.TLSDirectory()
+	if tls == nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableFromStruct(tls.Header, uintptr(tls.Offset), false)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table2, err := createTableForTlsCallbacks(tls.Callbacks, pointerSize(peFull))
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	split := container.NewVSplit(table.table, table2.table)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+func displayLoadConfigDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	config, err := peFull.LoadConfig()
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableFromStructPrefix(config.Header, uintptr(config.Offset), false, uintptr(config.Size))
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(table.table)
+}
+
+// displayGuardTableDetails shows one of the function tables the load config
+// points at, by the name of its load config field.
+func displayGuardTableDetails(ui *MyAppUI, peFull *pefile.PeFull, name string) {
+	config, err := peFull.LoadConfig()
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+	guardTable := config.GuardTable(name)
+	if guardTable == nil {
+		displayErrorOnRightPane(ui, fmt.Sprintf("%s not found", name))
+		return
+	}
+
+	table, err := createTableForGuardTable(guardTable)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	ui.rightPane.RemoveAll()
+	if guardTable.Err != nil {
+		ui.rightPane.Add(container.NewBorder(widget.NewLabel(guardTable.Err.Error()), nil, nil, nil, table.table))
+	} else {
+		ui.rightPane.Add(table.table)
+	}
+}
+
+// displayResourceTableDetails shows the resource directory or data entry
+// behind a "Resource Table" tree node.
+func displayResourceTableDetails(ui *MyAppUI, peFull *pefile.PeFull, uid string) {
+	resources, err := peFull.ResourceTable()
+	if resources == nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	var header any
+	var headerOffset uint32
+	var entries []pefile.ResourceEntry
+	if uid == "Resource Table" {
+		header, headerOffset, entries = resources.Header, resources.Offset, resources.Entries
+	} else {
+		entry, err := resources.FindByTreeID(uid)
+		if err != nil {
+			displayErrorOnRightPane(ui, err.Error())
+			return
+		}
+		if entry.Directory != nil {
+			header, headerOffset, entries = entry.Directory.Header, entry.Directory.Offset, entry.Directory.Entries
+		} else {
+			header, headerOffset, entries = entry.Data.Entry, entry.Data.Offset, []pefile.ResourceEntry{*entry}
+		}
+	}
+
+	table, err := createTableFromStruct(header, uintptr(headerOffset), false)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table2, err := createTableForResourceEntries(entries)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	split := container.NewVSplit(table.table, table2.table)
+
+	ui.rightPane.RemoveAll()
+	if iconPanel := resourceIconPanel(peFull, resources, uid); iconPanel != nil {
+		ui.rightPane.Add(container.NewVSplit(iconPanel, split))
+	} else {
+		ui.rightPane.Add(split)
+	}
+}
+
+// resourceIconPanel returns a preview of the icon group selected in the
+// resource tree, or nil when uid is not an icon group.
+func resourceIconPanel(peFull *pefile.PeFull, resources *pefile.ResourceDirectory, uid string) fyne.CanvasObject {
+	if !strings.HasPrefix(uid, "Resource Table/GROUP_ICON/") {
+		return nil
+	}
+	entry, err := resources.FindByTreeID(uid)
+	if err != nil {
+		return nil
+	}
+	data := entry.Data
+	if entry.Directory != nil {
+		data = entry.Directory.FirstData()
+	}
+	if data == nil {
+		return nil
+	}
+	group, err := peFull.IconGroup(data)
+	if err != nil {
+		return widget.NewLabel(err.Error())
+	}
+	return createIconGroupPanel(group)
+}
+
+func displayClrHeaderDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	clr, err := peFull.ClrHeader()
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableFromStruct(clr.Header, uintptr(clr.Offset), false)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	switch metadata, _ := peFull.Metadata(); {
+	case clr.Header.Flags&pefile.COMIMAGE_FLAGS_NATIVE_ENTRYPOINT != 0:
+		appendFieldMeaning(table, "EntryPointToken", "native entry point RVA")
+	case clr.Header.EntryPointToken == 0:
+		appendFieldMeaning(table, "EntryPointToken", "none")
+	case metadata != nil:
+		appendFieldMeaning(table, "EntryPointToken", metadata.TokenName(clr.Header.EntryPointToken))
+	}
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(table.table)
+}
+
+func displayMetadataDetails(ui *MyAppUI, peFull *pefile.PeFull) {
+	metadata, err := peFull.Metadata()
+	if metadata == nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableForMetadataRoot(metadata)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table2, err := createTableForMetadataStreams(metadata.Streams)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	split := container.NewVSplit(table.table, table2.table)
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(split)
+}
+
+// displayMetadataStreamDetails shows the tables of the #~ stream or the
+// entries of a heap.
+func displayMetadataStreamDetails(ui *MyAppUI, peFull *pefile.PeFull, name string) {
+	metadata, err := peFull.Metadata()
+	if metadata == nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	if name == "#~" || name == "#-" {
+		if metadata.Tables == nil {
+			displayErrorOnRightPane(ui, metadata.TablesErr.Error())
+			return
+		}
+		table, err := createTableFromStruct(metadata.Tables.Header, uintptr(metadata.Tables.Offset), false)
+		if err != nil {
+			displayErrorOnRightPane(ui, err.Error())
+			return
+		}
+		table2, err := createTableForMetadataTables(metadata.Tables)
+		if err != nil {
+			displayErrorOnRightPane(ui, err.Error())
+			return
+		}
+
+		var tables fyne.CanvasObject = table2.table
+		if metadata.TablesErr != nil {
+			tables = container.NewBorder(widget.NewLabel(metadata.TablesErr.Error()), nil, nil, nil, table2.table)
+		}
+		split := container.NewVSplit(table.table, tables)
+
+		ui.rightPane.RemoveAll()
+		ui.rightPane.Add(split)
+		return
+	}
+
+	entries, err := metadata.HeapEntries(name)
+	if len(entries) == 0 && err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	table, err := createTableForHeapEntries(entries)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(table.table)
+}
+
+func displayMetadataTableDetails(ui *MyAppUI, peFull *pefile.PeFull, name string) {
+	metadata, err := peFull.Metadata()
+	if metadata == nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+	var metadataTable *pefile.MetadataTable
+	if metadata.Tables != nil {
+		for i := range metadata.Tables.Tables {
+			if metadata.Tables.Tables[i].Name == name {
+				metadataTable = &metadata.Tables.Tables[i]
+			}
+		}
+	}
+	if metadataTable == nil {
+		displayErrorOnRightPane(ui, fmt.Sprintf("%s table not found", name))
+		return
+	}
+
+	table, err := createTableForMetadataRows(metadata, metadataTable)
+	if err != nil {
+		displayErrorOnRightPane(ui, err.Error())
+		return
+	}
+
+	ui.rightPane.RemoveAll()
+	ui.rightPane.Add(table.table)
+}
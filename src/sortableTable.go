@@ -15,6 +15,7 @@ import (
 type selectableLabel struct {
 	widget.Entry
 	originalText string
+	onTapped     func()
 }
 
 // newSelectableLabel creates a new selectableLabel with the given text.
@@ -34,6 +35,15 @@ func newSelectableLabel(text string) *selectableLabel {
 	return s
 }
 
+// Tapped lets the table know its row was picked before the entry handles
+// the tap.
+func (s *selectableLabel) Tapped(ev *fyne.PointEvent) {
+	if s.onTapped != nil {
+		s.onTapped()
+	}
+	s.Entry.Tapped(ev)
+}
+
 // CreateRenderer overrides the renderer to remove the default border and background.
 func (s *selectableLabel) CreateRenderer() fyne.WidgetRenderer {
 	// Get the original renderer.
@@ -76,12 +86,21 @@ type sortableTable struct {
 	colProps  []ColumnProps
 	// Track the current sort direction per column (true=asc, false=desc)
 	sortAsc map[int]bool
+	// sizes holds the number of bytes of the file each row after the header
+	// covers from its Offset; rows without one are not shown in the hex pane
+	sizes []uint64
+	// flagged, when set, marks the rows after the header that are drawn on
+	// a warning background
+	flagged []bool
+	// onSelect, when set, is called with the data row the user selects
+	onSelect func(row []string)
 }
 
 // newSortableTable creates a new sortableTable around an existing data set.
-func newSortableTable(data [][]string, colWidths []float32, colTypes []ColumnType, colProps []ColumnProps) *sortableTable {
+func newSortableTable(data [][]string, sizes []uint64, colWidths []float32, colTypes []ColumnType, colProps []ColumnProps) *sortableTable {
 	st := &sortableTable{
 		data:      data,
+		sizes:     sizes,
 		colWidths: colWidths,
 		sortAsc:   make(map[int]bool),
 		colTypes:  colTypes,
@@ -118,9 +137,16 @@ func newSortableTable(data [][]string, colWidths []float32, colTypes []ColumnTyp
 				})
 				c.Objects = []fyne.CanvasObject{rect, clickable}
 			} else {
+				rect.FillColor = theme.Color(theme.ColorNameBackground)
+				if id.Row <= len(st.flagged) && st.flagged[id.Row-1] {
+					rect.FillColor = tintColor(theme.ColorNameWarning, 0x60)
+				}
+
 				var entry fyne.CanvasObject
 				if st.colProps[id.Col].selectable {
-					entry = newSelectableLabel(text)
+					label := newSelectableLabel(text)
+					label.onTapped = func() { st.selectRow(id.Row) }
+					entry = label
 				} else {
 					// Just a normal label with wrapping
 					entry = widget.NewLabel(text)
@@ -132,11 +158,45 @@ func newSortableTable(data [][]string, colWidths []float32, colTypes []ColumnTyp
 		},
 	)
 
+	// Plain labels leave the tap to the table
+	tbl.OnSelected = func(id widget.TableCellID) {
+		st.selectRow(id.Row)
+	}
+
 	st.table = tbl
 	st.updateRowHeights()
 	return st
 }
 
+// selectRow shows the bytes of a data row in the hex pane.
+func (st *sortableTable) selectRow(row int) {
+	if row == 0 {
+		return
+	}
+	if st.onSelect != nil {
+		st.onSelect(st.data[row])
+	}
+	if fileHexView == nil {
+		return
+	}
+	if offset, size, ok := st.byteRange(row); ok {
+		fileHexView.highlight(offset, size)
+	}
+}
+
+// byteRange returns the file range a row covers: from its Offset, for the
+// number of bytes the table was built with for it.
+func (st *sortableTable) byteRange(row int) (uint64, uint64, bool) {
+	if st.data[0][0] != "Offset" || row > len(st.sizes) {
+		return 0, 0, false
+	}
+	offset, err := strconv.ParseUint(st.data[row][0], 0, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return offset, st.sizes[row-1], true
+}
+
 // sortByColumn sorts st.data (excluding row 0, which is the header) by the given col index.
 func (st *sortableTable) sortByColumn(col int) {
 	// If this column is "unsortable", just return (do nothing)
@@ -148,7 +208,7 @@ func (st *sortableTable) sortByColumn(col int) {
 	ascending := st.sortAsc[col]
 
 	// Sort the data in place, skipping row 0 (the header)
-	sort.Slice(st.data[1:], func(i, j int) bool {
+	sort.Sort(tableRows{st, func(i, j int) bool {
 		leftStr := st.data[1+i][col]
 		rightStr := st.data[1+j][col]
 
@@ -178,7 +238,7 @@ func (st *sortableTable) sortByColumn(col int) {
 
 		// Fallback (in case we add columns later):
 		return false
-	})
+	}})
 
 	// Re-measure row heights in case anything changed
 	st.updateRowHeights()
@@ -187,6 +247,27 @@ func (st *sortableTable) sortByColumn(col int) {
 	st.table.Refresh()
 }
 
+// tableRows sorts the rows of a table after the header, moving their sizes
+// and flags along with them.
+type tableRows struct {
+	st   *sortableTable
+	less func(i, j int) bool
+}
+
+func (r tableRows) Len() int           { return len(r.st.data) - 1 }
+func (r tableRows) Less(i, j int) bool { return r.less(i, j) }
+
+func (r tableRows) Swap(i, j int) {
+	rows := r.st.data[1:]
+	rows[i], rows[j] = rows[j], rows[i]
+	if sizes := r.st.sizes; sizes != nil {
+		sizes[i], sizes[j] = sizes[j], sizes[i]
+	}
+	if flagged := r.st.flagged; flagged != nil {
+		flagged[i], flagged[j] = flagged[j], flagged[i]
+	}
+}
+
 // parseHex attempts to parse a string like "0x10" or "0XFF" into an int64.
 // It automatically handles 0x prefix if you pass base=0 to ParseInt().
 func parseHex(s string) int64 {
@@ -225,6 +306,12 @@ func (st *sortableTable) removeRow(row int) {
 	}
 
 	st.data = append(st.data[:row], st.data[row+1:]...)
+	if row > 0 && row <= len(st.sizes) {
+		st.sizes = append(st.sizes[:row-1], st.sizes[row:]...)
+	}
+	if row > 0 && row <= len(st.flagged) {
+		st.flagged = append(st.flagged[:row-1], st.flagged[row:]...)
+	}
 	st.updateRowHeights()
 }
 